@@ -0,0 +1,134 @@
+package benchmarks
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+var cacheTypes = []cache.CacheType{cache.Blocking, cache.Refresh}
+var distributions = []Distribution{Uniform, Zipfian}
+
+// evicting from a full cache sorts every entry into a linked list, so larger caches take minutes per benchmark
+var cacheSizes = []int{1_000, 10_000}
+var goroutineCounts = []int{1, 8, 64}
+
+// keySpaceFactor - the key space is larger than the cache, so the workloads have misses and evictions
+const keySpaceFactor = 2
+
+func loadKey(k string) (string, error) {
+	return k, nil
+}
+
+func newCache(cacheType cache.CacheType, size int) cache.Cache[string, string] {
+	built := cache.NewCacheBuilder[string, string]().
+		SetCacheType(cacheType).
+		SetMaxSize(size).
+		Build(loadKey)
+	for i := 0; i < size; i++ {
+		built.Put(Key(i), Key(i))
+	}
+	return built
+}
+
+// runWorkers splits the b.N operations over goroutines, every goroutine calls op with its own keys
+func runWorkers(b *testing.B, goroutines int, distribution Distribution, keySpace int, op func(worker int, k string)) {
+	keys := make([][]string, goroutines)
+	for worker := range keys {
+		keys[worker] = distribution.Keys(int64(worker), keySpace, keysPerWorker)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	wait := &sync.WaitGroup{}
+	for worker := 0; worker < goroutines; worker++ {
+		ops := b.N / goroutines
+		if worker < b.N%goroutines {
+			ops++
+		}
+		wait.Add(1)
+		go func(worker int, ops int) {
+			defer wait.Done()
+			workerKeys := keys[worker]
+			for i := 0; i < ops; i++ {
+				op(worker, workerKeys[i%keysPerWorker])
+			}
+		}(worker, ops)
+	}
+	wait.Wait()
+}
+
+// benchmarkWorkload runs op for every combination of cache type, distribution, cache size and goroutine count
+func benchmarkWorkload(b *testing.B, op func(c cache.Cache[string, string], worker int, i int, k string)) {
+	for _, cacheType := range cacheTypes {
+		for _, distribution := range distributions {
+			for _, size := range cacheSizes {
+				for _, goroutines := range goroutineCounts {
+					name := fmt.Sprintf("type=%s/dist=%s/size=%d/goroutines=%d", cacheType, distribution, size, goroutines)
+					b.Run(name, func(b *testing.B) {
+						c := newCache(cacheType, size)
+						defer c.Close()
+						counters := make([]int, goroutines)
+						runWorkers(b, goroutines, distribution, size*keySpaceFactor, func(worker int, k string) {
+							counters[worker]++
+							op(c, worker, counters[worker], k)
+						})
+					})
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	benchmarkWorkload(b, func(c cache.Cache[string, string], worker int, i int, k string) {
+		c.Get(k)
+	})
+}
+
+func BenchmarkPut(b *testing.B) {
+	benchmarkWorkload(b, func(c cache.Cache[string, string], worker int, i int, k string) {
+		c.Put(k, k)
+	})
+}
+
+// BenchmarkMixed - 90% gets and 10% puts
+func BenchmarkMixed(b *testing.B) {
+	benchmarkWorkload(b, func(c cache.Cache[string, string], worker int, i int, k string) {
+		if i%10 == 0 {
+			c.Put(k, k)
+			return
+		}
+		c.Get(k)
+	})
+}
+
+// BenchmarkEviction measures removing the least recently accessed entries of a full cache data, which sorts all entries
+func BenchmarkEviction(b *testing.B) {
+	for _, size := range cacheSizes {
+		for _, evictionPercent := range []int{1, 10} {
+			evictions := size * evictionPercent / 100
+			b.Run(fmt.Sprintf("size=%d/evict=%d%%", size, evictionPercent), func(b *testing.B) {
+				data := cache.NewCacheData[string, string](cache.LocalClock{})
+				for i := 0; i < size; i++ {
+					data.Put(Key(i), Key(i))
+				}
+				next := size
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					data.RemoveLeastRecentlyAccessed(evictions)
+					b.StopTimer()
+					for j := 0; j < evictions; j++ {
+						data.Put(Key(next), Key(next))
+						next++
+					}
+					b.StartTimer()
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Package benchmarks has the benchmarks of the caches under realistic workloads. Run them on two revisions and compare the results with benchstat:
+//
+//	go test ./cache/benchmarks -run '^$' -bench . -count 10 > old.txt
+//	go test ./cache/benchmarks -run '^$' -bench . -count 10 > new.txt
+//	benchstat old.txt new.txt
+//
+// The benchmark names use key=value parts, so benchstat can also compare configurations, for example with -col /goroutines.
+package benchmarks
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+// keysPerWorker - the number of keys generated up front for every goroutine of a benchmark, so the benchmark loop does not measure the key generation
+const keysPerWorker = 1 << 14
+
+// zipfExponent - the skew of the zipfian distribution, about the skew of web and database caches
+const zipfExponent = 1.01
+
+// Distribution generates the keys requested by a workload
+type Distribution int
+
+const (
+	// Uniform requests every key of the key space equally often
+	Uniform Distribution = iota
+	// Zipfian requests a few keys of the key space most of the time
+	Zipfian
+)
+
+func (d Distribution) String() string {
+	switch d {
+	case Uniform:
+		return "uniform"
+	case Zipfian:
+		return "zipf"
+	default:
+		return "Distribution(" + strconv.Itoa(int(d)) + ")"
+	}
+}
+
+// Keys returns count keys out of a key space of keySpace keys, the same seed returns the same keys.
+func (d Distribution) Keys(seed int64, keySpace int, count int) []string {
+	random := rand.New(rand.NewSource(seed))
+	next := func() uint64 {
+		return uint64(random.Intn(keySpace))
+	}
+	if d == Zipfian {
+		next = rand.NewZipf(random, zipfExponent, 1, uint64(keySpace-1)).Uint64
+	}
+
+	keys := make([]string, count)
+	for i := range keys {
+		keys[i] = Key(int(next()))
+	}
+	return keys
+}
+
+// Key returns the key with the index i of a key space
+func Key(i int) string {
+	return "key-" + strconv.Itoa(i)
+}
@@ -1,27 +1,48 @@
 package cache
 
-import "time"
+import (
+	"context"
+	"errors"
+	"io"
+)
 
 type blockingExpiredCache[K comparable, V any] struct {
-	cacheInfo CacheInfo[K, V]
-	cacheData CacheData[K, V]
+	cacheInfo      CacheInfo[K, V]
+	cacheData      CacheData[K, V]
+	circuitBreaker *circuitBreaker
+	lifecycle      *lifecycle
+	snapshotter    *snapshotter
+	storage        *storageTier[K, V]
+	remote         *remoteTier[K, V]
+	invalidations  *invalidationTier[K]
+	writer         *cacheWriter[K, V]
+	registration   *registration
+	stats          *statsCounter[K]
+	logger         *cacheLogger[K]
 
 	clock Clock
 }
 
 func (b *blockingExpiredCache[K, V]) Get(k K) (V, bool) {
 	value, exists := b.cacheData.Get(k)
+	if !exists {
+		value, exists = b.getFromStorage(k)
+	}
 	if !exists || b.cacheData.IsExpired(k, b.cacheInfo.Expiration) {
 		b.cacheMiss(k)
 
-		value, err := b.loadCacheValue(k)
+		loadedValue, err := b.loadCacheValue(k)
 		if err != nil {
-			b.failedToLoadEntry(k)
-			return value, false
+			b.failedToLoadEntry(k, err)
+			// while the circuit is open or when configured for timeouts serve the expired value if we have one
+			if exists && canServeExpired(b.cacheInfo, err) {
+				return value, true
+			}
+			return loadedValue, false
 		}
 
-		b.cacheData.Put(k, value)
-		return value, true
+		b.store(k, loadedValue)
+		return loadedValue, true
 	} else {
 		b.cacheHit(k)
 		return value, true
@@ -29,15 +50,184 @@ func (b *blockingExpiredCache[K, V]) Get(k K) (V, bool) {
 }
 
 func (b *blockingExpiredCache[K, V]) Put(k K, v V) bool {
-	if b.cacheData.GetSize() >= *b.cacheInfo.MaxSize {
-		b.cacheData.RemoveLeastRecentlyAccessed(b.cacheInfo.GetEvictionSize())
+	if err := b.writer.write(k, v); err != nil {
+		return false
 	}
-	return b.cacheData.Put(k, v)
+	stored := b.store(k, v)
+	b.remote.set(k, v)
+	b.invalidations.publish(k)
+	return stored
 }
 
 func (b *blockingExpiredCache[K, V]) Remove(k K) bool {
+	if err := b.writer.delete(k); err != nil {
+		return false
+	}
 	b.cacheRemoved(k)
-	return b.cacheData.Remove(k)
+	b.storage.delete(k)
+	b.remote.delete(k)
+	removed := b.cacheData.Remove(k)
+	if removed {
+		b.stats.removed(1)
+	}
+	b.invalidations.publish(k)
+	return removed
+}
+
+func (b *blockingExpiredCache[K, V]) GetIfPresent(k K) (V, bool) {
+	return b.cacheData.GetIfPresent(k)
+}
+
+func (b *blockingExpiredCache[K, V]) Contains(k K) bool {
+	return b.cacheData.Contains(k)
+}
+
+func (b *blockingExpiredCache[K, V]) Len() int {
+	return b.cacheData.Len()
+}
+
+func (b *blockingExpiredCache[K, V]) Keys() []K {
+	return b.cacheData.Keys()
+}
+
+func (b *blockingExpiredCache[K, V]) Range(f func(k K, v V) bool) {
+	b.cacheData.Range(f)
+}
+
+func (b *blockingExpiredCache[K, V]) RemoveAll(keys []K) int {
+	keys = b.writer.deleteAll(keys)
+	b.remote.delete(keys...)
+	removed := b.removeLocal(keys)
+	b.invalidations.publish(keys...)
+	return removed
+}
+
+func (b *blockingExpiredCache[K, V]) InvalidateAll() {
+	keys := b.cacheData.AllKeys()
+	b.remote.delete(keys...)
+	b.removeLocal(keys)
+	b.storage.clear()
+	b.invalidations.publishAll()
+}
+
+func (b *blockingExpiredCache[K, V]) Clear() {
+	b.cacheData.Clear()
+	b.storage.clear()
+}
+
+func (b *blockingExpiredCache[K, V]) Compute(k K, f func(old V, exists bool) (V, Action)) (V, bool) {
+	removed := false
+	stored := false
+	added := false
+	value, exists := b.cacheData.Compute(k, func(old V, exists bool) (V, Action) {
+		value, action := f(old, exists)
+		removed = exists && action == Delete
+		stored = action == Store
+		added = !exists && action == Store
+		return value, action
+	})
+	// the action is only known after the compute, so room is made after a new entry was added
+	if added {
+		b.evictIfOverMaxSize()
+	}
+	if removed {
+		b.deleted(k)
+	}
+	if stored && exists && !b.updated(k, value) {
+		var defaultValue V
+		return defaultValue, false
+	}
+	return value, exists
+}
+
+func (b *blockingExpiredCache[K, V]) ComputeIfAbsent(k K, f func(k K) (V, bool)) (V, bool) {
+	return b.Compute(k, computeIfAbsent(k, f))
+}
+
+func (b *blockingExpiredCache[K, V]) ComputeIfPresent(k K, f func(k K, old V) (V, Action)) (V, bool) {
+	return b.Compute(k, computeIfPresent(k, f))
+}
+
+func (b *blockingExpiredCache[K, V]) Merge(k K, v V, f func(old V, v V) (V, Action)) (V, bool) {
+	return b.Compute(k, merge(v, f))
+}
+
+func (b *blockingExpiredCache[K, V]) PutIfAbsent(k K, v V) (V, bool) {
+	existing, loaded := b.cacheData.PutIfAbsent(k, v)
+	if !loaded {
+		b.evictIfOverMaxSize()
+	}
+	if !loaded && !b.updated(k, v) {
+		var defaultValue V
+		return defaultValue, true
+	}
+	return existing, loaded
+}
+
+func (b *blockingExpiredCache[K, V]) Replace(k K, v V) (V, bool) {
+	previous, replaced := b.cacheData.Replace(k, v)
+	if replaced && !b.updated(k, v) {
+		return previous, false
+	}
+	return previous, replaced
+}
+
+func (b *blockingExpiredCache[K, V]) CompareAndSwap(k K, old V, new V) bool {
+	if !b.cacheData.CompareAndSwap(k, old, new) {
+		return false
+	}
+	return b.updated(k, new)
+}
+
+func (b *blockingExpiredCache[K, V]) CompareAndDelete(k K, old V) bool {
+	if !b.cacheData.CompareAndDelete(k, old) {
+		return false
+	}
+	b.deleted(k)
+	return true
+}
+
+func (b *blockingExpiredCache[K, V]) Snapshot(w io.Writer) error {
+	return b.cacheData.Snapshot(w)
+}
+
+func (b *blockingExpiredCache[K, V]) Restore(reader io.Reader) error {
+	if err := b.cacheData.Restore(reader); err != nil {
+		return err
+	}
+	// a snapshot of a larger cache can not be restored completely
+	if size := b.cacheData.GetSize(); size > *b.cacheInfo.MaxSize {
+		b.storage.put(b.cacheData.RemoveLeastRecentlyAccessed(size - *b.cacheInfo.MaxSize))
+	}
+	return nil
+}
+
+func (b *blockingExpiredCache[K, V]) Close() error {
+	return b.Shutdown(context.Background())
+}
+
+func (b *blockingExpiredCache[K, V]) Shutdown(ctx context.Context) error {
+	if err := b.lifecycle.close(); err != nil {
+		return err
+	}
+	b.invalidations.close()
+	b.registration.close()
+	waitErr := b.lifecycle.wait(ctx)
+	writerErr := b.writer.close(ctx)
+	snapshotErr := b.snapshotter.stopAndWrite(b.Snapshot)
+
+	for _, k := range b.cacheData.AllKeys() {
+		b.cacheRemoved(k)
+	}
+	b.cacheData.Close()
+
+	if waitErr != nil {
+		return waitErr
+	}
+	if writerErr != nil {
+		return writerErr
+	}
+	return snapshotErr
 }
 
 func (b blockingExpiredCache[K, V]) cacheRemoved(k K) {
@@ -46,6 +236,93 @@ func (b blockingExpiredCache[K, V]) cacheRemoved(k K) {
 	}
 }
 
+// invalidate removes the keys invalidated by another instance of the cache, the remote store was already updated by that instance
+func (b *blockingExpiredCache[K, V]) invalidate(invalidation Invalidation[K]) {
+	if invalidation.All {
+		b.removeLocal(b.cacheData.AllKeys())
+		b.storage.clear()
+		return
+	}
+	b.removeLocal(invalidation.Keys)
+}
+
+// updated propagates a value stored by a conditional update. The value is written after the cache was updated because the update depends on the current value,
+// if a write-through write fails the entry is removed from the cache again so the cache never serves a value the system of record does not have
+func (b *blockingExpiredCache[K, V]) updated(k K, v V) bool {
+	if err := b.writer.write(k, v); err != nil {
+		b.cacheRemoved(k)
+		if b.cacheData.Remove(k) {
+			b.stats.removed(1)
+		}
+		b.remote.delete(k)
+		b.invalidations.publish(k)
+		return false
+	}
+	b.remote.set(k, v)
+	b.invalidations.publish(k)
+	return true
+}
+
+// deleted propagates an entry removed by a conditional update, the entry is already removed so a failed delete is only reported to the OnWriteError hook
+func (b *blockingExpiredCache[K, V]) deleted(k K) {
+	b.writer.delete(k)
+	b.cacheRemoved(k)
+	b.stats.removed(1)
+	b.storage.delete(k)
+	b.remote.delete(k)
+	b.invalidations.publish(k)
+}
+
+// removeLocal removes the keys from the cache and its storage
+func (b *blockingExpiredCache[K, V]) removeLocal(keys []K) int {
+	for _, k := range keys {
+		b.cacheRemoved(k)
+	}
+	b.storage.delete(keys...)
+	removed := b.cacheData.RemoveAll(keys)
+	b.stats.removed(removed)
+	return removed
+}
+
+// store puts the value in the cache, making room first if the cache has reached its max size
+func (b *blockingExpiredCache[K, V]) store(k K, v V) bool {
+	b.evictIfFull()
+	return b.cacheData.Put(k, v)
+}
+
+// evictIfFull removes the least recently accessed entries if the cache has reached its max size and moves them to the storage
+func (b *blockingExpiredCache[K, V]) evictIfFull() {
+	if b.cacheData.GetSize() >= *b.cacheInfo.MaxSize {
+		b.evict()
+	}
+}
+
+// evictIfOverMaxSize removes the least recently accessed entries if an entry added without making room first took the cache over its max size
+func (b *blockingExpiredCache[K, V]) evictIfOverMaxSize() {
+	if b.cacheData.GetSize() > *b.cacheInfo.MaxSize {
+		b.evict()
+	}
+}
+
+func (b *blockingExpiredCache[K, V]) evict() {
+	evicted := b.cacheData.RemoveLeastRecentlyAccessed(b.cacheInfo.GetEvictionSize())
+	b.logger.evicted(len(evicted))
+	b.storage.put(evicted)
+}
+
+// getFromStorage moves the entry for the key from the storage back into the cache, the entry keeps its timestamps so it can be expired
+func (b *blockingExpiredCache[K, V]) getFromStorage(k K) (V, bool) {
+	entry, found := b.storage.take(k)
+	if !found {
+		var defaultValue V
+		return defaultValue, false
+	}
+	entry.LastAccessTime = b.clock.Now()
+	b.evictIfFull()
+	b.cacheData.RestoreEntries([]CacheEntry[K, V]{entry})
+	return entry.Value, true
+}
+
 func (b *blockingExpiredCache[K, V]) cacheMiss(k K) {
 	if b.cacheInfo.Hooks.OnCacheMiss != nil {
 		b.cacheInfo.Hooks.OnCacheMiss(k)
@@ -58,17 +335,46 @@ func (b *blockingExpiredCache[K, V]) cacheHit(k K) {
 	}
 }
 
-func (b *blockingExpiredCache[K, V]) failedToLoadEntry(k K) {
-	if b.cacheInfo.Hooks.OnFailedToLoadEntry != nil {
+// failedToLoadEntry calls the hook unless the loader can not load values or the cache is closed,
+// a miss of a cache that is only filled with Put and a refresh that starts after the cache was closed are not failures
+func (b *blockingExpiredCache[K, V]) failedToLoadEntry(k K, err error) {
+	if b.cacheInfo.Hooks.OnFailedToLoadEntry != nil && !errors.Is(err, ErrNotLoadable) && !errors.Is(err, ErrClosed) {
 		b.cacheInfo.Hooks.OnFailedToLoadEntry(k)
 	}
 }
 
 func (b *blockingExpiredCache[K, V]) loadCacheValue(k K) (V, error) {
+	if err := b.lifecycle.begin(); err != nil {
+		var defaultValue V
+		return defaultValue, err
+	}
+	defer b.lifecycle.end()
+
+	if value, found := b.remote.get(k); found {
+		return value, nil
+	}
+
+	if err := b.circuitBreaker.allow(); err != nil {
+		b.logger.loadRejected(k, err)
+		var defaultValue V
+		return defaultValue, err
+	}
+
 	startLoad := b.clock.Now()
-	value, err := b.cacheInfo.CacheLoader(k)
+	value, err := callLoader(b.cacheInfo, b.clock, k)
+	if errors.Is(err, ErrNotLoadable) {
+		// nothing was loaded, the miss is neither a load nor a failure
+		b.circuitBreaker.release()
+		return value, err
+	}
+	loadDuration := b.clock.Now().Sub(startLoad)
 	if b.cacheInfo.Hooks.OnCacheLoadDuration != nil {
-		b.cacheInfo.Hooks.OnCacheLoadDuration(k, time.Since(startLoad))
+		b.cacheInfo.Hooks.OnCacheLoadDuration(k, loadDuration)
+	}
+	b.logger.loaded(k, loadDuration, err)
+	b.circuitBreaker.record(err)
+	if err == nil {
+		b.remote.set(k, value)
 	}
 	return value, err
 }
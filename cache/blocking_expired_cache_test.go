@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"fmt"
 	"testing"
 	"time"
 )
@@ -74,18 +75,13 @@ func TestWhenLoadingAValueThatIsInTheCacheAndNotExpiredDoNotLoadData(t *testing.
 func TestWhenLoadingDataThatHasExpiredReloadDataOnRequest(t *testing.T) {
 	// setup
 	initTests()
-	testCacheTimes := []time.Time{
-		time.Unix(1000, 0), // load cache value - start load - in case we are timing the cache request - not important for test
-		time.Unix(1000, 0), // initial insert - insertTime - not important for test
-		time.Unix(1000, 0), // initial insert - lastAccessTime - not important for test
-		time.Unix(1000, 0), // initial insert - lastUpdateTime - important for test
-		time.Unix(1000, 0), // second get request - value exists update last accessed time - not important for test
-		time.Unix(2000, 0), // second get request - checking to see if the entry is expired - important for test - should expire initial request
-	}
-	cache = BuildTestCacheByTypeAndExpirationMillis[string, string](Blocking, cacheLoader.Load, NewTestClock(testCacheTimes...), 10)
+	clock := NewFakeClock(time.Unix(1000, 0))
+	cache = BuildTestCacheByTypeAndExpirationMillis[string, string](Blocking, cacheLoader.Load, clock, 10)
 
 	// execute
 	value1, wasLoaded := cache.Get("key")
+	// past the expiration of the initial request
+	clock.Advance(time.Millisecond * 20)
 	value2, wasLoaded2 := cache.Get("key")
 
 	// verify
@@ -136,3 +132,90 @@ func TestWhenRemovingFromCacheAndItemDoesNotExistReturnFalse(t *testing.T) {
 		t.Errorf("Expected value to not exists when removing")
 	}
 }
+
+func TestFullCacheEvictsTheLeastRecentlyAccessedEntries(t *testing.T) {
+	for _, cacheType := range []CacheType{Blocking, Refresh} {
+		// setup
+		clock := NewFakeClock(time.Unix(1000, 0))
+		full := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{
+			Clock: clock,
+		}).
+			SetCacheType(cacheType).
+			SetMaxSize(10).
+			SetEvictionPercent(20).
+			Build(func(k string) (string, error) {
+				return k, nil
+			})
+
+		// execute
+		for i := 0; i < 10; i++ {
+			full.Put(fmt.Sprint(i), fmt.Sprint(i))
+			clock.Advance(time.Second)
+		}
+		full.Get("0")
+		full.Put("new", "new")
+
+		// verify
+		if full.Len() != 9 {
+			t.Errorf("Expected %v cache to evict 2 entries, got %d entries", cacheType, full.Len())
+		}
+		if !full.Contains("0") || full.Contains("1") || full.Contains("2") {
+			t.Errorf("Expected %v cache to evict the least recently accessed entries", cacheType)
+		}
+	}
+}
+
+func TestCacheSmallerThanTheEvictionPercentStillEvicts(t *testing.T) {
+	// setup
+	small := NewCacheBuilder[string, string]().
+		SetMaxSize(5).
+		Build(func(k string) (string, error) {
+			return k, nil
+		})
+
+	// execute
+	for i := 0; i < 20; i++ {
+		small.Put(fmt.Sprint(i), fmt.Sprint(i))
+	}
+
+	// verify
+	if small.Len() > 5 {
+		t.Errorf("Expected the cache to stay at its max size of 5, got %d entries", small.Len())
+	}
+}
+
+func TestComputesThatDoNotAddAnEntryDoNotEvict(t *testing.T) {
+	for _, cacheType := range []CacheType{Blocking, Refresh} {
+		// setup
+		fullCache := NewCacheBuilder[string, string]().
+			SetCacheType(cacheType).
+			SetMaxSize(2).
+			Build(func(k string) (string, error) {
+				return "loaded " + k, nil
+			})
+		fullCache.Put("a", "1")
+		fullCache.Put("b", "2")
+
+		// execute
+		fullCache.ComputeIfPresent("missing", func(k string, old string) (string, Action) {
+			return old, Store
+		})
+		fullCache.Compute("a", func(old string, exists bool) (string, Action) {
+			return old, Keep
+		})
+		fullCache.PutIfAbsent("b", "3")
+		keptBoth := fullCache.Contains("a") && fullCache.Contains("b")
+		fullCache.ComputeIfAbsent("c", func(k string) (string, bool) {
+			return "3", true
+		})
+
+		// verify
+		if !keptBoth {
+			t.Errorf("Expected %s cache not to evict for computes that do not add an entry, got %v", cacheType, fullCache.Keys())
+		}
+		if fullCache.Len() != 2 || !fullCache.Contains("c") {
+			t.Errorf("Expected %s cache to make room for the added 'c', got %v", cacheType, fullCache.Keys())
+		}
+		fullCache.Close()
+	}
+}
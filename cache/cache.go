@@ -1,6 +1,11 @@
 package cache
 
-import "time"
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
 
 type CacheType int
 
@@ -11,6 +16,29 @@ const (
 	Refresh CacheType = 1
 )
 
+func (c CacheType) String() string {
+	switch c {
+	case Blocking:
+		return "blocking"
+	case Refresh:
+		return "refresh"
+	default:
+		return "unknown"
+	}
+}
+
+// Action tells a compute operation what to do with the entry after the compute function returns.
+type Action int
+
+const (
+	// Store the value returned by the compute function in the cache.
+	Store Action = 0
+	// Delete the entry from the cache, the returned value is ignored.
+	Delete Action = 1
+	// Keep leaves the cache as it is, the returned value is ignored.
+	Keep Action = 2
+)
+
 type CacheInfo[K comparable, V any] struct {
 	// MaxSize is the maximum number of entries that the cache can hold. If the cache already has more entries than the new maximum size, the cache will evict entries until the size is less than or equal to the new maximum size.
 	MaxSize *int
@@ -24,6 +52,46 @@ type CacheInfo[K comparable, V any] struct {
 	EvictionPercent *int
 	// Hooks are hooks that can be set on a cache to be called when certain events occur.
 	Hooks CacheHooks[K]
+	// CircuitBreaker is the configuration of the circuit breaker around the cache loader. If nil the loader is always called on a miss.
+	CircuitBreaker *CircuitBreakerConfig
+	// Executor runs the background reloads of a refresh cache. If nil the cache will use its own bounded executor.
+	Executor Executor
+	// LoadTimeout is the maximum time to wait for the cache loader. If the loader takes longer the load fails with ErrLoadTimeout. 0 means no timeout.
+	LoadTimeout time.Duration
+	// ServeExpiredOnLoadTimeout will return the expired value of an entry, if there is one, when reloading it timed out.
+	ServeExpiredOnLoadTimeout bool
+	// HedgeDelay is the time to wait for the cache loader before a second call is made for the same key, the first successful result is used. 0 means no hedging.
+	HedgeDelay time.Duration
+	// ValueEqual is used by CompareAndSwap and CompareAndDelete to compare values. If nil values are compared with == when possible and reflect.DeepEqual otherwise.
+	ValueEqual func(a V, b V) bool
+	// Codec is used to encode keys and values when entries leave memory, for example in snapshots. If nil a GobCodec is used.
+	Codec Codec[K, V]
+	// SnapshotFile is the file the cache is restored from when it is built and snapshotted to when it is closed. If empty no snapshot file is used.
+	SnapshotFile string
+	// SnapshotInterval is the interval to write a snapshot to the SnapshotFile while the cache is running. 0 means the snapshot is only written when the cache is closed.
+	SnapshotInterval time.Duration
+	// Storage is the second tier of the cache. Evicted entries are put in the storage and it is checked on a miss before the loader is called. If nil evicted entries are dropped.
+	Storage Storage[K, V]
+	// RemoteStore is a store shared with other instances of the cache. It is checked before the loader is called and loaded values are written to it. If nil no remote store is used.
+	RemoteStore RemoteStore[K, V]
+	// RemoteTTL is how long values written to the remote store are kept. If 0 the Expiration of the cache is used.
+	RemoteTTL time.Duration
+	// InvalidationBus publishes the keys removed or put on this instance of the cache and removes the keys published by other instances. If nil invalidations are not shared.
+	InvalidationBus InvalidationBus[K]
+	// Writer writes the values put in the cache and deletes the keys removed from the cache in the system of record. If nil changes only update the cache.
+	Writer CacheWriter[K, V]
+	// WriterConfig is the configuration of the Writer, for example write-through or write-behind.
+	WriterConfig WriterConfig
+	// Name is the name the cache is registered with in the Registry. If empty the cache is not registered.
+	Name string
+	// Registry is the registry a named cache is registered in. If nil the DefaultRegistry is used.
+	Registry *Registry
+	// KeyParser parses the keys given as strings to a registered cache, for example by an admin handler. If nil string keys are used as they are and other keys are parsed with fmt.Sscan.
+	KeyParser func(key string) (K, error)
+	// Logger logs failed and slow loads, evictions and background reloads. If nil nothing is logged.
+	Logger *slog.Logger
+	// LogConfig is the configuration of the levels and key redaction of the Logger.
+	LogConfig LogConfig
 }
 
 // CacheHooks are hooks that can be set on a cache to be called when certain events occur.
@@ -33,10 +101,34 @@ type CacheHooks[K comparable] struct {
 	OnFailedToLoadEntry func(k K)
 	OnCacheRemove       func(k K)
 	OnCacheLoadDuration func(k K, duration time.Duration)
+	// OnCircuitBreakerStateChange is called when the circuit breaker around the cache loader changes state
+	OnCircuitBreakerStateChange func(from CircuitBreakerState, to CircuitBreakerState)
+	// OnRefreshRejected is called when the background reload of an expired entry was rejected by the executor
+	OnRefreshRejected func(k K)
+	// OnSnapshotError is called when the snapshot file could not be restored or written
+	OnSnapshotError func(err error)
+	// OnStorageHit is called when an entry missing from the cache was found in the second tier storage
+	OnStorageHit func(k K)
+	// OnStorageError is called when the second tier storage returns an error
+	OnStorageError func(err error)
+	// OnRemoteStoreHit is called when a value that had to be loaded was found in the remote store
+	OnRemoteStoreHit func(k K)
+	// OnRemoteStoreError is called when the remote store returns an error
+	OnRemoteStoreError func(err error)
+	// OnInvalidationError is called when an invalidation could not be published or the cache could not subscribe to the invalidation bus
+	OnInvalidationError func(err error)
+	// OnWriteError is called when a change to the key could not be written by the cache writer after all retries
+	OnWriteError func(k K, err error)
 }
 
+// GetEvictionSize returns the number of entries removed when the cache is full. It is at least 1, so a full cache never grows past its max size
+// when the eviction percent of the max size rounds down to 0.
 func (cacheInfo CacheInfo[K, V]) GetEvictionSize() int {
-	return *cacheInfo.MaxSize * *cacheInfo.EvictionPercent / 100
+	evictionSize := *cacheInfo.MaxSize * *cacheInfo.EvictionPercent / 100
+	if evictionSize < 1 {
+		return 1
+	}
+	return evictionSize
 }
 
 // Load will be called by the cache when a key is not found in the cache. The loader should return the value associated with the key, or an error if the value could not be loaded.
@@ -53,6 +145,61 @@ type CacheBuilder[K comparable, V any] interface {
 	SetCacheType(cacheType CacheType) CacheBuilder[K, V]
 	// SetEvictionPercent sets the percent of the max size to delete when the cache is full
 	SetEvictionPercent(evictionPercent int) CacheBuilder[K, V]
+	// SetHooks sets the hooks that will be called when certain events occur in the cache.
+	SetHooks(hooks CacheHooks[K]) CacheBuilder[K, V]
+	// SetCircuitBreaker wraps the cache loader in a circuit breaker. While the circuit is open misses fail fast without calling the loader and expired values are served if available.
+	// Zero values in the config are replaced with defaults. Defaults to no circuit breaker
+	SetCircuitBreaker(config CircuitBreakerConfig) CacheBuilder[K, V]
+	// SetExecutor sets the executor used to reload expired entries in the background for a refresh cache.
+	// Defaults to a bounded executor with 10 workers and a queue of 100 reloads
+	SetExecutor(executor Executor) CacheBuilder[K, V]
+	// SetLoadTimeout sets the maximum time to wait for the cache loader. A load that takes longer is abandoned and fails with ErrLoadTimeout.
+	// Defaults to 0 (no timeout)
+	SetLoadTimeout(timeout time.Duration) CacheBuilder[K, V]
+	// SetServeExpiredOnLoadTimeout will return the expired value of an entry, if there is one, when reloading it timed out instead of failing the get.
+	// Defaults to false
+	SetServeExpiredOnLoadTimeout(serveExpired bool) CacheBuilder[K, V]
+	// SetHedgeDelay sets the time to wait for the cache loader before making a second call for the same key. Whichever call succeeds first is used.
+	// Defaults to 0 (no hedging)
+	SetHedgeDelay(delay time.Duration) CacheBuilder[K, V]
+	// SetValueEqual sets the function used by CompareAndSwap and CompareAndDelete to compare values, for example for values that can not be compared with ==.
+	// Defaults to == when the values are comparable and reflect.DeepEqual otherwise
+	SetValueEqual(equal func(a V, b V) bool) CacheBuilder[K, V]
+	// SetCodec sets the codec used to encode keys and values when entries leave memory, for example in snapshots.
+	// Defaults to GobCodec
+	SetCodec(codec Codec[K, V]) CacheBuilder[K, V]
+	// SetSnapshotFile restores the cache from the file when it is built, if the file exists, and writes a snapshot to the file when the cache is closed.
+	// If interval is more than 0 a snapshot is also written every interval. Defaults to no snapshot file
+	SetSnapshotFile(path string, interval time.Duration) CacheBuilder[K, V]
+	// SetStorage sets a second tier for the cache, for example a FileStorage. Evicted entries are put in the storage and it is checked on a miss before the loader is called.
+	// The storage is not closed with the cache. Defaults to no storage
+	SetStorage(storage Storage[K, V]) CacheBuilder[K, V]
+	// SetRemoteStore sets a store shared with other instances of the cache, for example redis. The remote store is checked before the loader is called,
+	// loaded, put and updated values are written to it with the ttl and removed keys are deleted from it. If ttl is 0 the expiration of the cache is used. Defaults to no remote store
+	SetRemoteStore(store RemoteStore[K, V], ttl time.Duration) CacheBuilder[K, V]
+	// SetInvalidationBus shares invalidations with other instances of the cache. Keys removed or put on this instance are published on the bus,
+	// and keys published by other instances are removed from this instance. Defaults to no invalidation bus
+	SetInvalidationBus(bus InvalidationBus[K]) CacheBuilder[K, V]
+	// SetWriter propagates Put, Remove, RemoveAll and the conditional updates like Compute and CompareAndSwap to the system of record through the writer,
+	// either synchronously or in the background depending on the write mode. Conditional updates are written after the cache was updated,
+	// if that write-through write fails the entry is removed from the cache.
+	// Pending background writes are flushed when the cache is closed. Zero values in the config are replaced with defaults. Defaults to no writer
+	SetWriter(writer CacheWriter[K, V], config WriterConfig) CacheBuilder[K, V]
+	// SetName registers the cache with the name in the registry until it is closed, building a second cache with the name of a registered cache panics.
+	// Named caches count their hits, misses, loads and removals. Defaults to no name
+	SetName(name string) CacheBuilder[K, V]
+	// SetRegistry sets the registry a named cache is registered in.
+	// Defaults to DefaultRegistry
+	SetRegistry(registry *Registry) CacheBuilder[K, V]
+	// SetKeyParser sets the parser for keys given as strings to a registered cache, for example by an admin handler.
+	// Defaults to string keys as they are and fmt.Sscan for other keys
+	SetKeyParser(parser func(key string) (K, error)) CacheBuilder[K, V]
+	// SetLogger logs failed and slow loads, evictions and background reloads of the cache as structured events, named caches add their name to every event.
+	// Defaults to no logging
+	SetLogger(logger *slog.Logger) CacheBuilder[K, V]
+	// SetLogConfig sets the levels of the logged events, the threshold for slow loads and how keys are redacted.
+	// Zero values in the config are replaced with defaults. Defaults to failed loads at warn and evictions and reloads at debug
+	SetLogConfig(config LogConfig) CacheBuilder[K, V]
 	// Build creates a new cache with the specified loader and configuration.
 	Build(loader CacheLoader[K, V]) Cache[K, V]
 }
@@ -63,8 +210,64 @@ type Cache[K comparable, V any] interface {
 	Get(k K) (V, bool)
 	// Put inserts a value into the cache associated with the key k. If the key already exists, the value will be updated.
 	// The return value will be true if the value was inserted, and false if the value was updated.
+	// With a write-through writer the cache is only updated if the write succeeded, otherwise false is returned.
 	Put(k K, v V) bool
 	// Remove removes the value associated with the key k from the cache.
 	// The return value will be true if the value was removed, and false if the value was not found.
+	// With a write-through writer the value is only removed if the delete succeeded, otherwise false is returned.
 	Remove(k K) bool
+	// GetIfPresent returns the value associated with the key k if it is in the cache and not expired. The value is never loaded and no hit or miss hooks are called.
+	GetIfPresent(k K) (V, bool)
+	// Contains returns true if the key k is in the cache and not expired.
+	Contains(k K) bool
+	// Len returns the number of entries in the cache that are not expired.
+	Len() int
+	// Keys returns the keys of the entries in the cache that are not expired, in no particular order.
+	Keys() []K
+	// Range calls f for every entry in the cache that is not expired until f returns false. f is called on a snapshot of the entries, so it is safe to use the cache from f.
+	Range(f func(k K, v V) bool)
+	// RemoveAll removes the values associated with the given keys from the cache.
+	// The return value will be the number of values that were removed. With a write-through writer the values whose delete failed are kept.
+	RemoveAll(keys []K) int
+	// InvalidateAll removes every entry from the cache, calling the OnCacheRemove hook for each of them.
+	InvalidateAll()
+	// Clear removes every entry from the cache without calling any hooks.
+	Clear()
+	// Compute atomically computes a new value for the key k from its current value. exists is false if the key is not in the cache or is expired.
+	// The action returned by f decides if the value is stored, the entry is deleted or the cache is left as it is.
+	// f is called while holding the lock of the cache and must not use the cache. The return value is the value associated with k afterwards and if there is one.
+	Compute(k K, f func(old V, exists bool) (V, Action)) (V, bool)
+	// ComputeIfAbsent atomically stores the value returned by f if the key k is not in the cache or is expired. Nothing is stored if f returns false.
+	// f is called while holding the lock of the cache and must not use the cache. The return value is the value associated with k afterwards and if there is one.
+	ComputeIfAbsent(k K, f func(k K) (V, bool)) (V, bool)
+	// ComputeIfPresent atomically computes a new value for the key k if it is in the cache and not expired.
+	// f is called while holding the lock of the cache and must not use the cache. The return value is the value associated with k afterwards and if there is one.
+	ComputeIfPresent(k K, f func(k K, old V) (V, Action)) (V, bool)
+	// Merge atomically stores v if the key k is not in the cache or is expired, otherwise the value is computed by f from the current value and v.
+	// f is called while holding the lock of the cache and must not use the cache. The return value is the value associated with k afterwards and if there is one.
+	Merge(k K, v V, f func(old V, v V) (V, Action)) (V, bool)
+	// PutIfAbsent inserts v if the key k is not in the cache or is expired.
+	// If the key is in the cache the existing value is returned with loaded true, otherwise v is returned with loaded false.
+	// If the write-through of v by the writer fails v is removed again and the zero value is returned with loaded true, v was not stored.
+	PutIfAbsent(k K, v V) (existing V, loaded bool)
+	// Replace updates the value associated with the key k only if it is in the cache and not expired.
+	// The return value is the previous value and if it was replaced.
+	Replace(k K, v V) (V, bool)
+	// CompareAndSwap updates the value associated with the key k to new only if its current value is equal to old.
+	// The return value will be true if the value was swapped.
+	CompareAndSwap(k K, old V, new V) bool
+	// CompareAndDelete removes the entry for the key k only if its current value is equal to old.
+	// The return value will be true if the entry was removed.
+	CompareAndDelete(k K, old V) bool
+	// Snapshot writes all entries of the cache, including expired entries, with their timestamps to w using the codec of the cache.
+	Snapshot(w io.Writer) error
+	// Restore reads entries written by Snapshot from r into the cache keeping their timestamps, so entries expire as if the cache had never been stopped.
+	// Restored entries replace existing entries unless the existing entry was updated more recently.
+	Restore(r io.Reader) error
+	// Close closes the cache and waits for all in flight loads to finish, see Shutdown.
+	Close() error
+	// Shutdown closes the cache. New loads are rejected with ErrClosed and in flight loads are waited for until the context is done.
+	// If a snapshot file is configured a final snapshot is written. The OnCacheRemove hook is called for every entry left in the cache before the entries are released, gets on a closed cache will not return a value.
+	// The return value will be the context error if the in flight loads did not finish in time, or ErrClosed if the cache was already closed.
+	Shutdown(ctx context.Context) error
 }
@@ -1,6 +1,9 @@
 package cache
 
-import "time"
+import (
+	"log/slog"
+	"time"
+)
 
 const defaultMaxSize = 10
 const defaultEvictionPercent = 10
@@ -44,6 +47,99 @@ func (c *cacheBuilder[K, V]) SetCacheType(cacheType CacheType) CacheBuilder[K, V
 	return c
 }
 
+func (c *cacheBuilder[K, V]) SetHooks(hooks CacheHooks[K]) CacheBuilder[K, V] {
+	c.cacheInfo.Hooks = hooks
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetCircuitBreaker(config CircuitBreakerConfig) CacheBuilder[K, V] {
+	c.cacheInfo.CircuitBreaker = &config
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetExecutor(executor Executor) CacheBuilder[K, V] {
+	c.cacheInfo.Executor = executor
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetLoadTimeout(timeout time.Duration) CacheBuilder[K, V] {
+	c.cacheInfo.LoadTimeout = timeout
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetServeExpiredOnLoadTimeout(serveExpired bool) CacheBuilder[K, V] {
+	c.cacheInfo.ServeExpiredOnLoadTimeout = serveExpired
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetHedgeDelay(delay time.Duration) CacheBuilder[K, V] {
+	c.cacheInfo.HedgeDelay = delay
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetValueEqual(equal func(a V, b V) bool) CacheBuilder[K, V] {
+	c.cacheInfo.ValueEqual = equal
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetCodec(codec Codec[K, V]) CacheBuilder[K, V] {
+	c.cacheInfo.Codec = codec
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetSnapshotFile(path string, interval time.Duration) CacheBuilder[K, V] {
+	c.cacheInfo.SnapshotFile = path
+	c.cacheInfo.SnapshotInterval = interval
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetStorage(storage Storage[K, V]) CacheBuilder[K, V] {
+	c.cacheInfo.Storage = storage
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetRemoteStore(store RemoteStore[K, V], ttl time.Duration) CacheBuilder[K, V] {
+	c.cacheInfo.RemoteStore = store
+	c.cacheInfo.RemoteTTL = ttl
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetInvalidationBus(bus InvalidationBus[K]) CacheBuilder[K, V] {
+	c.cacheInfo.InvalidationBus = bus
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetWriter(writer CacheWriter[K, V], config WriterConfig) CacheBuilder[K, V] {
+	c.cacheInfo.Writer = writer
+	c.cacheInfo.WriterConfig = config
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetName(name string) CacheBuilder[K, V] {
+	c.cacheInfo.Name = name
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetRegistry(registry *Registry) CacheBuilder[K, V] {
+	c.cacheInfo.Registry = registry
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetKeyParser(parser func(key string) (K, error)) CacheBuilder[K, V] {
+	c.cacheInfo.KeyParser = parser
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetLogger(logger *slog.Logger) CacheBuilder[K, V] {
+	c.cacheInfo.Logger = logger
+	return c
+}
+
+func (c *cacheBuilder[K, V]) SetLogConfig(config LogConfig) CacheBuilder[K, V] {
+	c.cacheInfo.LogConfig = config
+	return c
+}
+
 func (c *cacheBuilder[K, V]) Build(loader CacheLoader[K, V]) Cache[K, V] {
 
 	if c.cacheInfo.MaxSize == nil {
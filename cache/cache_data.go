@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"context"
+	"io"
 	"sync"
 	"time"
 )
@@ -9,7 +11,15 @@ type CacheData[K comparable, V any] interface {
 	Cache[K, V]
 	GetSize() int
 	IsExpired(key K, cacheDuration time.Duration) bool
-	RemoveLeastRecentlyAccessed(numToDelete int)
+	// RemoveLeastRecentlyAccessed removes up to numToDelete entries that were accessed the longest time ago and returns the removed entries.
+	RemoveLeastRecentlyAccessed(numToDelete int) []CacheEntry[K, V]
+	// AllKeys returns the keys of all entries in the data store, including expired entries.
+	AllKeys() []K
+	// Entries returns all entries in the data store with their timestamps, including expired entries.
+	Entries() []CacheEntry[K, V]
+	// RestoreEntries puts the entries in the data store keeping their timestamps.
+	// An entry replaces an existing entry unless the existing entry was updated more recently.
+	RestoreEntries(entries []CacheEntry[K, V])
 }
 
 type cacheKey[K comparable] struct {
@@ -29,19 +39,52 @@ type cacheData[K comparable, V any] struct {
 	valueData map[K]V
 	dataLock  *sync.Mutex
 	clock     Clock
+	// expiration is used by the map operations to leave out expired entries, 0 means entries never expire
+	expiration time.Duration
+	// valueEqual compares values for CompareAndSwap and CompareAndDelete
+	valueEqual func(a V, b V) bool
+	// codec encodes entries for snapshots
+	codec Codec[K, V]
+	// closed data stores have released their entries and ignore puts
+	closed bool
 }
 
 func NewCacheData[K comparable, V any](clockVar Clock) CacheData[K, V] {
 	return &cacheData[K, V]{
-		keyData:   make(map[K]*cacheKey[K]),
-		valueData: make(map[K]V),
-		dataLock:  &sync.Mutex{},
-		clock:     clockVar,
+		keyData:    make(map[K]*cacheKey[K]),
+		valueData:  make(map[K]V),
+		dataLock:   &sync.Mutex{},
+		clock:      clockVar,
+		valueEqual: defaultValueEqual[V],
+		codec:      GobCodec[K, V]{},
 	}
 
 }
 
+// NewCacheDataFromInfo creates a data store that uses the expiration of the cache info for the map operations (GetIfPresent, Contains, Len, Keys and Range)
+// the ValueEqual of the cache info for CompareAndSwap and CompareAndDelete, and the Codec of the cache info for snapshots.
+func NewCacheDataFromInfo[K comparable, V any](cacheInfo CacheInfo[K, V], clockVar Clock) CacheData[K, V] {
+	data := &cacheData[K, V]{
+		keyData:    make(map[K]*cacheKey[K]),
+		valueData:  make(map[K]V),
+		dataLock:   &sync.Mutex{},
+		clock:      clockVar,
+		expiration: cacheInfo.Expiration,
+		valueEqual: defaultValueEqual[V],
+		codec:      GobCodec[K, V]{},
+	}
+	if cacheInfo.ValueEqual != nil {
+		data.valueEqual = cacheInfo.ValueEqual
+	}
+	if cacheInfo.Codec != nil {
+		data.codec = cacheInfo.Codec
+	}
+	return data
+}
+
 func (c *cacheData[K, V]) GetSize() int {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
 	return len(c.keyData)
 }
 
@@ -51,6 +94,9 @@ func (c *cacheData[K, V]) IsExpired(key K, cacheDuration time.Duration) bool {
 		return false
 	}
 
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
 	cacheKey, exists := c.keyData[key]
 	if !exists {
 		return false
@@ -59,7 +105,9 @@ func (c *cacheData[K, V]) IsExpired(key K, cacheDuration time.Duration) bool {
 	}
 }
 
-func (c *cacheData[K, V]) RemoveLeastRecentlyAccessed(numToDelete int) {
+func (c *cacheData[K, V]) RemoveLeastRecentlyAccessed(numToDelete int) []CacheEntry[K, V] {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
 
 	// create a list that will maintain order by last accessed
 	sortedList := LinkedSortedList[*cacheKey[K]]{
@@ -75,10 +123,19 @@ func (c *cacheData[K, V]) RemoveLeastRecentlyAccessed(numToDelete int) {
 
 	// remove the keys
 	keysToDelete := sortedList.GetFirstN(numToDelete)
+	removed := make([]CacheEntry[K, V], 0, len(keysToDelete))
 	for _, key := range keysToDelete {
+		removed = append(removed, CacheEntry[K, V]{
+			Key:            key.key,
+			Value:          c.valueData[key.key],
+			InsertTime:     key.insertTime,
+			LastAccessTime: key.lastAccessTime,
+			LastUpdateTime: key.lastUpdateTime,
+		})
 		delete(c.keyData, key.key)
 		delete(c.valueData, key.key)
 	}
+	return removed
 }
 
 func (c *cacheData[K, V]) Get(k K) (V, bool) {
@@ -99,6 +156,13 @@ func (c *cacheData[K, V]) Get(k K) (V, bool) {
 func (c *cacheData[K, V]) Put(k K, v V) bool {
 	c.dataLock.Lock()
 	defer c.dataLock.Unlock()
+	return c.putLocked(k, v)
+}
+
+func (c *cacheData[K, V]) putLocked(k K, v V) bool {
+	if c.closed {
+		return false
+	}
 
 	key, exists := c.keyData[k]
 	if !exists {
@@ -127,3 +191,322 @@ func (c *cacheData[K, V]) Remove(k K) bool {
 	delete(c.valueData, k)
 	return exists
 }
+
+func (c *cacheData[K, V]) AllKeys() []K {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	keys := make([]K, 0, len(c.keyData))
+	for k := range c.keyData {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// isExpiredAt returns true if the entry is expired at the given time using the expiration of the data store
+func (c *cacheData[K, V]) isExpiredAt(key *cacheKey[K], now time.Time) bool {
+	if c.expiration < 1 {
+		return false
+	}
+	return now.After(key.lastUpdateTime.Add(c.expiration))
+}
+
+func (c *cacheData[K, V]) GetIfPresent(k K) (V, bool) {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	now := c.clock.Now()
+	key, exists := c.keyData[k]
+	if !exists || c.isExpiredAt(key, now) {
+		var defaultValue V
+		return defaultValue, false
+	}
+	key.lastAccessTime = now
+	return c.valueData[k], true
+}
+
+func (c *cacheData[K, V]) Contains(k K) bool {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	key, exists := c.keyData[k]
+	return exists && !c.isExpiredAt(key, c.clock.Now())
+}
+
+func (c *cacheData[K, V]) Len() int {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	if c.expiration < 1 {
+		return len(c.keyData)
+	}
+
+	now := c.clock.Now()
+	size := 0
+	for _, key := range c.keyData {
+		if !c.isExpiredAt(key, now) {
+			size++
+		}
+	}
+	return size
+}
+
+func (c *cacheData[K, V]) Keys() []K {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	now := c.clock.Now()
+	keys := make([]K, 0, len(c.keyData))
+	for k, key := range c.keyData {
+		if !c.isExpiredAt(key, now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Range calls f for a snapshot of the entries that are not expired, f is called without holding the lock so it can use the data store
+func (c *cacheData[K, V]) Range(f func(k K, v V) bool) {
+	c.dataLock.Lock()
+	now := c.clock.Now()
+	keys := make([]K, 0, len(c.keyData))
+	values := make([]V, 0, len(c.keyData))
+	for k, key := range c.keyData {
+		if !c.isExpiredAt(key, now) {
+			keys = append(keys, k)
+			values = append(values, c.valueData[k])
+		}
+	}
+	c.dataLock.Unlock()
+
+	for i := range keys {
+		if !f(keys[i], values[i]) {
+			return
+		}
+	}
+}
+
+func (c *cacheData[K, V]) RemoveAll(keys []K) int {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	removed := 0
+	for _, k := range keys {
+		if _, exists := c.keyData[k]; exists {
+			delete(c.keyData, k)
+			delete(c.valueData, k)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (c *cacheData[K, V]) Clear() {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	c.keyData = make(map[K]*cacheKey[K])
+	c.valueData = make(map[K]V)
+}
+
+// InvalidateAll is the same as Clear for a data store, there are no hooks to call
+func (c *cacheData[K, V]) InvalidateAll() {
+	c.Clear()
+}
+
+func (c *cacheData[K, V]) Close() error {
+	return c.Shutdown(context.Background())
+}
+
+// Shutdown releases all entries, puts after the data store is closed are ignored
+func (c *cacheData[K, V]) Shutdown(ctx context.Context) error {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+	c.closed = true
+	c.keyData = make(map[K]*cacheKey[K])
+	c.valueData = make(map[K]V)
+	return nil
+}
+
+func (c *cacheData[K, V]) Compute(k K, f func(old V, exists bool) (V, Action)) (V, bool) {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	var old V
+	key, exists := c.keyData[k]
+	if exists && !c.isExpiredAt(key, c.clock.Now()) {
+		old = c.valueData[k]
+	} else {
+		exists = false
+	}
+
+	value, action := f(old, exists)
+	switch action {
+	case Store:
+		c.putLocked(k, value)
+		if c.closed {
+			var defaultValue V
+			return defaultValue, false
+		}
+		return value, true
+	case Delete:
+		delete(c.keyData, k)
+		delete(c.valueData, k)
+		var defaultValue V
+		return defaultValue, false
+	default:
+		return old, exists
+	}
+}
+
+func (c *cacheData[K, V]) ComputeIfAbsent(k K, f func(k K) (V, bool)) (V, bool) {
+	return c.Compute(k, computeIfAbsent(k, f))
+}
+
+func (c *cacheData[K, V]) ComputeIfPresent(k K, f func(k K, old V) (V, Action)) (V, bool) {
+	return c.Compute(k, computeIfPresent(k, f))
+}
+
+func (c *cacheData[K, V]) Merge(k K, v V, f func(old V, v V) (V, Action)) (V, bool) {
+	return c.Compute(k, merge(v, f))
+}
+
+// computeIfAbsent adapts a ComputeIfAbsent function to a Compute function
+func computeIfAbsent[K comparable, V any](k K, f func(k K) (V, bool)) func(old V, exists bool) (V, Action) {
+	return func(old V, exists bool) (V, Action) {
+		if exists {
+			return old, Keep
+		}
+		value, ok := f(k)
+		if !ok {
+			return value, Keep
+		}
+		return value, Store
+	}
+}
+
+// computeIfPresent adapts a ComputeIfPresent function to a Compute function
+func computeIfPresent[K comparable, V any](k K, f func(k K, old V) (V, Action)) func(old V, exists bool) (V, Action) {
+	return func(old V, exists bool) (V, Action) {
+		if !exists {
+			return old, Keep
+		}
+		return f(k, old)
+	}
+}
+
+// merge adapts a Merge function to a Compute function
+func merge[V any](v V, f func(old V, v V) (V, Action)) func(old V, exists bool) (V, Action) {
+	return func(old V, exists bool) (V, Action) {
+		if !exists {
+			return v, Store
+		}
+		return f(old, v)
+	}
+}
+
+func (c *cacheData[K, V]) PutIfAbsent(k K, v V) (V, bool) {
+	loaded := false
+	value, _ := c.Compute(k, func(old V, exists bool) (V, Action) {
+		if exists {
+			loaded = true
+			return old, Keep
+		}
+		return v, Store
+	})
+	return value, loaded
+}
+
+func (c *cacheData[K, V]) Replace(k K, v V) (V, bool) {
+	var previous V
+	replaced := false
+	c.Compute(k, func(old V, exists bool) (V, Action) {
+		if !exists {
+			return old, Keep
+		}
+		previous = old
+		replaced = true
+		return v, Store
+	})
+	return previous, replaced
+}
+
+func (c *cacheData[K, V]) CompareAndSwap(k K, old V, new V) bool {
+	swapped := false
+	c.Compute(k, func(current V, exists bool) (V, Action) {
+		if !exists || !c.valueEqual(current, old) {
+			return current, Keep
+		}
+		swapped = true
+		return new, Store
+	})
+	return swapped
+}
+
+func (c *cacheData[K, V]) CompareAndDelete(k K, old V) bool {
+	deleted := false
+	c.Compute(k, func(current V, exists bool) (V, Action) {
+		if !exists || !c.valueEqual(current, old) {
+			return current, Keep
+		}
+		deleted = true
+		return current, Delete
+	})
+	return deleted
+}
+
+func (c *cacheData[K, V]) Entries() []CacheEntry[K, V] {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	entries := make([]CacheEntry[K, V], 0, len(c.keyData))
+	for k, key := range c.keyData {
+		entries = append(entries, CacheEntry[K, V]{
+			Key:            k,
+			Value:          c.valueData[k],
+			InsertTime:     key.insertTime,
+			LastAccessTime: key.lastAccessTime,
+			LastUpdateTime: key.lastUpdateTime,
+		})
+	}
+	return entries
+}
+
+func (c *cacheData[K, V]) RestoreEntries(entries []CacheEntry[K, V]) {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	for _, entry := range entries {
+		if existing, exists := c.keyData[entry.Key]; exists && existing.lastUpdateTime.After(entry.LastUpdateTime) {
+			continue
+		}
+		c.keyData[entry.Key] = &cacheKey[K]{
+			key:            entry.Key,
+			insertTime:     entry.InsertTime,
+			lastAccessTime: entry.LastAccessTime,
+			lastUpdateTime: entry.LastUpdateTime,
+		}
+		c.valueData[entry.Key] = entry.Value
+	}
+}
+
+func (c *cacheData[K, V]) Snapshot(w io.Writer) error {
+	return writeSnapshot(w, c.codec, c.Entries())
+}
+
+func (c *cacheData[K, V]) Restore(r io.Reader) error {
+	entries, err := readSnapshot(r, c.codec)
+	if err != nil {
+		return err
+	}
+	c.RestoreEntries(entries)
+	return nil
+}
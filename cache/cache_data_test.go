@@ -0,0 +1,363 @@
+package cache
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func buildExpiringTestCache(clock Clock) Cache[string, string] {
+	return NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{
+		Clock: clock,
+	}).
+		SetExpiration(time.Second).
+		Build(func(k string) (string, error) {
+			return "loaded", nil
+		})
+}
+
+func TestGetIfPresentDoesNotLoadOrCallHooks(t *testing.T) {
+	// setup
+	hits := 0
+	misses := 0
+	initTests()
+	presentCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{
+		Clock: LocalClock{},
+	}).
+		SetHooks(CacheHooks[string]{
+			OnCacheHit:  func(k string) { hits++ },
+			OnCacheMiss: func(k string) { misses++ },
+		}).
+		Build(cacheLoader.Load)
+	presentCache.Put("key", "value")
+
+	// execute
+	value, exists := presentCache.GetIfPresent("key")
+	_, missingExists := presentCache.GetIfPresent("missing")
+
+	// verify
+	if !exists || value != "value" {
+		t.Errorf("Expected value to be 'value'")
+	}
+	if missingExists {
+		t.Errorf("Expected missing key not to exist")
+	}
+	if len(cacheLoader.KeysRequests) != 0 {
+		t.Errorf("Expected loader not to be called")
+	}
+	if hits != 0 || misses != 0 {
+		t.Errorf("Expected no hit or miss hooks to be called")
+	}
+}
+
+func TestMapOperationsLeaveOutExpiredEntries(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	expiringCache := buildExpiringTestCache(clock)
+	expiringCache.Put("old", "value")
+	clock.Set(time.Unix(1002, 0))
+	expiringCache.Put("new", "value")
+
+	// execute
+	_, oldPresent := expiringCache.GetIfPresent("old")
+	oldContained := expiringCache.Contains("old")
+	newContained := expiringCache.Contains("new")
+	size := expiringCache.Len()
+	keys := expiringCache.Keys()
+	ranged := make([]string, 0)
+	expiringCache.Range(func(k string, v string) bool {
+		ranged = append(ranged, k)
+		return true
+	})
+
+	// verify
+	if oldPresent || oldContained {
+		t.Errorf("Expected expired entry not to be present")
+	}
+	if !newContained {
+		t.Errorf("Expected new entry to be present")
+	}
+	if size != 1 {
+		t.Errorf("Expected length to be 1, got %d", size)
+	}
+	if len(keys) != 1 || keys[0] != "new" {
+		t.Errorf("Expected keys to only contain 'new'")
+	}
+	if len(ranged) != 1 || ranged[0] != "new" {
+		t.Errorf("Expected range to only visit 'new'")
+	}
+}
+
+func TestRangeStopsWhenFunctionReturnsFalse(t *testing.T) {
+	// setup
+	initTests()
+	cache.Put("a", "value")
+	cache.Put("b", "value")
+	cache.Put("c", "value")
+
+	// execute
+	visited := 0
+	cache.Range(func(k string, v string) bool {
+		visited++
+		// using the cache while ranging should not deadlock
+		cache.Contains(k)
+		return false
+	})
+
+	// verify
+	if visited != 1 {
+		t.Errorf("Expected range to stop after the first entry, visited %d", visited)
+	}
+}
+
+func TestRemoveAllReturnsTheNumberOfRemovedEntries(t *testing.T) {
+	// setup
+	initTests()
+	cache.Put("a", "value")
+	cache.Put("b", "value")
+	cache.Put("c", "value")
+
+	// execute
+	removed := cache.RemoveAll([]string{"a", "b", "missing"})
+
+	// verify
+	if removed != 2 {
+		t.Errorf("Expected 2 entries to be removed, got %d", removed)
+	}
+	if keys := cache.Keys(); len(keys) != 1 || keys[0] != "c" {
+		t.Errorf("Expected only 'c' to be left")
+	}
+}
+
+func TestInvalidateAllCallsRemoveHookAndClearDoesNot(t *testing.T) {
+	// setup
+	removed := make([]string, 0)
+	hookCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{
+		Clock: LocalClock{},
+	}).
+		SetHooks(CacheHooks[string]{
+			OnCacheRemove: func(k string) {
+				removed = append(removed, k)
+			},
+		}).
+		Build(func(k string) (string, error) {
+			return "value", nil
+		})
+	hookCache.Put("a", "value")
+	hookCache.Put("b", "value")
+
+	// execute
+	hookCache.InvalidateAll()
+	invalidatedSize := hookCache.Len()
+	hookCache.Put("c", "value")
+	hookCache.Clear()
+
+	// verify
+	sort.Strings(removed)
+	if len(removed) != 2 || removed[0] != "a" || removed[1] != "b" {
+		t.Errorf("Expected remove hook to be called for 'a' and 'b', got %v", removed)
+	}
+	if invalidatedSize != 0 || hookCache.Len() != 0 {
+		t.Errorf("Expected cache to be empty")
+	}
+}
+
+func TestConcurrentMergesDoNotLoseUpdates(t *testing.T) {
+	// setup
+	counterCache := NewCacheBuilder[string, int]().
+		Build(func(k string) (int, error) {
+			return 0, nil
+		})
+	done := make(chan struct{})
+
+	// execute
+	for i := 0; i < 20; i++ {
+		go func() {
+			for j := 0; j < 100; j++ {
+				counterCache.Merge("counter", 1, func(old int, v int) (int, Action) {
+					return old + v, Store
+				})
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		<-done
+	}
+	counter, _ := counterCache.GetIfPresent("counter")
+
+	// verify
+	if counter != 2000 {
+		t.Errorf("Expected counter to be 2000, got %d", counter)
+	}
+}
+
+func TestComputeActions(t *testing.T) {
+	// setup
+	removed := make([]string, 0)
+	computeCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{
+		Clock: LocalClock{},
+	}).
+		SetHooks(CacheHooks[string]{
+			OnCacheRemove: func(k string) {
+				removed = append(removed, k)
+			},
+		}).
+		Build(func(k string) (string, error) {
+			return "loaded", nil
+		})
+	computeCache.Put("key", "value")
+
+	// execute
+	stored, storedExists := computeCache.Compute("key", func(old string, exists bool) (string, Action) {
+		return old + "-updated", Store
+	})
+	kept, keptExists := computeCache.Compute("key", func(old string, exists bool) (string, Action) {
+		return "ignored", Keep
+	})
+	_, deletedExists := computeCache.Compute("key", func(old string, exists bool) (string, Action) {
+		return "", Delete
+	})
+
+	// verify
+	if !storedExists || stored != "value-updated" {
+		t.Errorf("Expected value to be 'value-updated', got '%s'", stored)
+	}
+	if !keptExists || kept != "value-updated" {
+		t.Errorf("Expected value to be kept, got '%s'", kept)
+	}
+	if deletedExists || computeCache.Contains("key") {
+		t.Errorf("Expected entry to be deleted")
+	}
+	if len(removed) != 1 || removed[0] != "key" {
+		t.Errorf("Expected remove hook to be called for 'key'")
+	}
+}
+
+func TestComputeIfAbsentAndComputeIfPresent(t *testing.T) {
+	// setup
+	initTests()
+	calls := 0
+
+	// execute
+	absentValue, absentExists := cache.ComputeIfAbsent("key", func(k string) (string, bool) {
+		calls++
+		return "computed", true
+	})
+	secondValue, _ := cache.ComputeIfAbsent("key", func(k string) (string, bool) {
+		calls++
+		return "other", true
+	})
+	_, notStoredExists := cache.ComputeIfAbsent("missing", func(k string) (string, bool) {
+		return "", false
+	})
+	_, presentExists := cache.ComputeIfPresent("other", func(k string, old string) (string, Action) {
+		calls++
+		return "value", Store
+	})
+
+	// verify
+	if !absentExists || absentValue != "computed" || secondValue != "computed" {
+		t.Errorf("Expected value to be 'computed'")
+	}
+	if notStoredExists || cache.Contains("missing") {
+		t.Errorf("Expected nothing to be stored when the function returns false")
+	}
+	if presentExists || cache.Contains("other") {
+		t.Errorf("Expected nothing to be stored for an absent key")
+	}
+	if calls != 1 {
+		t.Errorf("Expected compute functions to be called once, got %d", calls)
+	}
+}
+
+func TestPutIfAbsentOnlyInsertsMissingKeys(t *testing.T) {
+	// setup
+	initTests()
+	cache.Put("key", "value")
+
+	// execute
+	existing, loaded := cache.PutIfAbsent("key", "other")
+	inserted, insertedLoaded := cache.PutIfAbsent("new", "other")
+
+	// verify
+	if !loaded || existing != "value" {
+		t.Errorf("Expected existing value 'value' to be returned")
+	}
+	if insertedLoaded || inserted != "other" {
+		t.Errorf("Expected 'other' to be inserted")
+	}
+	if value, _ := cache.GetIfPresent("new"); value != "other" {
+		t.Errorf("Expected 'new' to be 'other'")
+	}
+}
+
+func TestReplaceOnlyUpdatesExistingKeys(t *testing.T) {
+	// setup
+	initTests()
+	cache.Put("key", "value")
+
+	// execute
+	previous, replaced := cache.Replace("key", "other")
+	_, missingReplaced := cache.Replace("missing", "other")
+
+	// verify
+	if !replaced || previous != "value" {
+		t.Errorf("Expected 'value' to be replaced")
+	}
+	if missingReplaced || cache.Contains("missing") {
+		t.Errorf("Expected missing key not to be inserted")
+	}
+}
+
+func TestCompareAndSwapAndCompareAndDelete(t *testing.T) {
+	// setup
+	initTests()
+	cache.Put("key", "value")
+
+	// execute
+	wrongSwap := cache.CompareAndSwap("key", "wrong", "other")
+	swapped := cache.CompareAndSwap("key", "value", "other")
+	wrongDelete := cache.CompareAndDelete("key", "value")
+	deleted := cache.CompareAndDelete("key", "other")
+
+	// verify
+	if wrongSwap || wrongDelete {
+		t.Errorf("Expected operations with the wrong old value to fail")
+	}
+	if !swapped || !deleted {
+		t.Errorf("Expected operations with the current value to succeed")
+	}
+	if cache.Contains("key") {
+		t.Errorf("Expected entry to be deleted")
+	}
+}
+
+func TestCompareAndSwapWorksForValuesThatAreNotComparable(t *testing.T) {
+	// setup
+	sliceCache := NewCacheBuilder[string, []string]().
+		Build(func(k string) ([]string, error) {
+			return nil, nil
+		})
+	lengthCache := NewCacheBuilder[string, []string]().
+		SetValueEqual(func(a []string, b []string) bool {
+			return len(a) == len(b)
+		}).
+		Build(func(k string) ([]string, error) {
+			return nil, nil
+		})
+	sliceCache.Put("key", []string{"a"})
+	lengthCache.Put("key", []string{"a"})
+
+	// execute
+	swapped := sliceCache.CompareAndSwap("key", []string{"a"}, []string{"b"})
+	lengthSwapped := lengthCache.CompareAndSwap("key", []string{"z"}, []string{"b"})
+
+	// verify
+	if !swapped {
+		t.Errorf("Expected equal slices to be swapped")
+	}
+	if !lengthSwapped {
+		t.Errorf("Expected custom equality to be used")
+	}
+}
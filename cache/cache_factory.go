@@ -14,25 +14,103 @@ func NewCacheTypeFactory[K comparable, V any]() CacheTypeCacheFactory[K, V] {
 	}
 }
 
+// BuildCache builds the cache of the configured type with its tiers.
+// The tiers of features that are not configured, like the stats counter, the registration, the snapshotter, the remote store or the writer, are nil.
+// Their methods do nothing on a nil receiver, and a nil circuit breaker allows every load, so the caches call them unconditionally.
 func (c CacheTypeCacheFactory[K, V]) BuildCache(cacheInfo CacheInfo[K, V]) Cache[K, V] {
+	// named caches are registered with counters wrapped around their hooks
+	var counter *statsCounter[K]
+	if cacheInfo.Name != "" {
+		counter = &statsCounter[K]{}
+		cacheInfo.Hooks = counter.wrap(cacheInfo.Hooks)
+	}
+
+	// the name is reserved before anything is started, so a duplicate name panics without leaving a writer or subscription behind
+	registration := newRegistration(cacheInfo)
+	registration.reserve()
+
+	cacheData := NewCacheDataFromInfo[K, V](cacheInfo, c.Clock)
+	snapshotter := newSnapshotter(cacheInfo.SnapshotFile, cacheInfo.SnapshotInterval, c.Clock, cacheInfo.Hooks.OnSnapshotError)
+	invalidations := newInvalidationTier(cacheInfo)
+	built := c.buildCacheType(cacheInfo, cacheData, snapshotter, invalidations, registration, counter)
+	snapshotter.restore(built.Restore)
+	snapshotter.start(built.Snapshot)
+	invalidations.subscribe(built.(invalidator[K]).invalidate)
+	registration.register(&registeredCache[K, V]{
+		cacheInfo: cacheInfo,
+		cache:     built,
+		cacheData: cacheData,
+		counter:   counter,
+	})
+	return built
+}
+
+// invalidator - a cache that can apply the invalidations published by other instances
+type invalidator[K comparable] interface {
+	invalidate(invalidation Invalidation[K])
+}
+
+func (c CacheTypeCacheFactory[K, V]) buildCacheType(cacheInfo CacheInfo[K, V], cacheData CacheData[K, V], snapshotter *snapshotter,
+	invalidations *invalidationTier[K], registration *registration, counter *statsCounter[K]) Cache[K, V] {
+	breaker := newCircuitBreaker(cacheInfo.CircuitBreaker, c.Clock, cacheInfo.Hooks.OnCircuitBreakerStateChange)
+	executor := cacheInfo.Executor
+	ownsExecutor := false
+	if executor == nil {
+		executor = NewBoundedExecutor(defaultExecutorWorkers, defaultExecutorQueueSize)
+		ownsExecutor = true
+	}
 	switch cacheInfo.CacheType {
 	case Refresh:
 		return &refreshingExpiredCache[K, V]{
-			cacheInfo: cacheInfo,
-			cacheData: NewCacheData[K, V](c.Clock),
-			clock:     c.Clock,
+			cacheInfo:      cacheInfo,
+			cacheData:      cacheData,
+			circuitBreaker: breaker,
+			executor:       executor,
+			ownsExecutor:   ownsExecutor,
+			lifecycle:      newLifecycle(),
+			snapshotter:    snapshotter,
+			storage:        newStorageTier(cacheInfo),
+			remote:         newRemoteTier(cacheInfo),
+			invalidations:  invalidations,
+			writer:         newCacheWriter(cacheInfo, c.Clock),
+			registration:   registration,
+			stats:          counter,
+			logger:         newCacheLogger(cacheInfo),
+			clock:          c.Clock,
 		}
 	case Blocking:
 		return &blockingExpiredCache[K, V]{
-			cacheInfo: cacheInfo,
-			cacheData: NewCacheData[K, V](c.Clock),
-			clock:     c.Clock,
+			cacheInfo:      cacheInfo,
+			cacheData:      cacheData,
+			circuitBreaker: breaker,
+			lifecycle:      newLifecycle(),
+			snapshotter:    snapshotter,
+			storage:        newStorageTier(cacheInfo),
+			remote:         newRemoteTier(cacheInfo),
+			invalidations:  invalidations,
+			writer:         newCacheWriter(cacheInfo, c.Clock),
+			registration:   registration,
+			stats:          counter,
+			logger:         newCacheLogger(cacheInfo),
+			clock:          c.Clock,
 		}
 	default:
 		return &refreshingExpiredCache[K, V]{
-			cacheInfo: cacheInfo,
-			cacheData: NewCacheData[K, V](c.Clock),
-			clock:     c.Clock,
+			cacheInfo:      cacheInfo,
+			cacheData:      cacheData,
+			circuitBreaker: breaker,
+			executor:       executor,
+			ownsExecutor:   ownsExecutor,
+			lifecycle:      newLifecycle(),
+			snapshotter:    snapshotter,
+			storage:        newStorageTier(cacheInfo),
+			remote:         newRemoteTier(cacheInfo),
+			invalidations:  invalidations,
+			writer:         newCacheWriter(cacheInfo, c.Clock),
+			registration:   registration,
+			stats:          counter,
+			logger:         newCacheLogger(cacheInfo),
+			clock:          c.Clock,
 		}
 	}
 }
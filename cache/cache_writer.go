@@ -0,0 +1,324 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultWriteBatchSize = 100
+const defaultFlushInterval = time.Second
+const defaultWriteRetries = 3
+const defaultRetryBackoff = time.Millisecond * 100
+
+// CacheWriter writes the values put in a cache and deletes the keys removed from a cache in the system of record, for example a database.
+// Implementations have to be safe for concurrent use.
+type CacheWriter[K comparable, V any] interface {
+	// Write stores the value for the key k in the system of record.
+	Write(k K, v V) error
+	// Delete removes the key k from the system of record.
+	Delete(k K) error
+}
+
+// BatchCacheWriter is a CacheWriter that can write several changes with one call. A write-behind cache flushes each batch with a single WriteBatch call.
+type BatchCacheWriter[K comparable, V any] interface {
+	CacheWriter[K, V]
+	// WriteBatch stores the writes and removes the deletes in the system of record, a key is never in both.
+	WriteBatch(writes map[K]V, deletes []K) error
+}
+
+type WriteMode int
+
+const (
+	// WriteThrough writes to the system of record before the cache is updated, a failed write or delete leaves the cache unchanged.
+	WriteThrough WriteMode = 0
+	// WriteBehind updates the cache immediately and writes to the system of record in the background. Repeated writes to the same key
+	// before a flush are coalesced into one write of the latest value.
+	WriteBehind WriteMode = 1
+)
+
+type WriterConfig struct {
+	// Mode is the write mode of the cache, see write modes.
+	// Defaults to WriteThrough
+	Mode WriteMode
+	// BatchSize is the maximum number of changes flushed at once in write-behind mode. A flush is started early when this many changes are pending.
+	// Defaults to 100
+	BatchSize int
+	// FlushInterval is the interval pending changes are flushed in write-behind mode.
+	// Defaults to 1 second
+	FlushInterval time.Duration
+	// MaxRetries is the number of times a failed write is retried before it is given up.
+	// Defaults to 3
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, it is doubled for every following retry.
+	// Defaults to 100 milliseconds
+	RetryBackoff time.Duration
+}
+
+func (config WriterConfig) withDefaults() WriterConfig {
+	if config.BatchSize < 1 {
+		config.BatchSize = defaultWriteBatchSize
+	}
+	if config.FlushInterval < 1 {
+		config.FlushInterval = defaultFlushInterval
+	}
+	if config.MaxRetries < 1 {
+		config.MaxRetries = defaultWriteRetries
+	}
+	if config.RetryBackoff < 1 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
+	return config
+}
+
+// pendingWrite - a change waiting to be flushed, only the latest change to a key is kept
+type pendingWrite[V any] struct {
+	value  V
+	delete bool
+}
+
+// cacheWriter - writes the changes of a cache to its CacheWriter either directly or in the background
+type cacheWriter[K comparable, V any] struct {
+	writer  CacheWriter[K, V]
+	config  WriterConfig
+	clock   Clock
+	onError func(k K, err error)
+
+	// lock guards the pending changes of a write-behind writer
+	lock    *sync.Mutex
+	pending map[K]pendingWrite[V]
+	// order is the order keys were first changed in since the last flush, so the oldest changes are flushed first
+	order  []K
+	closed bool
+	// flushContext is cancelled when the context passed to close is done, so a background flush in progress stops retrying
+	flushContext context.Context
+	cancelFlush  context.CancelFunc
+	flushNow     chan struct{}
+	stop         chan struct{}
+	stopped      chan struct{}
+}
+
+func newCacheWriter[K comparable, V any](cacheInfo CacheInfo[K, V], clock Clock) *cacheWriter[K, V] {
+	if cacheInfo.Writer == nil {
+		return nil
+	}
+	flushContext, cancelFlush := context.WithCancel(context.Background())
+	w := &cacheWriter[K, V]{
+		writer:       cacheInfo.Writer,
+		config:       cacheInfo.WriterConfig.withDefaults(),
+		clock:        clock,
+		onError:      cacheInfo.Hooks.OnWriteError,
+		lock:         &sync.Mutex{},
+		pending:      make(map[K]pendingWrite[V]),
+		flushContext: flushContext,
+		cancelFlush:  cancelFlush,
+		flushNow:     make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+	if w.config.Mode == WriteBehind {
+		go w.run()
+	} else {
+		close(w.stopped)
+	}
+	return w
+}
+
+// write writes the value in write-through mode and returns the error of the last retry, in write-behind mode it queues the value and never fails
+func (w *cacheWriter[K, V]) write(k K, v V) error {
+	if w == nil {
+		return nil
+	}
+	if w.config.Mode == WriteBehind {
+		w.enqueue(k, pendingWrite[V]{value: v})
+		return nil
+	}
+	err := w.retry(context.Background(), func() error {
+		return w.writer.Write(k, v)
+	})
+	if err != nil {
+		w.failed(k, err)
+	}
+	return err
+}
+
+// delete deletes the key in write-through mode and returns the error of the last retry, in write-behind mode it queues the delete and never fails
+func (w *cacheWriter[K, V]) delete(k K) error {
+	if w == nil {
+		return nil
+	}
+	if w.config.Mode == WriteBehind {
+		w.enqueue(k, pendingWrite[V]{delete: true})
+		return nil
+	}
+	err := w.retry(context.Background(), func() error {
+		return w.writer.Delete(k)
+	})
+	if err != nil {
+		w.failed(k, err)
+	}
+	return err
+}
+
+// deleteAll deletes the keys and returns the keys that were deleted, the keys whose delete failed have to stay in the cache
+func (w *cacheWriter[K, V]) deleteAll(keys []K) []K {
+	if w == nil {
+		return keys
+	}
+	deleted := make([]K, 0, len(keys))
+	for _, k := range keys {
+		if w.delete(k) == nil {
+			deleted = append(deleted, k)
+		}
+	}
+	return deleted
+}
+
+// close stops the background flushes and flushes the pending changes, retries stop when the context is done
+func (w *cacheWriter[K, V]) close(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+	stopCancel := context.AfterFunc(ctx, w.cancelFlush)
+	defer stopCancel()
+	defer w.cancelFlush()
+
+	w.lock.Lock()
+	w.closed = true
+	w.lock.Unlock()
+	close(w.stop)
+	<-w.stopped
+	return w.flush(ctx)
+}
+
+func (w *cacheWriter[K, V]) enqueue(k K, change pendingWrite[V]) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	// the cache ignores changes after it is closed
+	if w.closed {
+		return
+	}
+	if _, exists := w.pending[k]; !exists {
+		w.order = append(w.order, k)
+	}
+	w.pending[k] = change
+
+	if len(w.order) >= w.config.BatchSize {
+		select {
+		case w.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *cacheWriter[K, V]) run() {
+	defer close(w.stopped)
+	ticker := w.clock.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			w.flush(w.flushContext)
+		case <-w.flushNow:
+			w.flush(w.flushContext)
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// flush writes all pending changes in batches, returns the last error of a batch that could not be written
+func (w *cacheWriter[K, V]) flush(ctx context.Context) error {
+	var lastErr error
+	for {
+		writes, deletes := w.takeBatch()
+		if len(writes) == 0 && len(deletes) == 0 {
+			return lastErr
+		}
+		if err := w.writeBatch(ctx, writes, deletes); err != nil {
+			lastErr = err
+		}
+	}
+}
+
+// takeBatch removes the oldest pending changes up to the batch size
+func (w *cacheWriter[K, V]) takeBatch() (map[K]V, []K) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	size := len(w.order)
+	if size > w.config.BatchSize {
+		size = w.config.BatchSize
+	}
+	writes := make(map[K]V)
+	var deletes []K
+	for _, k := range w.order[:size] {
+		change := w.pending[k]
+		delete(w.pending, k)
+		if change.delete {
+			deletes = append(deletes, k)
+		} else {
+			writes[k] = change.value
+		}
+	}
+	w.order = w.order[size:]
+	return writes, deletes
+}
+
+func (w *cacheWriter[K, V]) writeBatch(ctx context.Context, writes map[K]V, deletes []K) error {
+	if batchWriter, ok := w.writer.(BatchCacheWriter[K, V]); ok {
+		err := w.retry(ctx, func() error {
+			return batchWriter.WriteBatch(writes, deletes)
+		})
+		if err != nil {
+			for k := range writes {
+				w.failed(k, err)
+			}
+			for _, k := range deletes {
+				w.failed(k, err)
+			}
+		}
+		return err
+	}
+
+	var lastErr error
+	for k, v := range writes {
+		k, v := k, v
+		if err := w.retry(ctx, func() error { return w.writer.Write(k, v) }); err != nil {
+			w.failed(k, err)
+			lastErr = err
+		}
+	}
+	for _, k := range deletes {
+		k := k
+		if err := w.retry(ctx, func() error { return w.writer.Delete(k) }); err != nil {
+			w.failed(k, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// retry calls f until it succeeds or has been retried MaxRetries times, waiting RetryBackoff doubled for every retry in between
+func (w *cacheWriter[K, V]) retry(ctx context.Context, f func() error) error {
+	backoff := w.config.RetryBackoff
+	err := f()
+	for retries := 0; err != nil && retries < w.config.MaxRetries; retries++ {
+		timer := w.clock.NewTimer(backoff)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+		err = f()
+	}
+	return err
+}
+
+func (w *cacheWriter[K, V]) failed(k K, err error) {
+	if w.onError != nil {
+		w.onError(k, err)
+	}
+}
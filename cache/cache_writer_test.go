@@ -0,0 +1,300 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingWriter - a cache writer recording its calls, the first failures calls fail
+type recordingWriter struct {
+	lock     *sync.Mutex
+	writes   map[string]string
+	deletes  []string
+	calls    int
+	failures int
+}
+
+func newRecordingWriter(failures int) *recordingWriter {
+	return &recordingWriter{lock: &sync.Mutex{}, writes: map[string]string{}, failures: failures}
+}
+
+func (r *recordingWriter) Write(k string, v string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls++
+	if r.calls <= r.failures {
+		return errors.New("write failed")
+	}
+	r.writes[k] = v
+	return nil
+}
+
+func (r *recordingWriter) Delete(k string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls++
+	if r.calls <= r.failures {
+		return errors.New("delete failed")
+	}
+	r.deletes = append(r.deletes, k)
+	return nil
+}
+
+func (r *recordingWriter) callCount() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.calls
+}
+
+// recordingBatchWriter - a recording writer that also writes batches
+type recordingBatchWriter struct {
+	*recordingWriter
+	batches int
+}
+
+func (r *recordingBatchWriter) WriteBatch(writes map[string]string, deletes []string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.batches++
+	for k, v := range writes {
+		r.writes[k] = v
+	}
+	r.deletes = append(r.deletes, deletes...)
+	return nil
+}
+
+func buildWriterTestCache(writer CacheWriter[string, string], config WriterConfig, hooks CacheHooks[string]) Cache[string, string] {
+	return NewCacheBuilder[string, string]().
+		SetWriter(writer, config).
+		SetHooks(hooks).
+		Build(func(k string) (string, error) {
+			return "loaded " + k, nil
+		})
+}
+
+func TestWriteThroughWritesBeforeUpdatingTheCache(t *testing.T) {
+	// setup
+	writer := newRecordingWriter(0)
+	writerCache := buildWriterTestCache(writer, WriterConfig{}, CacheHooks[string]{})
+
+	// execute
+	writerCache.Put("a", "1")
+	writerCache.Get("b")
+	writerCache.Remove("a")
+
+	// verify
+	if writer.writes["a"] != "1" {
+		t.Errorf("Expected 'a' to be written")
+	}
+	if _, written := writer.writes["b"]; written {
+		t.Errorf("Expected loaded values not to be written")
+	}
+	if len(writer.deletes) != 1 || writer.deletes[0] != "a" {
+		t.Errorf("Expected 'a' to be deleted")
+	}
+}
+
+func TestFailedWriteThroughLeavesTheCacheUnchanged(t *testing.T) {
+	// setup
+	writer := newRecordingWriter(10)
+	var writeErr error
+	writerCache := buildWriterTestCache(writer, WriterConfig{MaxRetries: 2, RetryBackoff: time.Millisecond}, CacheHooks[string]{
+		OnWriteError: func(k string, err error) { writeErr = err },
+	})
+
+	// execute
+	inserted := writerCache.Put("a", "1")
+
+	// verify
+	if inserted || writerCache.Contains("a") {
+		t.Errorf("Expected 'a' not to be put in the cache")
+	}
+	if writer.callCount() != 3 {
+		t.Errorf("Expected write to be tried 3 times, got %d", writer.callCount())
+	}
+	if writeErr == nil {
+		t.Errorf("Expected write error to be reported")
+	}
+}
+
+func TestWriteThroughRetriesFailedWrites(t *testing.T) {
+	// setup
+	writer := newRecordingWriter(1)
+	writerCache := buildWriterTestCache(writer, WriterConfig{RetryBackoff: time.Millisecond}, CacheHooks[string]{})
+
+	// execute
+	writerCache.Put("a", "1")
+
+	// verify
+	if writer.writes["a"] != "1" || !writerCache.Contains("a") {
+		t.Errorf("Expected 'a' to be written after a retry")
+	}
+}
+
+func TestWriteBehindCoalescesAndFlushesOnClose(t *testing.T) {
+	// setup
+	writer := &recordingBatchWriter{recordingWriter: newRecordingWriter(0)}
+	writerCache := buildWriterTestCache(writer, WriterConfig{Mode: WriteBehind, FlushInterval: time.Hour}, CacheHooks[string]{})
+
+	// execute
+	writerCache.Put("a", "1")
+	writerCache.Put("a", "2")
+	writerCache.Put("b", "1")
+	writerCache.Put("c", "1")
+	writerCache.Remove("c")
+	valueBeforeFlush, _ := writerCache.GetIfPresent("a")
+	writesBeforeClose := len(writer.writes)
+	err := writerCache.Close()
+
+	// verify
+	if valueBeforeFlush != "2" {
+		t.Errorf("Expected the cache to be updated immediately")
+	}
+	if err != nil || writesBeforeClose != 0 {
+		t.Errorf("Expected writes to be flushed on close")
+	}
+	if writer.batches != 1 {
+		t.Errorf("Expected 1 batch, got %d", writer.batches)
+	}
+	if len(writer.writes) != 2 || writer.writes["a"] != "2" || writer.writes["b"] != "1" {
+		t.Errorf("Expected the latest values of 'a' and 'b' to be written, got %v", writer.writes)
+	}
+	if len(writer.deletes) != 1 || writer.deletes[0] != "c" {
+		t.Errorf("Expected 'c' to be deleted, got %v", writer.deletes)
+	}
+}
+
+func TestWriteBehindFlushesWhenBatchIsFull(t *testing.T) {
+	// setup
+	writer := newRecordingWriter(0)
+	writerCache := buildWriterTestCache(writer, WriterConfig{Mode: WriteBehind, BatchSize: 2, FlushInterval: time.Hour}, CacheHooks[string]{})
+	defer writerCache.Close()
+
+	// execute
+	writerCache.Put("a", "1")
+	writerCache.Put("b", "1")
+
+	// verify
+	deadline := time.Now().Add(time.Second)
+	for writer.callCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if writer.callCount() != 2 {
+		t.Errorf("Expected a full batch to be flushed before the interval, got %d writes", writer.callCount())
+	}
+}
+
+func TestConditionalUpdatesAreWritten(t *testing.T) {
+	// setup
+	writer := newRecordingWriter(0)
+	writerCache := buildWriterTestCache(writer, WriterConfig{}, CacheHooks[string]{})
+
+	// execute
+	writerCache.PutIfAbsent("a", "1")
+	writerCache.Replace("a", "2")
+	writerCache.CompareAndSwap("a", "2", "3")
+	writerCache.Merge("b", "1", func(old string, v string) (string, Action) {
+		return old + v, Store
+	})
+	writerCache.Compute("c", func(old string, exists bool) (string, Action) {
+		return "1", Store
+	})
+	writerCache.CompareAndDelete("c", "1")
+
+	// verify
+	if writer.writes["a"] != "3" || writer.writes["b"] != "1" {
+		t.Errorf("Expected the conditional updates to be written, got %v", writer.writes)
+	}
+	if len(writer.deletes) != 1 || writer.deletes[0] != "c" {
+		t.Errorf("Expected 'c' to be deleted, got %v", writer.deletes)
+	}
+}
+
+func TestFailedWriteThroughOfAConditionalUpdateRemovesTheEntry(t *testing.T) {
+	// setup
+	writer := newRecordingWriter(10)
+	writerCache := buildWriterTestCache(writer, WriterConfig{MaxRetries: 1, RetryBackoff: time.Millisecond}, CacheHooks[string]{})
+
+	// execute
+	_, exists := writerCache.Compute("a", func(old string, exists bool) (string, Action) {
+		return "1", Store
+	})
+
+	// verify
+	if exists || writerCache.Contains("a") {
+		t.Errorf("Expected 'a' not to be in the cache after its write failed")
+	}
+}
+
+func TestFailedWriteThroughDeletesKeepTheEntries(t *testing.T) {
+	// setup
+	writer := newRecordingWriter(0)
+	writerCache := buildWriterTestCache(writer, WriterConfig{MaxRetries: 1, RetryBackoff: time.Millisecond}, CacheHooks[string]{})
+	writerCache.Put("a", "1")
+	writerCache.Put("b", "2")
+	writerCache.Put("c", "3")
+	writer.lock.Lock()
+	// the delete of "a" and of "b" fail with their retry
+	writer.failures = writer.calls + 4
+	writer.lock.Unlock()
+
+	// execute
+	removed := writerCache.Remove("a")
+	removedCount := writerCache.RemoveAll([]string{"b", "c"})
+
+	// verify
+	if removed || !writerCache.Contains("a") {
+		t.Errorf("Expected 'a' to stay in the cache after its delete failed")
+	}
+	if removedCount != 1 || !writerCache.Contains("b") || writerCache.Contains("c") {
+		t.Errorf("Expected only 'c' to be removed, got %d removed", removedCount)
+	}
+}
+
+func TestFailedWriteThroughOfPutIfAbsentIsNotReportedAsStored(t *testing.T) {
+	// setup
+	writer := newRecordingWriter(10)
+	writerCache := buildWriterTestCache(writer, WriterConfig{MaxRetries: 1, RetryBackoff: time.Millisecond}, CacheHooks[string]{})
+
+	// execute
+	existing, loaded := writerCache.PutIfAbsent("a", "1")
+
+	// verify
+	if !loaded || existing != "" || writerCache.Contains("a") {
+		t.Errorf("Expected PutIfAbsent not to store 'a' after its write failed, got '%s' and %t", existing, loaded)
+	}
+}
+
+func TestWriteBehindFlushStopsRetryingWhenTheCloseContextIsDone(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	writer := newRecordingWriter(10)
+	writerCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{
+		Clock: clock,
+	}).
+		SetWriter(writer, WriterConfig{Mode: WriteBehind, BatchSize: 1, FlushInterval: time.Hour, RetryBackoff: time.Hour}).
+		Build(func(k string) (string, error) {
+			return "loaded " + k, nil
+		})
+	writerCache.Put("a", "1")
+	// the flush ticker and the backoff of the failed write
+	clock.WaitForTimers(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// execute
+	closed := make(chan error, 1)
+	go func() {
+		closed <- writerCache.Shutdown(ctx)
+	}()
+
+	// verify
+	select {
+	case <-closed:
+	case <-time.After(time.Second * 5):
+		t.Fatalf("Expected shutdown to stop the background flush")
+	}
+}
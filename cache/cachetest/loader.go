@@ -0,0 +1,187 @@
+// Package cachetest provides utilities for testing code that uses caches and a conformance suite for implementations of cache.Cache and cache.CacheData.
+package cachetest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+// Result is a scripted result of a FakeLoader.
+type Result[V any] struct {
+	Value V
+	Err   error
+	// Delay is the time the load takes before the result is returned, measured on the clock of the loader.
+	Delay time.Duration
+}
+
+// FakeLoader is a scriptable cache loader. Results can be set per key, loads can be delayed or held, and calls are counted.
+// Keys without a scripted result are loaded with the fallback. It is safe for concurrent use.
+type FakeLoader[K comparable, V any] struct {
+	lock     *sync.Mutex
+	clock    cache.Clock
+	results  map[K][]Result[V]
+	fallback func(k K) (V, error)
+	holds    map[K]chan struct{}
+	calls    map[K]int
+	keys     []K
+	// called is closed and replaced on every call, so WaitForCalls can wait without polling
+	called chan struct{}
+}
+
+// NewFakeLoader creates a loader that returns the zero value for keys without a scripted result.
+func NewFakeLoader[K comparable, V any]() *FakeLoader[K, V] {
+	return &FakeLoader[K, V]{
+		lock:  &sync.Mutex{},
+		clock: cache.LocalClock{},
+		fallback: func(k K) (V, error) {
+			var defaultValue V
+			return defaultValue, nil
+		},
+		results: make(map[K][]Result[V]),
+		holds:   make(map[K]chan struct{}),
+		calls:   make(map[K]int),
+		called:  make(chan struct{}),
+	}
+}
+
+// Load loads the key, pass it to CacheBuilder.Build.
+func (f *FakeLoader[K, V]) Load(k K) (V, error) {
+	f.lock.Lock()
+	f.calls[k]++
+	f.keys = append(f.keys, k)
+	close(f.called)
+	f.called = make(chan struct{})
+	result, scripted := f.nextResultLocked(k)
+	hold := f.holds[k]
+	clock := f.clock
+	fallback := f.fallback
+	f.lock.Unlock()
+
+	if hold != nil {
+		<-hold
+	}
+	if !scripted {
+		return fallback(k)
+	}
+	if result.Delay > 0 {
+		timer := clock.NewTimer(result.Delay)
+		<-timer.C()
+	}
+	return result.Value, result.Err
+}
+
+// SetClock sets the clock delays are measured on, for example a cache.FakeClock. Defaults to cache.LocalClock.
+func (f *FakeLoader[K, V]) SetClock(clock cache.Clock) *FakeLoader[K, V] {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.clock = clock
+	return f
+}
+
+// SetFallback sets the function loading keys without a scripted result.
+func (f *FakeLoader[K, V]) SetFallback(fallback func(k K) (V, error)) *FakeLoader[K, V] {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.fallback = fallback
+	return f
+}
+
+// SetValue makes every load of the key return the value.
+func (f *FakeLoader[K, V]) SetValue(k K, v V) *FakeLoader[K, V] {
+	return f.SetResults(k, Result[V]{Value: v})
+}
+
+// SetError makes every load of the key fail with the error.
+func (f *FakeLoader[K, V]) SetError(k K, err error) *FakeLoader[K, V] {
+	return f.SetResults(k, Result[V]{Err: err})
+}
+
+// SetResults makes the loads of the key return the results in order, the last result is repeated once all results were returned.
+func (f *FakeLoader[K, V]) SetResults(k K, results ...Result[V]) *FakeLoader[K, V] {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.results[k] = results
+	return f
+}
+
+// Hold makes loads of the key wait until the returned release function is called, to test what happens while a load is in flight.
+func (f *FakeLoader[K, V]) Hold(k K) (release func()) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	hold := make(chan struct{})
+	f.holds[k] = hold
+	once := &sync.Once{}
+	return func() {
+		once.Do(func() {
+			f.lock.Lock()
+			if f.holds[k] == hold {
+				delete(f.holds, k)
+			}
+			f.lock.Unlock()
+			close(hold)
+		})
+	}
+}
+
+// Calls returns the number of loads of the key.
+func (f *FakeLoader[K, V]) Calls(k K) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.calls[k]
+}
+
+// TotalCalls returns the number of loads of all keys.
+func (f *FakeLoader[K, V]) TotalCalls() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.keys)
+}
+
+// Keys returns the loaded keys in the order of the loads.
+func (f *FakeLoader[K, V]) Keys() []K {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]K(nil), f.keys...)
+}
+
+// WaitForCalls waits until there were at least n loads in total, returns false if the timeout passed first.
+func (f *FakeLoader[K, V]) WaitForCalls(n int, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		f.lock.Lock()
+		calls := len(f.keys)
+		called := f.called
+		f.lock.Unlock()
+		if calls >= n {
+			return true
+		}
+		select {
+		case <-called:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
+
+// Reset forgets the calls, scripted results and holds are kept.
+func (f *FakeLoader[K, V]) Reset() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls = make(map[K]int)
+	f.keys = nil
+}
+
+func (f *FakeLoader[K, V]) nextResultLocked(k K) (Result[V], bool) {
+	results, scripted := f.results[k]
+	if !scripted || len(results) == 0 {
+		return Result[V]{}, false
+	}
+	result := results[0]
+	if len(results) > 1 {
+		f.results[k] = results[1:]
+	}
+	return result, true
+}
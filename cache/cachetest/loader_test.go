@@ -0,0 +1,94 @@
+package cachetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+func TestScriptedResultsAreReturnedInOrderAndTheLastIsRepeated(t *testing.T) {
+	// setup
+	loader := NewFakeLoader[string, string]().SetResults("a", Result[string]{Err: errors.New("failed")}, Result[string]{Value: "1"})
+
+	// execute
+	_, firstErr := loader.Load("a")
+	second, _ := loader.Load("a")
+	third, _ := loader.Load("a")
+	missing, missingErr := loader.Load("missing")
+
+	// verify
+	if firstErr == nil || second != "1" || third != "1" {
+		t.Errorf("Expected an error followed by '1', got '%s' and '%s'", second, third)
+	}
+	if missing != "" || missingErr != nil {
+		t.Errorf("Expected unscripted keys to load the zero value")
+	}
+	if loader.Calls("a") != 3 || loader.TotalCalls() != 4 {
+		t.Errorf("Expected 3 loads of 'a' and 4 in total, got %d and %d", loader.Calls("a"), loader.TotalCalls())
+	}
+}
+
+func TestDelayedResultWaitsForTheClock(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	loader := NewFakeLoader[string, string]().
+		SetClock(clock).
+		SetResults("a", Result[string]{Value: "1", Delay: time.Second})
+	done := make(chan string)
+
+	// execute
+	go func() {
+		value, _ := loader.Load("a")
+		done <- value
+	}()
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+
+	// verify
+	if value := <-done; value != "1" {
+		t.Errorf("Expected '1', got '%s'", value)
+	}
+}
+
+func TestHeldLoadWaitsForRelease(t *testing.T) {
+	// setup
+	loader := NewFakeLoader[string, string]().SetValue("a", "1")
+	release := loader.Hold("a")
+	done := make(chan string)
+
+	// execute
+	go func() {
+		value, _ := loader.Load("a")
+		done <- value
+	}()
+	called := loader.WaitForCalls(1, time.Second)
+	select {
+	case <-done:
+		t.Fatalf("Expected the load to wait for release")
+	default:
+	}
+	release()
+
+	// verify
+	if !called {
+		t.Errorf("Expected the load to be called")
+	}
+	if value := <-done; value != "1" {
+		t.Errorf("Expected '1', got '%s'", value)
+	}
+}
+
+func TestWaitForCallsTimesOut(t *testing.T) {
+	// setup
+	loader := NewFakeLoader[string, string]()
+
+	// execute
+	called := loader.WaitForCalls(1, time.Millisecond)
+
+	// verify
+	if called {
+		t.Errorf("Expected WaitForCalls to time out")
+	}
+}
@@ -0,0 +1,434 @@
+package cachetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+// NewCacheFunc builds the cache under test with the loader. The cache has to hold at least 100 entries and entries must not expire during the test.
+type NewCacheFunc func(t *testing.T, loader cache.CacheLoader[string, string]) cache.Cache[string, string]
+
+// NewCacheDataFunc builds the data store under test with the clock.
+type NewCacheDataFunc func(t *testing.T, clock cache.Clock) cache.CacheData[string, string]
+
+// TestCache runs the conformance suite for cache.Cache against the caches built by newCache, every test gets a new cache.
+func TestCache(t *testing.T, newCache NewCacheFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, newCache NewCacheFunc)
+	}{
+		{"GetLoadsOnce", testGetLoadsOnce},
+		{"FailedLoadIsNotCached", testFailedLoadIsNotCached},
+		{"PutAndGetIfPresent", testPutAndGetIfPresent},
+		{"Remove", testRemove},
+		{"ContainsLenAndKeys", testContainsLenAndKeys},
+		{"Range", testRange},
+		{"RemoveAllAndInvalidateAll", testRemoveAllAndInvalidateAll},
+		{"Compute", testCompute},
+		{"ConditionalUpdates", testConditionalUpdates},
+		{"SnapshotAndRestore", testSnapshotAndRestore},
+		{"ConcurrentAccess", testConcurrentAccess},
+		{"Close", testClose},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, newCache)
+		})
+	}
+}
+
+// TestCacheFactory runs the conformance suite for cache.Cache against caches built by the factory through a cache builder, for every cache type.
+func TestCacheFactory(t *testing.T, factory cache.CacheFactory[string, string]) {
+	for _, cacheType := range []cache.CacheType{cache.Blocking, cache.Refresh} {
+		cacheType := cacheType
+		t.Run(cacheType.String(), func(t *testing.T) {
+			TestCache(t, func(t *testing.T, loader cache.CacheLoader[string, string]) cache.Cache[string, string] {
+				return cache.NewCacheBuilderWithFactory[string, string](factory).
+					SetCacheType(cacheType).
+					SetMaxSize(1000).
+					Build(loader)
+			})
+		})
+	}
+}
+
+// TestCacheData runs the conformance suite for cache.CacheData against the data stores built by newCacheData, every test gets a new data store.
+// The data store is used without a loader, so the suite for cache.Cache does not apply to it.
+func TestCacheData(t *testing.T, newCacheData NewCacheDataFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, newCacheData NewCacheDataFunc)
+	}{
+		{"PutGetAndRemove", testDataPutGetAndRemove},
+		{"IsExpired", testDataIsExpired},
+		{"RemoveLeastRecentlyAccessed", testDataRemoveLeastRecentlyAccessed},
+		{"EntriesAndRestoreEntries", testDataEntriesAndRestoreEntries},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, newCacheData)
+		})
+	}
+}
+
+func newTestCache(t *testing.T, newCache NewCacheFunc, loader *FakeLoader[string, string]) cache.Cache[string, string] {
+	built := newCache(t, loader.Load)
+	t.Cleanup(func() {
+		built.Close()
+	})
+	return built
+}
+
+func testGetLoadsOnce(t *testing.T, newCache NewCacheFunc) {
+	loader := NewFakeLoader[string, string]().SetValue("a", "loaded a")
+	c := newTestCache(t, newCache, loader)
+
+	first, firstExists := c.Get("a")
+	second, secondExists := c.Get("a")
+
+	if !firstExists || !secondExists || first != "loaded a" || second != "loaded a" {
+		t.Errorf("Expected 'a' to be 'loaded a', got '%s' and '%s'", first, second)
+	}
+	if loader.Calls("a") != 1 {
+		t.Errorf("Expected 'a' to be loaded once, got %d loads", loader.Calls("a"))
+	}
+}
+
+func testFailedLoadIsNotCached(t *testing.T, newCache NewCacheFunc) {
+	loader := NewFakeLoader[string, string]().SetResults("a", Result[string]{Err: errors.New("failed")}, Result[string]{Value: "loaded a"})
+	c := newTestCache(t, newCache, loader)
+
+	_, failedExists := c.Get("a")
+	containsAfterFailure := c.Contains("a")
+	value, exists := c.Get("a")
+
+	if failedExists || containsAfterFailure {
+		t.Errorf("Expected a failed load not to be cached")
+	}
+	if !exists || value != "loaded a" {
+		t.Errorf("Expected 'a' to be loaded again, got '%s'", value)
+	}
+}
+
+func testPutAndGetIfPresent(t *testing.T, newCache NewCacheFunc) {
+	loader := NewFakeLoader[string, string]()
+	c := newTestCache(t, newCache, loader)
+
+	inserted := c.Put("a", "1")
+	updated := c.Put("a", "2")
+	value, exists := c.GetIfPresent("a")
+	_, missingExists := c.GetIfPresent("missing")
+
+	if !inserted || updated {
+		t.Errorf("Expected Put to return true for an insert and false for an update")
+	}
+	if !exists || value != "2" {
+		t.Errorf("Expected 'a' to be '2', got '%s'", value)
+	}
+	if missingExists || loader.TotalCalls() != 0 {
+		t.Errorf("Expected GetIfPresent not to load")
+	}
+}
+
+func testRemove(t *testing.T, newCache NewCacheFunc) {
+	c := newTestCache(t, newCache, NewFakeLoader[string, string]())
+	c.Put("a", "1")
+
+	removed := c.Remove("a")
+	removedAgain := c.Remove("a")
+
+	if !removed || removedAgain {
+		t.Errorf("Expected only the first Remove to remove 'a'")
+	}
+	if c.Contains("a") {
+		t.Errorf("Expected 'a' to be removed")
+	}
+}
+
+func testContainsLenAndKeys(t *testing.T, newCache NewCacheFunc) {
+	c := newTestCache(t, newCache, NewFakeLoader[string, string]())
+	c.Put("a", "1")
+	c.Put("b", "2")
+
+	keys := c.Keys()
+	sort.Strings(keys)
+
+	if !c.Contains("a") || c.Contains("missing") {
+		t.Errorf("Expected the cache to contain only 'a' and 'b'")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Expected keys 'a' and 'b', got %v", keys)
+	}
+}
+
+func testRange(t *testing.T, newCache NewCacheFunc) {
+	c := newTestCache(t, newCache, NewFakeLoader[string, string]())
+	for i := 0; i < 10; i++ {
+		c.Put(fmt.Sprint(i), fmt.Sprint(i))
+	}
+
+	all := map[string]string{}
+	c.Range(func(k string, v string) bool {
+		all[k] = v
+		// the cache can be used from f
+		c.Contains(k)
+		return true
+	})
+	visited := 0
+	c.Range(func(k string, v string) bool {
+		visited++
+		return visited < 3
+	})
+
+	if len(all) != 10 || all["5"] != "5" {
+		t.Errorf("Expected all 10 entries to be visited, got %v", all)
+	}
+	if visited != 3 {
+		t.Errorf("Expected Range to stop when f returns false, visited %d", visited)
+	}
+}
+
+func testRemoveAllAndInvalidateAll(t *testing.T, newCache NewCacheFunc) {
+	c := newTestCache(t, newCache, NewFakeLoader[string, string]())
+	c.Put("a", "1")
+	c.Put("b", "2")
+	c.Put("c", "3")
+
+	removed := c.RemoveAll([]string{"a", "b", "missing"})
+	lenAfterRemoveAll := c.Len()
+	c.InvalidateAll()
+
+	if removed != 2 || lenAfterRemoveAll != 1 {
+		t.Errorf("Expected 2 entries to be removed and 1 to be left, got %d and %d", removed, lenAfterRemoveAll)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected InvalidateAll to remove every entry, got %d", c.Len())
+	}
+}
+
+func testCompute(t *testing.T, newCache NewCacheFunc) {
+	c := newTestCache(t, newCache, NewFakeLoader[string, string]())
+	appendValue := func(old string, exists bool) (string, cache.Action) {
+		return old + "x", cache.Store
+	}
+
+	first, _ := c.Compute("a", appendValue)
+	second, _ := c.Compute("a", appendValue)
+	kept, keptExists := c.Compute("a", func(old string, exists bool) (string, cache.Action) {
+		return "ignored", cache.Keep
+	})
+	_, deletedExists := c.Compute("a", func(old string, exists bool) (string, cache.Action) {
+		return old, cache.Delete
+	})
+	absent, _ := c.ComputeIfAbsent("b", func(k string) (string, bool) { return "new " + k, true })
+	notReplaced, _ := c.ComputeIfAbsent("b", func(k string) (string, bool) { return "other", true })
+	present, _ := c.ComputeIfPresent("b", func(k string, old string) (string, cache.Action) { return old + "!", cache.Store })
+	_, missingPresent := c.ComputeIfPresent("missing", func(k string, old string) (string, cache.Action) { return "x", cache.Store })
+	merged, _ := c.Merge("b", "?", func(old string, v string) (string, cache.Action) { return old + v, cache.Store })
+
+	if first != "x" || second != "xx" {
+		t.Errorf("Expected Compute to store 'x' and 'xx', got '%s' and '%s'", first, second)
+	}
+	if !keptExists || kept != "xx" {
+		t.Errorf("Expected Keep to leave 'xx', got '%s'", kept)
+	}
+	if deletedExists || c.Contains("a") {
+		t.Errorf("Expected Delete to remove 'a'")
+	}
+	if absent != "new b" || notReplaced != "new b" {
+		t.Errorf("Expected ComputeIfAbsent to only store 'new b', got '%s' and '%s'", absent, notReplaced)
+	}
+	if present != "new b!" || missingPresent {
+		t.Errorf("Expected ComputeIfPresent to only update 'b', got '%s'", present)
+	}
+	if merged != "new b!?" {
+		t.Errorf("Expected Merge to combine the values, got '%s'", merged)
+	}
+}
+
+func testConditionalUpdates(t *testing.T, newCache NewCacheFunc) {
+	c := newTestCache(t, newCache, NewFakeLoader[string, string]())
+
+	_, replacedMissing := c.Replace("a", "0")
+	stored, loaded := c.PutIfAbsent("a", "1")
+	existing, loadedAgain := c.PutIfAbsent("a", "2")
+	previous, replaced := c.Replace("a", "3")
+	swappedWrong := c.CompareAndSwap("a", "1", "4")
+	swapped := c.CompareAndSwap("a", "3", "4")
+	deletedWrong := c.CompareAndDelete("a", "3")
+	deleted := c.CompareAndDelete("a", "4")
+
+	if replacedMissing {
+		t.Errorf("Expected Replace not to insert a missing key")
+	}
+	if loaded || stored != "1" || !loadedAgain || existing != "1" {
+		t.Errorf("Expected PutIfAbsent to only insert '1'")
+	}
+	if !replaced || previous != "1" {
+		t.Errorf("Expected Replace to return the previous value '1', got '%s'", previous)
+	}
+	if swappedWrong || !swapped {
+		t.Errorf("Expected CompareAndSwap to only swap the current value")
+	}
+	if deletedWrong || !deleted || c.Contains("a") {
+		t.Errorf("Expected CompareAndDelete to only delete the current value")
+	}
+}
+
+func testSnapshotAndRestore(t *testing.T, newCache NewCacheFunc) {
+	c := newTestCache(t, newCache, NewFakeLoader[string, string]())
+	c.Put("a", "1")
+	c.Put("b", "2")
+	restored := newTestCache(t, newCache, NewFakeLoader[string, string]())
+
+	buffer := &bytes.Buffer{}
+	snapshotErr := c.Snapshot(buffer)
+	restoreErr := restored.Restore(buffer)
+
+	if snapshotErr != nil || restoreErr != nil {
+		t.Fatalf("Expected snapshot and restore to succeed, got %v and %v", snapshotErr, restoreErr)
+	}
+	if value, _ := restored.GetIfPresent("b"); value != "2" || restored.Len() != 2 {
+		t.Errorf("Expected both entries to be restored")
+	}
+}
+
+func testConcurrentAccess(t *testing.T, newCache NewCacheFunc) {
+	loader := NewFakeLoader[string, string]().SetFallback(func(k string) (string, error) {
+		return "loaded " + k, nil
+	})
+	c := newTestCache(t, newCache, loader)
+
+	wait := &sync.WaitGroup{}
+	for worker := 0; worker < 8; worker++ {
+		wait.Add(1)
+		go func(worker int) {
+			defer wait.Done()
+			for i := 0; i < 100; i++ {
+				k := fmt.Sprint(i % 20)
+				switch (worker + i) % 4 {
+				case 0:
+					if value, exists := c.Get(k); exists && value != "loaded "+k && value != "put "+k {
+						t.Errorf("Expected the value of '%s', got '%s'", k, value)
+					}
+				case 1:
+					c.Put(k, "put "+k)
+				case 2:
+					c.Remove(k)
+				default:
+					c.Compute(k, func(old string, exists bool) (string, cache.Action) {
+						return "put " + k, cache.Store
+					})
+				}
+			}
+		}(worker)
+	}
+	wait.Wait()
+}
+
+func testClose(t *testing.T, newCache NewCacheFunc) {
+	c := newCache(t, NewFakeLoader[string, string]().Load)
+	c.Put("a", "1")
+
+	closeErr := c.Close()
+	secondCloseErr := c.Close()
+	_, exists := c.Get("a")
+
+	if closeErr != nil {
+		t.Errorf("Expected Close to succeed, got %v", closeErr)
+	}
+	if !errors.Is(secondCloseErr, cache.ErrClosed) {
+		t.Errorf("Expected a second Close to return ErrClosed, got %v", secondCloseErr)
+	}
+	if exists {
+		t.Errorf("Expected a closed cache not to return values")
+	}
+}
+
+func testDataPutGetAndRemove(t *testing.T, newCacheData NewCacheDataFunc) {
+	data := newCacheData(t, cache.NewFakeClock(time.Unix(1000, 0)))
+
+	data.Put("a", "1")
+	data.Put("b", "2")
+	value, exists := data.Get("a")
+	sizeBeforeRemove := data.GetSize()
+	removed := data.Remove("a")
+
+	if !exists || value != "1" {
+		t.Errorf("Expected 'a' to be '1', got '%s'", value)
+	}
+	if sizeBeforeRemove != 2 || data.GetSize() != 1 || !removed {
+		t.Errorf("Expected the size to go from 2 to 1, got %d and %d", sizeBeforeRemove, data.GetSize())
+	}
+}
+
+func testDataIsExpired(t *testing.T, newCacheData NewCacheDataFunc) {
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	data := newCacheData(t, clock)
+	data.Put("a", "1")
+
+	expiredBefore := data.IsExpired("a", time.Minute)
+	clock.Advance(time.Minute * 2)
+	expiredAfter := data.IsExpired("a", time.Minute)
+
+	if expiredBefore || !expiredAfter {
+		t.Errorf("Expected 'a' to expire after a minute")
+	}
+}
+
+func testDataRemoveLeastRecentlyAccessed(t *testing.T, newCacheData NewCacheDataFunc) {
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	data := newCacheData(t, clock)
+	for _, k := range []string{"a", "b", "c"} {
+		data.Put(k, k)
+		clock.Advance(time.Second)
+	}
+	data.Get("a")
+
+	removed := data.RemoveLeastRecentlyAccessed(2)
+
+	removedKeys := []string{}
+	for _, entry := range removed {
+		removedKeys = append(removedKeys, entry.Key)
+	}
+	sort.Strings(removedKeys)
+	if len(removedKeys) != 2 || removedKeys[0] != "b" || removedKeys[1] != "c" {
+		t.Errorf("Expected 'b' and 'c' to be removed, got %v", removedKeys)
+	}
+	if !data.Contains("a") || data.GetSize() != 1 {
+		t.Errorf("Expected 'a' to be kept")
+	}
+}
+
+func testDataEntriesAndRestoreEntries(t *testing.T, newCacheData NewCacheDataFunc) {
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	data := newCacheData(t, clock)
+	data.Put("a", "1")
+	restored := newCacheData(t, clock)
+	clock.Advance(time.Hour)
+
+	restored.RestoreEntries(data.Entries())
+
+	entries := restored.Entries()
+	if len(entries) != 1 || entries[0].Key != "a" || entries[0].Value != "1" {
+		t.Fatalf("Expected 'a' to be restored, got %v", entries)
+	}
+	if !entries[0].LastUpdateTime.Equal(time.Unix(1000, 0)) {
+		t.Errorf("Expected the timestamps to be kept, got %v", entries[0].LastUpdateTime)
+	}
+	if keys := restored.AllKeys(); len(keys) != 1 || keys[0] != "a" {
+		t.Errorf("Expected AllKeys to return 'a', got %v", keys)
+	}
+}
@@ -0,0 +1,17 @@
+package cachetest
+
+import (
+	"testing"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+func TestDefaultCacheFactory(t *testing.T) {
+	TestCacheFactory(t, cache.NewCacheTypeFactory[string, string]())
+}
+
+func TestDefaultCacheData(t *testing.T) {
+	TestCacheData(t, func(t *testing.T, clock cache.Clock) cache.CacheData[string, string] {
+		return cache.NewCacheData[string, string](clock)
+	})
+}
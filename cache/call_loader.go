@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotLoadable is returned by loaders that can not load values, like PutOnlyLoader. The cache treats it as a miss and not as a failed load,
+// so it is not counted by the circuit breaker, the stats or the OnFailedToLoadEntry hook.
+var ErrNotLoadable = errors.New("cache: value can not be loaded")
+
+// ErrNotLoaded is returned by CallLoader.Get when the cache returned no value without calling the load function, for example because the cache is closed,
+// the circuit is open or the load timed out.
+var ErrNotLoaded = errors.New("cache: value was not loaded")
+
+// PutOnlyLoader is the loader of caches that are only filled with Put, and the fallback of a CallLoader without one. Get returns false for keys that are not in the cache.
+func PutOnlyLoader[K comparable, V any](k K) (V, error) {
+	var defaultValue V
+	return defaultValue, ErrNotLoadable
+}
+
+// CallLoader is the loader of a cache whose loads depend on the call, for example a memoized function that returns the error of the call.
+// Every Get passes its own load function, which is called through the loading path of the cache with its remote store, circuit breaker, timeouts and hooks.
+// Concurrent Gets of a key share one load. Loads without a Get in progress, like the background refreshes of a Refresh cache, use the fallback loader,
+// without a fallback loader they fail with ErrNotLoadable.
+type CallLoader[K comparable, V any] struct {
+	fallback CacheLoader[K, V]
+
+	// lock guards calls, the loads of the Gets in progress by key
+	lock  *sync.Mutex
+	calls map[K]*loadCall[V]
+}
+
+// loadCall - the load shared by the Gets of a key in progress, the load function is called at most once
+type loadCall[V any] struct {
+	load func() (V, error)
+	gets int
+	once *sync.Once
+	// done is closed when the load function returned, value and err are only read after that
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// NewCallLoader creates a CallLoader, build the cache with its Load method. If fallback is nil PutOnlyLoader is used.
+func NewCallLoader[K comparable, V any](fallback CacheLoader[K, V]) *CallLoader[K, V] {
+	if fallback == nil {
+		fallback = PutOnlyLoader[K, V]
+	}
+	return &CallLoader[K, V]{
+		fallback: fallback,
+		lock:     &sync.Mutex{},
+		calls:    make(map[K]*loadCall[V]),
+	}
+}
+
+// Load is the CacheLoader of the cache, it calls the load function of the Gets of the key in progress or the fallback loader.
+func (c *CallLoader[K, V]) Load(k K) (V, error) {
+	c.lock.Lock()
+	call := c.calls[k]
+	c.lock.Unlock()
+	if call == nil {
+		return c.fallback(k)
+	}
+
+	call.once.Do(func() {
+		defer close(call.done)
+		call.value, call.err = call.load()
+	})
+	return call.value, call.err
+}
+
+// Get returns the value of the key from the cache, the cache calls load if it has to load the value.
+// The error is the error returned by load, or ErrNotLoaded if the cache did not call it.
+func (c *CallLoader[K, V]) Get(cache Cache[K, V], k K, load func() (V, error)) (V, error) {
+	call := c.begin(k, load)
+	defer c.end(k, call)
+
+	value, exists := cache.Get(k)
+	if exists {
+		return value, nil
+	}
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return value, call.err
+		}
+	default:
+	}
+	return value, ErrNotLoaded
+}
+
+// begin joins the load of the Gets of the key in progress or starts a new one with the load function
+func (c *CallLoader[K, V]) begin(k K, load func() (V, error)) *loadCall[V] {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	call, exists := c.calls[k]
+	if !exists {
+		call = &loadCall[V]{load: load, once: &sync.Once{}, done: make(chan struct{})}
+		c.calls[k] = call
+	}
+	call.gets++
+	return call
+}
+
+func (c *CallLoader[K, V]) end(k K, call *loadCall[V]) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	call.gets--
+	if call.gets == 0 {
+		delete(c.calls, k)
+	}
+}
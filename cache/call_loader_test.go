@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForGets waits until n Gets of the key joined its load
+func waitForGets[K comparable, V any](loader *CallLoader[K, V], k K, n int) {
+	for {
+		loader.lock.Lock()
+		call := loader.calls[k]
+		joined := call != nil && call.gets >= n
+		loader.lock.Unlock()
+		if joined {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCallLoaderSharesOneLoadBetweenConcurrentGets(t *testing.T) {
+	// setup
+	loader := NewCallLoader[string, string](func(k string) (string, error) {
+		return "fallback", nil
+	})
+	calls := NewCacheBuilder[string, string]().Build(loader.Load)
+	loads := atomic.Int32{}
+	release := make(chan struct{})
+
+	// execute
+	wait := &sync.WaitGroup{}
+	results := make([]string, 10)
+	for i := range results {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			results[i], _ = loader.Get(calls, "key", func() (string, error) {
+				loads.Add(1)
+				<-release
+				return "loaded", nil
+			})
+		}(i)
+	}
+	waitForGets(loader, "key", len(results))
+	close(release)
+	wait.Wait()
+
+	// verify
+	if loads.Load() != 1 {
+		t.Errorf("Expected one load, got %d", loads.Load())
+	}
+	for i, result := range results {
+		if result != "loaded" {
+			t.Errorf("Expected get %d to return 'loaded', got '%s'", i, result)
+		}
+	}
+}
+
+func TestCallLoaderReturnsTheErrorOfTheLoad(t *testing.T) {
+	// setup
+	loader := NewCallLoader[string, string](func(k string) (string, error) {
+		return "fallback", nil
+	})
+	calls := NewCacheBuilder[string, string]().Build(loader.Load)
+	loadErr := errors.New("load failed")
+
+	// execute
+	_, err := loader.Get(calls, "key", func() (string, error) {
+		return "", loadErr
+	})
+	calls.Close()
+	_, closedErr := loader.Get(calls, "key", func() (string, error) {
+		return "loaded", nil
+	})
+
+	// verify
+	if !errors.Is(err, loadErr) {
+		t.Errorf("Expected the error of the load, got %v", err)
+	}
+	if !errors.Is(closedErr, ErrNotLoaded) {
+		t.Errorf("Expected ErrNotLoaded from a closed cache, got %v", closedErr)
+	}
+}
+
+func TestMissesOfAPutOnlyCacheAreNotLoadFailures(t *testing.T) {
+	// setup
+	registry := NewRegistry()
+	failures := 0
+	putOnly := NewCacheBuilder[string, string]().
+		SetName("put only").
+		SetRegistry(registry).
+		SetCircuitBreaker(CircuitBreakerConfig{MinimumLoads: 1, WindowSize: 1}).
+		SetHooks(CacheHooks[string]{
+			OnFailedToLoadEntry: func(k string) { failures++ },
+		}).
+		Build(PutOnlyLoader[string, string])
+	registered, _ := registry.Get("put only")
+
+	// execute
+	_, missExists := putOnly.Get("a")
+	putOnly.Get("b")
+	putOnly.Put("a", "put")
+	value, exists := putOnly.Get("a")
+
+	// verify
+	if missExists || !exists || value != "put" {
+		t.Errorf("Expected a miss and then the put value, got '%s'", value)
+	}
+	if failures != 0 {
+		t.Errorf("Expected misses not to be load failures, got %d", failures)
+	}
+	if stats := registered.Stats(); stats.Loads != 0 || stats.LoadFailures != 0 || stats.Misses != 2 {
+		t.Errorf("Expected 2 misses without loads, got %+v", stats)
+	}
+}
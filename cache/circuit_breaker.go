@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned from the loading path when the circuit breaker is open and the loader was not called.
+var ErrCircuitOpen = errors.New("cache: circuit breaker is open")
+
+const defaultFailureRatePercent = 50
+const defaultMinimumLoads = 10
+const defaultWindowSize = 20
+const defaultCoolDown = time.Second * 30
+const defaultHalfOpenLoads = 1
+
+type CircuitBreakerState int
+
+const (
+	// CircuitClosed loads are passed through to the loader and their results are recorded.
+	CircuitClosed CircuitBreakerState = 0
+	// CircuitOpen loads fail fast with ErrCircuitOpen without calling the loader until the cool down has passed.
+	CircuitOpen CircuitBreakerState = 1
+	// CircuitHalfOpen a limited number of trial loads are let through to decide if the circuit should close or open again.
+	CircuitHalfOpen CircuitBreakerState = 2
+)
+
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type CircuitBreakerConfig struct {
+	// FailureRatePercent is the percent of failed loads within the window that will open the circuit.
+	// Defaults to 50
+	FailureRatePercent int
+	// MinimumLoads is the number of loads that have to be recorded in the window before the failure rate is checked.
+	// Defaults to 10
+	MinimumLoads int
+	// WindowSize is the number of most recent loads used to calculate the failure rate.
+	// Defaults to 20
+	WindowSize int
+	// CoolDown is how long the circuit stays open before trial loads are let through.
+	// Defaults to 30 seconds
+	CoolDown time.Duration
+	// HalfOpenLoads is the number of trial loads let through while half open. All of them have to succeed to close the circuit.
+	// Defaults to 1
+	HalfOpenLoads int
+}
+
+func (config CircuitBreakerConfig) withDefaults() CircuitBreakerConfig {
+	if config.FailureRatePercent < 1 {
+		config.FailureRatePercent = defaultFailureRatePercent
+	}
+	if config.WindowSize < 1 {
+		config.WindowSize = defaultWindowSize
+	}
+	if config.MinimumLoads < 1 {
+		config.MinimumLoads = defaultMinimumLoads
+	}
+	if config.MinimumLoads > config.WindowSize {
+		config.MinimumLoads = config.WindowSize
+	}
+	if config.CoolDown < 1 {
+		config.CoolDown = defaultCoolDown
+	}
+	if config.HalfOpenLoads < 1 {
+		config.HalfOpenLoads = defaultHalfOpenLoads
+	}
+	return config
+}
+
+// circuitBreaker - thread safe breaker around the cache loader
+type circuitBreaker struct {
+	config        CircuitBreakerConfig
+	clock         Clock
+	onStateChange func(from CircuitBreakerState, to CircuitBreakerState)
+
+	lock  *sync.Mutex
+	state CircuitBreakerState
+	// failures is a ring buffer of the most recent load results, true if the load failed
+	failures     []bool
+	failureIdx   int
+	loadCount    int
+	failureCount int
+	openedAt     time.Time
+	// trial loads while half open
+	trialsStarted   int
+	trialsSucceeded int
+}
+
+func newCircuitBreaker(config *CircuitBreakerConfig, clock Clock, onStateChange func(from CircuitBreakerState, to CircuitBreakerState)) *circuitBreaker {
+	if config == nil {
+		return nil
+	}
+	withDefaults := config.withDefaults()
+	return &circuitBreaker{
+		config:        withDefaults,
+		clock:         clock,
+		onStateChange: onStateChange,
+		lock:          &sync.Mutex{},
+		state:         CircuitClosed,
+		failures:      make([]bool, withDefaults.WindowSize),
+	}
+}
+
+// allow returns ErrCircuitOpen if the loader should not be called
+// every allowed load must be followed by a call to record with the result of the load
+func (c *circuitBreaker) allow() error {
+	if c == nil {
+		return nil
+	}
+
+	c.lock.Lock()
+	from := c.state
+	err := c.allowLocked()
+	to := c.state
+	c.lock.Unlock()
+
+	c.stateChanged(from, to)
+	return err
+}
+
+func (c *circuitBreaker) allowLocked() error {
+	if c.state == CircuitOpen {
+		if c.clock.Now().Before(c.openedAt.Add(c.config.CoolDown)) {
+			return ErrCircuitOpen
+		}
+		c.state = CircuitHalfOpen
+		c.trialsStarted = 0
+		c.trialsSucceeded = 0
+	}
+
+	if c.state == CircuitHalfOpen {
+		if c.trialsStarted >= c.config.HalfOpenLoads {
+			return ErrCircuitOpen
+		}
+		c.trialsStarted++
+	}
+	return nil
+}
+
+// record records the result of a load that was allowed
+func (c *circuitBreaker) record(err error) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	from := c.state
+	c.recordLocked(err != nil)
+	to := c.state
+	c.lock.Unlock()
+
+	c.stateChanged(from, to)
+}
+
+// release gives back a load that was allowed but not made, so a trial load while half open can be let through again
+func (c *circuitBreaker) release() {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.state == CircuitHalfOpen && c.trialsStarted > 0 {
+		c.trialsStarted--
+	}
+}
+
+func (c *circuitBreaker) recordLocked(failed bool) {
+	switch c.state {
+	case CircuitClosed:
+		if c.failures[c.failureIdx] && c.loadCount == len(c.failures) {
+			c.failureCount--
+		}
+		c.failures[c.failureIdx] = failed
+		c.failureIdx = (c.failureIdx + 1) % len(c.failures)
+		if c.loadCount < len(c.failures) {
+			c.loadCount++
+		}
+		if failed {
+			c.failureCount++
+		}
+		if c.loadCount >= c.config.MinimumLoads && c.failureCount*100 >= c.config.FailureRatePercent*c.loadCount {
+			c.open()
+		}
+	case CircuitHalfOpen:
+		if failed {
+			c.open()
+			return
+		}
+		c.trialsSucceeded++
+		if c.trialsSucceeded >= c.config.HalfOpenLoads {
+			c.close()
+		}
+	case CircuitOpen:
+		// a load that started before the circuit opened, the result is no longer relevant
+	}
+}
+
+func (c *circuitBreaker) open() {
+	c.state = CircuitOpen
+	c.openedAt = c.clock.Now()
+}
+
+func (c *circuitBreaker) close() {
+	c.state = CircuitClosed
+	c.failureIdx = 0
+	c.loadCount = 0
+	c.failureCount = 0
+	for i := range c.failures {
+		c.failures[i] = false
+	}
+}
+
+// currentState returns the current state of the circuit breaker
+func (c *circuitBreaker) currentState() CircuitBreakerState {
+	if c == nil {
+		return CircuitClosed
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.state
+}
+
+func (c *circuitBreaker) stateChanged(from CircuitBreakerState, to CircuitBreakerState) {
+	if from != to && c.onStateChange != nil {
+		c.onStateChange(from, to)
+	}
+}
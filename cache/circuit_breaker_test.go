@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestCircuitBreaker(clock Clock, transitions *[]CircuitBreakerState) *circuitBreaker {
+	return newCircuitBreaker(&CircuitBreakerConfig{
+		FailureRatePercent: 50,
+		MinimumLoads:       4,
+		WindowSize:         4,
+		CoolDown:           time.Second * 10,
+		HalfOpenLoads:      1,
+	}, clock, func(from CircuitBreakerState, to CircuitBreakerState) {
+		*transitions = append(*transitions, to)
+	})
+}
+
+func TestCircuitBreakerOpensWhenFailureRateIsReached(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	transitions := make([]CircuitBreakerState, 0)
+	breaker := newTestCircuitBreaker(clock, &transitions)
+	loadErr := errors.New("backend down")
+
+	// execute
+	for _, err := range []error{nil, nil, loadErr, loadErr} {
+		if allowErr := breaker.allow(); allowErr != nil {
+			t.Fatalf("Expected load to be allowed")
+		}
+		breaker.record(err)
+	}
+	allowErr := breaker.allow()
+
+	// verify
+	if breaker.currentState() != CircuitOpen {
+		t.Errorf("Expected circuit to be open")
+	}
+	if !errors.Is(allowErr, ErrCircuitOpen) {
+		t.Errorf("Expected load to fail fast")
+	}
+	if len(transitions) != 1 || transitions[0] != CircuitOpen {
+		t.Errorf("Expected a single transition to open")
+	}
+}
+
+func TestCircuitBreakerDoesNotOpenBeforeMinimumLoads(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	transitions := make([]CircuitBreakerState, 0)
+	breaker := newTestCircuitBreaker(clock, &transitions)
+
+	// execute
+	for i := 0; i < 3; i++ {
+		breaker.allow()
+		breaker.record(errors.New("backend down"))
+	}
+
+	// verify
+	if breaker.currentState() != CircuitClosed {
+		t.Errorf("Expected circuit to be closed")
+	}
+}
+
+func TestCircuitBreakerClosesAfterSuccessfulTrialLoad(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	transitions := make([]CircuitBreakerState, 0)
+	breaker := newTestCircuitBreaker(clock, &transitions)
+	for i := 0; i < 4; i++ {
+		breaker.allow()
+		breaker.record(errors.New("backend down"))
+	}
+
+	// execute
+	clock.Set(time.Unix(1011, 0))
+	trialErr := breaker.allow()
+	secondTrialErr := breaker.allow()
+	breaker.record(nil)
+
+	// verify
+	if trialErr != nil {
+		t.Errorf("Expected trial load to be allowed after cool down")
+	}
+	if !errors.Is(secondTrialErr, ErrCircuitOpen) {
+		t.Errorf("Expected only one trial load while half open")
+	}
+	if breaker.currentState() != CircuitClosed {
+		t.Errorf("Expected circuit to be closed")
+	}
+	expected := []CircuitBreakerState{CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(transitions) != len(expected) {
+		t.Fatalf("Expected %d transitions, got %d", len(expected), len(transitions))
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("Expected transition %d to be %s, got %s", i, expected[i], transitions[i])
+		}
+	}
+}
+
+func TestCircuitBreakerReopensWhenTrialLoadFails(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	transitions := make([]CircuitBreakerState, 0)
+	breaker := newTestCircuitBreaker(clock, &transitions)
+	for i := 0; i < 4; i++ {
+		breaker.allow()
+		breaker.record(errors.New("backend down"))
+	}
+
+	// execute
+	clock.Set(time.Unix(1011, 0))
+	breaker.allow()
+	breaker.record(errors.New("still down"))
+	allowErr := breaker.allow()
+
+	// verify
+	if breaker.currentState() != CircuitOpen {
+		t.Errorf("Expected circuit to be open")
+	}
+	if !errors.Is(allowErr, ErrCircuitOpen) {
+		t.Errorf("Expected load to fail fast during the new cool down")
+	}
+}
+
+func TestWhenCircuitIsOpenBlockingCacheServesExpiredValueWithoutLoading(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	loads := 0
+	failing := false
+	openCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{
+		Clock: clock,
+	}).
+		SetExpiration(time.Second).
+		SetCircuitBreaker(CircuitBreakerConfig{MinimumLoads: 1, WindowSize: 1}).
+		Build(func(k string) (string, error) {
+			loads++
+			if failing {
+				return "", errors.New("backend down")
+			}
+			return "value", nil
+		})
+	openCache.Get("key")
+	failing = true
+	clock.Set(time.Unix(1002, 0))
+	openCache.Get("other")
+
+	// execute
+	value, exists := openCache.Get("key")
+	_, missingExists := openCache.Get("missing")
+
+	// verify
+	if !exists || value != "value" {
+		t.Errorf("Expected expired value to be served while the circuit is open")
+	}
+	if missingExists {
+		t.Errorf("Expected missing key to fail fast while the circuit is open")
+	}
+	if loads != 2 {
+		t.Errorf("Expected loader to be called twice, got %d", loads)
+	}
+}
@@ -4,6 +4,27 @@ import "time"
 
 type Clock interface {
 	Now() time.Time
+	// NewTimer creates a timer that sends the current time on its channel once d has passed.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc calls f in its own goroutine once d has passed, the channel of the returned timer is nil.
+	AfterFunc(d time.Duration, f func()) Timer
+	// NewTicker creates a ticker that sends the current time on its channel every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a time.Timer created by a Clock.
+type Timer interface {
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, returns false if the timer already fired or was stopped.
+	Stop() bool
+	// Reset changes the timer to fire after d, returns true if the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// Ticker is a time.Ticker created by a Clock.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
 }
 
 type LocalClock struct {
@@ -12,3 +33,43 @@ type LocalClock struct {
 func (l LocalClock) Now() time.Time {
 	return time.Now()
 }
+
+func (l LocalClock) NewTimer(d time.Duration) Timer {
+	return localTimer{timer: time.NewTimer(d)}
+}
+
+func (l LocalClock) AfterFunc(d time.Duration, f func()) Timer {
+	return localTimer{timer: time.AfterFunc(d, f)}
+}
+
+func (l LocalClock) NewTicker(d time.Duration) Ticker {
+	return localTicker{ticker: time.NewTicker(d)}
+}
+
+type localTimer struct {
+	timer *time.Timer
+}
+
+func (l localTimer) C() <-chan time.Time {
+	return l.timer.C
+}
+
+func (l localTimer) Stop() bool {
+	return l.timer.Stop()
+}
+
+func (l localTimer) Reset(d time.Duration) bool {
+	return l.timer.Reset(d)
+}
+
+type localTicker struct {
+	ticker *time.Ticker
+}
+
+func (l localTicker) C() <-chan time.Time {
+	return l.ticker.C
+}
+
+func (l localTicker) Stop() {
+	l.ticker.Stop()
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Codec converts keys and values to bytes and back. It is used whenever entries leave memory, for example to snapshot a cache.
+type Codec[K comparable, V any] interface {
+	EncodeKey(k K) ([]byte, error)
+	DecodeKey(data []byte) (K, error)
+	EncodeValue(v V) ([]byte, error)
+	DecodeValue(data []byte) (V, error)
+}
+
+// GobCodec is a Codec using encoding/gob. Keys and values have to be encodable by gob, so structs need exported fields and
+// concrete types stored in interfaces have to be registered with gob.Register.
+type GobCodec[K comparable, V any] struct {
+}
+
+func (g GobCodec[K, V]) EncodeKey(k K) ([]byte, error) {
+	return gobEncode(k)
+}
+
+func (g GobCodec[K, V]) DecodeKey(data []byte) (K, error) {
+	var k K
+	err := gobDecode(data, &k)
+	return k, err
+}
+
+func (g GobCodec[K, V]) EncodeValue(v V) ([]byte, error) {
+	return gobEncode(v)
+}
+
+func (g GobCodec[K, V]) DecodeValue(data []byte) (V, error) {
+	var v V
+	err := gobDecode(data, &v)
+	return v, err
+}
+
+// gobValue wraps encoded keys and values, gob leaves out nil pointers and zero values in a struct instead of failing on them
+type gobValue[T any] struct {
+	Value T
+}
+
+func gobEncode[T any](value T) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	if err := gob.NewEncoder(buffer).Encode(gobValue[T]{Value: value}); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func gobDecode[T any](data []byte, value *T) error {
+	decoded := gobValue[T]{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&decoded); err != nil {
+		return err
+	}
+	*value = decoded.Value
+	return nil
+}
@@ -0,0 +1,236 @@
+// Package debughttp provides an http.Handler to inspect and manage the caches of a cache.Registry while a service is running.
+//
+// The handler answers with JSON:
+//
+//	GET    /                  lists the caches with their configuration, size and stats
+//	GET    /{cache}           returns one cache
+//	DELETE /{cache}           removes every entry from the cache
+//	GET    /{cache}/keys/{key} returns the value of the key without loading it
+//	DELETE /{cache}/keys/{key} removes the key from the cache
+//
+// Cache names and keys containing a slash have to be escaped. Mount the handler under a prefix with http.StripPrefix.
+package debughttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+type Options struct {
+	// Registry is the registry of the caches served by the handler.
+	// Defaults to cache.DefaultRegistry
+	Registry *cache.Registry
+	// ReadOnly rejects requests that change a cache with 403 Forbidden.
+	// Defaults to false
+	ReadOnly bool
+}
+
+type handler struct {
+	registry *cache.Registry
+	readOnly bool
+}
+
+// NewHandler creates the handler with the options.
+func NewHandler(options Options) http.Handler {
+	registry := options.Registry
+	if registry == nil {
+		registry = cache.DefaultRegistry
+	}
+	return &handler{
+		registry: registry,
+		readOnly: options.ReadOnly,
+	}
+}
+
+type cacheResponse struct {
+	Name            string        `json:"name"`
+	CacheType       string        `json:"cacheType"`
+	MaxSize         int           `json:"maxSize"`
+	Expiration      string        `json:"expiration"`
+	EvictionPercent int           `json:"evictionPercent"`
+	Size            int           `json:"size"`
+	Stats           statsResponse `json:"stats"`
+}
+
+type statsResponse struct {
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRate       float64 `json:"hitRate"`
+	Loads         uint64  `json:"loads"`
+	LoadFailures  uint64  `json:"loadFailures"`
+	TotalLoadTime string  `json:"totalLoadTime"`
+	Removals      uint64  `json:"removals"`
+}
+
+type keyResponse struct {
+	Key   string `json:"key"`
+	Found bool   `json:"found"`
+	Value any    `json:"value,omitempty"`
+}
+
+type invalidateResponse struct {
+	Key     string `json:"key,omitempty"`
+	Removed bool   `json:"removed"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments, err := pathSegments(r.URL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(segments) == 0 {
+		h.listCaches(w, r)
+		return
+	}
+
+	registered, exists := h.registry.Get(segments[0])
+	if !exists {
+		writeError(w, http.StatusNotFound, fmt.Errorf("cache %q not found", segments[0]))
+		return
+	}
+	switch {
+	case len(segments) == 1:
+		h.serveCache(w, r, registered)
+	case len(segments) == 3 && segments[1] == "keys":
+		h.serveKey(w, r, registered, segments[2])
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (h *handler) listCaches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	caches := []cacheResponse{}
+	for _, registered := range h.registry.List() {
+		caches = append(caches, newCacheResponse(registered))
+	}
+	writeJSON(w, http.StatusOK, caches)
+}
+
+func (h *handler) serveCache(w http.ResponseWriter, r *http.Request, registered cache.RegisteredCache) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, newCacheResponse(registered))
+	case http.MethodDelete:
+		if !h.allowChanges(w) {
+			return
+		}
+		registered.InvalidateAll()
+		writeJSON(w, http.StatusOK, invalidateResponse{Removed: true})
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+func (h *handler) serveKey(w http.ResponseWriter, r *http.Request, registered cache.RegisteredCache, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		value, found, err := registered.Lookup(key)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		response := keyResponse{Key: key, Found: found}
+		if found {
+			response.Value = jsonValue(value)
+		}
+		writeJSON(w, http.StatusOK, response)
+	case http.MethodDelete:
+		if !h.allowChanges(w) {
+			return
+		}
+		removed, err := registered.Invalidate(key)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, invalidateResponse{Key: key, Removed: removed})
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+func (h *handler) allowChanges(w http.ResponseWriter) bool {
+	if h.readOnly {
+		writeError(w, http.StatusForbidden, errors.New("handler is read only"))
+		return false
+	}
+	return true
+}
+
+func newCacheResponse(registered cache.RegisteredCache) cacheResponse {
+	config := registered.Config()
+	stats := registered.Stats()
+	return cacheResponse{
+		Name:            registered.Name(),
+		CacheType:       config.CacheType.String(),
+		MaxSize:         config.MaxSize,
+		Expiration:      config.Expiration.String(),
+		EvictionPercent: config.EvictionPercent,
+		Size:            registered.Size(),
+		Stats: statsResponse{
+			Hits:          stats.Hits,
+			Misses:        stats.Misses,
+			HitRate:       stats.HitRate(),
+			Loads:         stats.Loads,
+			LoadFailures:  stats.LoadFailures,
+			TotalLoadTime: stats.TotalLoadTime.String(),
+			Removals:      stats.Removals,
+		},
+	}
+}
+
+// pathSegments splits the escaped path, so escaped slashes in cache names and keys do not split them
+func pathSegments(u *url.URL) ([]string, error) {
+	path := strings.Trim(u.EscapedPath(), "/")
+	if path == "" {
+		return nil, nil
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, err
+		}
+		segments[i] = unescaped
+	}
+	return segments, nil
+}
+
+// jsonValue returns values that can not be encoded to JSON in their fmt representation
+func jsonValue(value any) any {
+	if _, err := json.Marshal(value); err != nil {
+		return fmt.Sprintf("%+v", value)
+	}
+	return value
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,135 @@
+package debughttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+func newTestRegistry(t *testing.T) (*cache.Registry, cache.Cache[int, string]) {
+	registry := cache.NewRegistry()
+	users := cache.NewCacheBuilder[int, string]().
+		SetName("users").
+		SetRegistry(registry).
+		SetMaxSize(100).
+		SetExpiration(time.Minute).
+		Build(func(k int) (string, error) {
+			return "user", nil
+		})
+	t.Cleanup(func() {
+		users.Close()
+	})
+	return registry, users
+}
+
+func serve(handler http.Handler, method string, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	return recorder
+}
+
+func TestListCaches(t *testing.T) {
+	// setup
+	registry, users := newTestRegistry(t)
+	users.Get(1)
+	users.Get(1)
+	handler := NewHandler(Options{Registry: registry})
+
+	// execute
+	response := serve(handler, http.MethodGet, "/")
+
+	// verify
+	caches := []cacheResponse{}
+	if err := json.NewDecoder(response.Body).Decode(&caches); err != nil {
+		t.Fatalf("Expected a JSON list of caches: %v", err)
+	}
+	if len(caches) != 1 {
+		t.Fatalf("Expected 1 cache, got %d", len(caches))
+	}
+	usersCache := caches[0]
+	if usersCache.Name != "users" || usersCache.MaxSize != 100 || usersCache.Expiration != "1m0s" || usersCache.CacheType != "blocking" {
+		t.Errorf("Expected the configuration of 'users', got %+v", usersCache)
+	}
+	if usersCache.Size != 1 || usersCache.Stats.HitRate != 0.5 {
+		t.Errorf("Expected size 1 and hit rate 0.5, got %d and %f", usersCache.Size, usersCache.Stats.HitRate)
+	}
+}
+
+func TestLookupAndInvalidateKey(t *testing.T) {
+	// setup
+	registry, users := newTestRegistry(t)
+	users.Put(1, "sam")
+	handler := NewHandler(Options{Registry: registry})
+
+	// execute
+	lookup := serve(handler, http.MethodGet, "/users/keys/1")
+	invalidate := serve(handler, http.MethodDelete, "/users/keys/1")
+	invalidParse := serve(handler, http.MethodGet, "/users/keys/sam")
+
+	// verify
+	found := keyResponse{}
+	json.NewDecoder(lookup.Body).Decode(&found)
+	if !found.Found || found.Value != "sam" {
+		t.Errorf("Expected key 1 to be 'sam', got %+v", found)
+	}
+	if invalidate.Code != http.StatusOK || users.Contains(1) {
+		t.Errorf("Expected key 1 to be invalidated")
+	}
+	if invalidParse.Code != http.StatusBadRequest {
+		t.Errorf("Expected a key that is not an int to be rejected, got %d", invalidParse.Code)
+	}
+}
+
+func TestClearCache(t *testing.T) {
+	// setup
+	registry, users := newTestRegistry(t)
+	users.Put(1, "sam")
+	users.Put(2, "alex")
+	handler := NewHandler(Options{Registry: registry})
+
+	// execute
+	response := serve(handler, http.MethodDelete, "/users")
+
+	// verify
+	if response.Code != http.StatusOK || users.Len() != 0 {
+		t.Errorf("Expected 'users' to be cleared")
+	}
+}
+
+func TestReadOnlyHandlerRejectsChanges(t *testing.T) {
+	// setup
+	registry, users := newTestRegistry(t)
+	users.Put(1, "sam")
+	handler := NewHandler(Options{Registry: registry, ReadOnly: true})
+
+	// execute
+	clear := serve(handler, http.MethodDelete, "/users")
+	invalidate := serve(handler, http.MethodDelete, "/users/keys/1")
+	lookup := serve(handler, http.MethodGet, "/users/keys/1")
+
+	// verify
+	if clear.Code != http.StatusForbidden || invalidate.Code != http.StatusForbidden {
+		t.Errorf("Expected changes to be forbidden, got %d and %d", clear.Code, invalidate.Code)
+	}
+	if lookup.Code != http.StatusOK || !users.Contains(1) {
+		t.Errorf("Expected lookups to be allowed and the cache to be unchanged")
+	}
+}
+
+func TestUnknownCacheIsNotFound(t *testing.T) {
+	// setup
+	registry, _ := newTestRegistry(t)
+	handler := NewHandler(Options{Registry: registry})
+
+	// execute
+	response := serve(handler, http.MethodGet, "/accounts")
+
+	// verify
+	if response.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", response.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrExecutorFull is returned when a task is rejected because the executor queue is full.
+var ErrExecutorFull = errors.New("cache: executor queue is full")
+
+// ErrExecutorClosed is returned when a task is rejected because the executor has been shut down.
+var ErrExecutorClosed = errors.New("cache: executor is closed")
+
+const defaultExecutorWorkers = 10
+const defaultExecutorQueueSize = 100
+
+// Executor runs background work for a cache, for example refreshing expired entries.
+type Executor interface {
+	// Execute schedules the task to be run. If an error is returned the task was rejected and will not be run.
+	Execute(task func()) error
+	// Shutdown stops accepting new tasks and waits for queued and running tasks to finish.
+	// If the context is done first, queued tasks that have not started are dropped and the context error is returned.
+	Shutdown(ctx context.Context) error
+}
+
+// BoundedExecutor is an Executor that runs at most a fixed number of tasks at the same time and queues up to a fixed number of waiting tasks.
+// Tasks submitted while the queue is full are rejected. Workers are only running while there are tasks, so an idle executor holds no goroutines.
+type BoundedExecutor struct {
+	maxWorkers   int
+	maxQueueSize int
+
+	lock     *sync.Mutex
+	queue    []func()
+	running  int
+	rejected uint64
+	closed   bool
+	// stopped is closed once the executor is shut down and the last worker has exited
+	stopped chan struct{}
+}
+
+// NewBoundedExecutor creates an executor running at most workers tasks at the same time with room for queueSize waiting tasks.
+func NewBoundedExecutor(workers int, queueSize int) *BoundedExecutor {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	return &BoundedExecutor{
+		maxWorkers:   workers,
+		maxQueueSize: queueSize,
+		lock:         &sync.Mutex{},
+		queue:        make([]func(), 0),
+	}
+}
+
+func (e *BoundedExecutor) Execute(task func()) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.closed {
+		e.rejected++
+		return ErrExecutorClosed
+	}
+
+	if e.running < e.maxWorkers {
+		e.running++
+		go e.work(task)
+		return nil
+	}
+
+	if len(e.queue) >= e.maxQueueSize {
+		e.rejected++
+		return ErrExecutorFull
+	}
+	e.queue = append(e.queue, task)
+	return nil
+}
+
+// work runs the given task and then keeps taking tasks from the queue until it is empty
+func (e *BoundedExecutor) work(task func()) {
+	for task != nil {
+		task()
+		task = e.next()
+	}
+}
+
+func (e *BoundedExecutor) next() func() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if len(e.queue) == 0 {
+		e.running--
+		if e.closed && e.running == 0 {
+			close(e.stopped)
+		}
+		return nil
+	}
+
+	task := e.queue[0]
+	e.queue[0] = nil
+	e.queue = e.queue[1:]
+	return task
+}
+
+func (e *BoundedExecutor) Shutdown(ctx context.Context) error {
+	e.lock.Lock()
+	if !e.closed {
+		e.closed = true
+		e.stopped = make(chan struct{})
+		if e.running == 0 {
+			close(e.stopped)
+		}
+	}
+	stopped := e.stopped
+	e.lock.Unlock()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		// drop the tasks that have not started, the running ones will finish on their own
+		e.lock.Lock()
+		e.queue = nil
+		e.lock.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting new tasks and waits for all queued and running tasks to finish.
+func (e *BoundedExecutor) Close() error {
+	return e.Shutdown(context.Background())
+}
+
+// QueueDepth returns the number of tasks waiting for a worker.
+func (e *BoundedExecutor) QueueDepth() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return len(e.queue)
+}
+
+// Running returns the number of tasks currently being run.
+func (e *BoundedExecutor) Running() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.running
+}
+
+// Rejected returns the total number of tasks that were rejected because the queue was full or the executor was closed.
+func (e *BoundedExecutor) Rejected() uint64 {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.rejected
+}
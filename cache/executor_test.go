@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBoundedExecutorQueuesTasksWhenAllWorkersAreBusy(t *testing.T) {
+	// setup
+	executor := NewBoundedExecutor(1, 2)
+	release := make(chan struct{})
+	var ran int32
+
+	// execute
+	for i := 0; i < 3; i++ {
+		err := executor.Execute(func() {
+			<-release
+			atomic.AddInt32(&ran, 1)
+		})
+		if err != nil {
+			t.Fatalf("Expected task %d to be accepted", i)
+		}
+	}
+	queueDepth := executor.QueueDepth()
+	close(release)
+	executor.Close()
+
+	// verify
+	if queueDepth != 2 {
+		t.Errorf("Expected 2 queued tasks, got %d", queueDepth)
+	}
+	if atomic.LoadInt32(&ran) != 3 {
+		t.Errorf("Expected all tasks to run before close returns")
+	}
+}
+
+func TestBoundedExecutorRejectsTasksWhenQueueIsFull(t *testing.T) {
+	// setup
+	executor := NewBoundedExecutor(1, 1)
+	release := make(chan struct{})
+	executor.Execute(func() { <-release })
+	executor.Execute(func() {})
+
+	// execute
+	err := executor.Execute(func() {})
+	close(release)
+	executor.Close()
+
+	// verify
+	if !errors.Is(err, ErrExecutorFull) {
+		t.Errorf("Expected task to be rejected")
+	}
+	if executor.Rejected() != 1 {
+		t.Errorf("Expected rejected count to be 1")
+	}
+}
+
+func TestBoundedExecutorRejectsTasksAfterShutdown(t *testing.T) {
+	// setup
+	executor := NewBoundedExecutor(1, 1)
+	executor.Close()
+
+	// execute
+	err := executor.Execute(func() {})
+
+	// verify
+	if !errors.Is(err, ErrExecutorClosed) {
+		t.Errorf("Expected task to be rejected")
+	}
+}
+
+func TestBoundedExecutorShutdownDropsQueuedTasksWhenContextIsDone(t *testing.T) {
+	// setup
+	executor := NewBoundedExecutor(1, 1)
+	release := make(chan struct{})
+	var ran int32
+	executor.Execute(func() { <-release })
+	executor.Execute(func() { atomic.AddInt32(&ran, 1) })
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	// execute
+	err := executor.Shutdown(ctx)
+	close(release)
+	executor.Close()
+
+	// verify
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected shutdown to time out")
+	}
+	if atomic.LoadInt32(&ran) != 0 {
+		t.Errorf("Expected queued task to be dropped")
+	}
+}
+
+func TestWhenRefreshIsRejectedRefreshCacheCallsHookAndServesExpiredValue(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	executor := NewBoundedExecutor(1, 1)
+	executor.Close()
+	rejected := make([]string, 0)
+	refreshCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{
+		Clock: clock,
+	}).
+		SetCacheType(Refresh).
+		SetExpiration(time.Second).
+		SetExecutor(executor).
+		SetHooks(CacheHooks[string]{
+			OnRefreshRejected: func(k string) {
+				rejected = append(rejected, k)
+			},
+		}).
+		Build(func(k string) (string, error) {
+			return "value", nil
+		})
+	refreshCache.Get("key")
+	clock.Set(time.Unix(1002, 0))
+
+	// execute
+	value, exists := refreshCache.Get("key")
+
+	// verify
+	if !exists || value != "value" {
+		t.Errorf("Expected expired value to be served")
+	}
+	if len(rejected) != 1 || rejected[0] != "key" {
+		t.Errorf("Expected rejected hook to be called for 'key'")
+	}
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"expvar"
+)
+
+// expvarCache - the JSON published for a cache, durations are in seconds
+type expvarCache struct {
+	CacheType         string  `json:"cacheType"`
+	MaxSize           int     `json:"maxSize"`
+	ExpirationSeconds float64 `json:"expirationSeconds"`
+	EvictionPercent   int     `json:"evictionPercent"`
+	Size              int     `json:"size"`
+	Hits              uint64  `json:"hits"`
+	Misses            uint64  `json:"misses"`
+	HitRate           float64 `json:"hitRate"`
+	Loads             uint64  `json:"loads"`
+	LoadFailures      uint64  `json:"loadFailures"`
+	LoadSeconds       float64 `json:"loadSeconds"`
+	Removals          uint64  `json:"removals"`
+}
+
+func newExpvarCache(registered RegisteredCache) expvarCache {
+	config := registered.Config()
+	stats := registered.Stats()
+	return expvarCache{
+		CacheType:         config.CacheType.String(),
+		MaxSize:           config.MaxSize,
+		ExpirationSeconds: config.Expiration.Seconds(),
+		EvictionPercent:   config.EvictionPercent,
+		Size:              registered.Size(),
+		Hits:              stats.Hits,
+		Misses:            stats.Misses,
+		HitRate:           stats.HitRate(),
+		Loads:             stats.Loads,
+		LoadFailures:      stats.LoadFailures,
+		LoadSeconds:       stats.TotalLoadTime.Seconds(),
+		Removals:          stats.Removals,
+	}
+}
+
+// ExpvarVar returns an expvar.Var with the configuration, size and counters of the registered cache, the values are read every time the var is read.
+func ExpvarVar(registered RegisteredCache) expvar.Var {
+	return expvar.Func(func() any {
+		return newExpvarCache(registered)
+	})
+}
+
+// PublishExpvar publishes the registered cache as the expvar variable name, so it is served on /debug/vars. Like expvar.Publish it panics if the name is already used.
+func PublishExpvar(name string, registered RegisteredCache) expvar.Var {
+	published := ExpvarVar(registered)
+	expvar.Publish(name, published)
+	return published
+}
+
+// PublishRegistryExpvar publishes all caches of the registry as the expvar variable name, keyed by cache name.
+// Caches registered after publishing are included and closed caches are left out. Like expvar.Publish it panics if the name is already used.
+func PublishRegistryExpvar(name string, registry *Registry) expvar.Var {
+	published := expvar.Func(func() any {
+		caches := make(map[string]expvarCache)
+		for _, registered := range registry.List() {
+			caches[registered.Name()] = newExpvarCache(registered)
+		}
+		return caches
+	})
+	expvar.Publish(name, published)
+	return published
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"testing"
+)
+
+// expvarNames - published vars can not be removed, so every published var gets a new name when tests run more than once
+var expvarNames = 0
+
+func uniqueExpvarName(t *testing.T) string {
+	expvarNames++
+	return fmt.Sprintf("%s-%d", t.Name(), expvarNames)
+}
+
+func TestExpvarIsUpdatedLive(t *testing.T) {
+	// setup
+	registry := NewRegistry()
+	users := buildRegisteredTestCache(registry, "users")
+	registered, _ := registry.Get("users")
+	name := uniqueExpvarName(t)
+	published := PublishExpvar(name, registered)
+
+	// execute
+	users.Get("a")
+	users.Get("a")
+	decoded := expvarCache{}
+	err := json.Unmarshal([]byte(published.String()), &decoded)
+
+	// verify
+	if err != nil {
+		t.Fatalf("Expected the var to be JSON: %v", err)
+	}
+	if decoded.MaxSize != 50 || decoded.ExpirationSeconds != 60 || decoded.CacheType != "blocking" {
+		t.Errorf("Expected the configuration of the cache, got %+v", decoded)
+	}
+	if decoded.Size != 1 || decoded.Hits != 1 || decoded.Misses != 1 || decoded.Loads != 1 {
+		t.Errorf("Expected size 1 with 1 hit, 1 miss and 1 load, got %+v", decoded)
+	}
+	if expvar.Get(name) == nil {
+		t.Errorf("Expected the var to be published")
+	}
+}
+
+func TestRegistryExpvarIncludesLaterRegisteredCaches(t *testing.T) {
+	// setup
+	registry := NewRegistry()
+	published := PublishRegistryExpvar(uniqueExpvarName(t), registry)
+
+	// execute
+	buildRegisteredTestCache(registry, "users")
+	decoded := map[string]expvarCache{}
+	json.Unmarshal([]byte(published.String()), &decoded)
+
+	// verify
+	if _, exists := decoded["users"]; !exists || len(decoded) != 1 {
+		t.Errorf("Expected 'users' to be published, got %v", decoded)
+	}
+}
@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// FakeClock is a Clock for tests where time only moves when Advance or Set is called. Timers, tickers and AfterFunc functions fire
+// when the time reaches them, in the order of their deadlines. It is safe for concurrent use.
+type FakeClock struct {
+	lock   *sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// changed is closed and replaced whenever a timer is added, so WaitForTimers can wait without polling
+	changed chan struct{}
+}
+
+// fakeTimer - a timer, ticker or AfterFunc of a FakeClock
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	// period is the interval of a ticker, 0 for timers
+	period time.Duration
+	c      chan time.Time
+	f      func()
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		lock:    &sync.Mutex{},
+		now:     now,
+		changed: make(chan struct{}),
+	}
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.now
+}
+
+// Advance moves the time forward by d and fires the timers that are due.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.setLocked(f.now.Add(d))
+}
+
+// Set sets the time and fires the timers that are due, setting the time back does not fire any timers.
+func (f *FakeClock) Set(now time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.setLocked(now)
+}
+
+func (f *FakeClock) NewTimer(d time.Duration) Timer {
+	return f.add(d, 0, nil)
+}
+
+func (f *FakeClock) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.add(d, 0, fn)
+}
+
+func (f *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d < 1 {
+		panic("cache: non-positive interval for NewTicker")
+	}
+	return fakeTicker{timer: f.add(d, d, nil)}
+}
+
+// Timers returns the number of timers, tickers and AfterFunc functions that have not fired or been stopped.
+func (f *FakeClock) Timers() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.timers)
+}
+
+// WaitForTimers blocks until at least n timers are waiting, so a test can advance the time after a goroutine created its timer.
+func (f *FakeClock) WaitForTimers(n int) {
+	for {
+		f.lock.Lock()
+		waiting := len(f.timers)
+		changed := f.changed
+		f.lock.Unlock()
+		if waiting >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+func (f *FakeClock) add(d time.Duration, period time.Duration, fn func()) *fakeTimer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	timer := &fakeTimer{
+		clock:  f,
+		period: period,
+		f:      fn,
+	}
+	if fn == nil {
+		// buffered like the channels of time.Timer, a tick that is not received in time is dropped
+		timer.c = make(chan time.Time, 1)
+	}
+	f.scheduleLocked(timer, f.now.Add(d))
+	return timer
+}
+
+func (f *FakeClock) scheduleLocked(timer *fakeTimer, deadline time.Time) {
+	timer.deadline = deadline
+	f.timers = append(f.timers, timer)
+	close(f.changed)
+	f.changed = make(chan struct{})
+	// timers with a deadline that already passed fire right away
+	f.fireLocked()
+}
+
+func (f *FakeClock) setLocked(now time.Time) {
+	f.now = now
+	f.fireLocked()
+}
+
+// fireLocked fires all timers due at the current time in the order of their deadlines
+func (f *FakeClock) fireLocked() {
+	sort.SliceStable(f.timers, func(i, j int) bool {
+		return f.timers[i].deadline.Before(f.timers[j].deadline)
+	})
+	for len(f.timers) > 0 && !f.timers[0].deadline.After(f.now) {
+		timer := f.timers[0]
+		f.timers = f.timers[1:]
+		timer.fire(f.now)
+		if timer.period > 0 {
+			// a ticker is rescheduled after the current time, ticks missed in between are dropped like with time.Ticker
+			next := timer.deadline.Add(timer.period)
+			for !next.After(f.now) {
+				next = next.Add(timer.period)
+			}
+			timer.deadline = next
+			f.timers = append(f.timers, timer)
+			sort.SliceStable(f.timers, func(i, j int) bool {
+				return f.timers[i].deadline.Before(f.timers[j].deadline)
+			})
+		}
+	}
+}
+
+func (t *fakeTimer) fire(now time.Time) {
+	if t.f != nil {
+		go t.f()
+		return
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	return t.clock.removeLocked(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	active := t.clock.removeLocked(t)
+	t.clock.scheduleLocked(t, t.clock.now.Add(d))
+	return active
+}
+
+func (f *FakeClock) removeLocked(timer *fakeTimer) bool {
+	for i, waiting := range f.timers {
+		if waiting == timer {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTicker struct {
+	timer *fakeTimer
+}
+
+func (t fakeTicker) C() <-chan time.Time {
+	return t.timer.C()
+}
+
+func (t fakeTicker) Stop() {
+	t.timer.Stop()
+}
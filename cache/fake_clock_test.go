@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestFakeClockFiresTimersWhenAdvanced(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	timer := clock.NewTimer(time.Second)
+	called := make(chan struct{})
+	clock.AfterFunc(time.Second*2, func() { close(called) })
+
+	// execute
+	clock.Advance(time.Millisecond * 999)
+	firedEarly := len(timer.C()) > 0
+	clock.Advance(time.Millisecond)
+	fired := <-timer.C()
+	clock.Set(time.Unix(1002, 0))
+
+	// verify
+	if firedEarly {
+		t.Errorf("Expected timer not to fire before its deadline")
+	}
+	if !fired.Equal(time.Unix(1001, 0)) {
+		t.Errorf("Expected timer to send the time it fired at, got %v", fired)
+	}
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Errorf("Expected AfterFunc to be called")
+	}
+}
+
+func TestFakeClockTickerDropsMissedTicks(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	ticker := clock.NewTicker(time.Second)
+
+	// execute
+	clock.Advance(time.Second * 3)
+	ticks := len(ticker.C())
+	<-ticker.C()
+	clock.Advance(time.Second)
+	nextTicks := len(ticker.C())
+	ticker.Stop()
+	clock.Advance(time.Second * 10)
+
+	// verify
+	if ticks != 1 {
+		t.Errorf("Expected 1 tick for 3 missed periods, got %d", ticks)
+	}
+	if nextTicks != 1 {
+		t.Errorf("Expected a tick for the next period, got %d", nextTicks)
+	}
+	if clock.Timers() != 0 {
+		t.Errorf("Expected a stopped ticker to be removed")
+	}
+}
+
+func TestFakeClockStopAndReset(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	timer := clock.NewTimer(time.Second)
+
+	// execute
+	stopped := timer.Stop()
+	stoppedAgain := timer.Stop()
+	clock.Advance(time.Second)
+	firedAfterStop := len(timer.C())
+	active := timer.Reset(time.Second)
+	clock.Advance(time.Second)
+
+	// verify
+	if !stopped || stoppedAgain || active {
+		t.Errorf("Expected only the first stop to stop an active timer")
+	}
+	if firedAfterStop != 0 {
+		t.Errorf("Expected a stopped timer not to fire")
+	}
+	if len(timer.C()) != 1 {
+		t.Errorf("Expected a reset timer to fire")
+	}
+}
+
+func TestFakeClockDrivesPeriodicSnapshots(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	written := make(chan struct{}, 1)
+	snapshots := newSnapshotter(t.TempDir()+"/users.snapshot", time.Minute, clock, nil)
+	snapshots.start(func(w io.Writer) error {
+		written <- struct{}{}
+		return nil
+	})
+	defer snapshots.stopAndWrite(func(w io.Writer) error { return nil })
+
+	// execute
+	clock.WaitForTimers(1)
+	clock.Advance(time.Minute)
+
+	// verify
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Errorf("Expected a snapshot to be written after the interval")
+	}
+}
@@ -0,0 +1,435 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultFileStorageMaxEntries = 10000
+const defaultSegmentSize = 16 << 20
+
+// recordHeaderSize is the size of the length and checksum in front of every record
+const recordHeaderSize = 8
+
+type FileStorageOptions[K comparable, V any] struct {
+	// MaxEntries is the maximum number of entries in the storage, the entries that were put first are removed when the storage is full.
+	// Defaults to 10000
+	MaxEntries int
+	// TTL is how long an entry is kept in the storage after it was put. 0 means entries do not expire in the storage.
+	TTL time.Duration
+	// SegmentSize is the size in bytes after which a new segment file is started.
+	// Defaults to 16MB
+	SegmentSize int64
+	// Codec is used to encode the keys and values in the segment files.
+	// Defaults to GobCodec
+	Codec Codec[K, V]
+	// Clock is used to expire entries.
+	// Defaults to LocalClock
+	Clock Clock
+}
+
+// FileStorage is a Storage keeping entries in append only segment files in a directory with an index of the entries in memory.
+// Segment files are removed once none of their entries are used anymore, so the directory can hold more data than MaxEntries for a while.
+// The index is rebuilt from the segment files when a storage is created for a directory that already has segment files.
+type FileStorage[K comparable, V any] struct {
+	dir     string
+	options FileStorageOptions[K, V]
+
+	lock *sync.Mutex
+	// segments oldest first, new records are appended to the last segment
+	segments []*fileSegment
+	index    map[K]*fileLocation
+	// order has the keys of the index, the first one was put first
+	order  *list.List
+	closed bool
+}
+
+type fileSegment struct {
+	id   int
+	file *os.File
+	size int64
+	// live is the number of entries in the index stored in this segment
+	live int
+}
+
+type fileLocation struct {
+	segment  *fileSegment
+	offset   int64
+	length   int
+	storedAt time.Time
+	element  *list.Element
+}
+
+type fileRecord struct {
+	Key            []byte
+	Value          []byte
+	Deleted        bool
+	StoredAt       time.Time
+	InsertTime     time.Time
+	LastAccessTime time.Time
+	LastUpdateTime time.Time
+}
+
+// NewFileStorage opens a file storage in the directory, creating the directory if it does not exist.
+func NewFileStorage[K comparable, V any](dir string, options FileStorageOptions[K, V]) (*FileStorage[K, V], error) {
+	if options.MaxEntries < 1 {
+		options.MaxEntries = defaultFileStorageMaxEntries
+	}
+	if options.SegmentSize < 1 {
+		options.SegmentSize = defaultSegmentSize
+	}
+	if options.Codec == nil {
+		options.Codec = GobCodec[K, V]{}
+	}
+	if options.Clock == nil {
+		options.Clock = LocalClock{}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	storage := &FileStorage[K, V]{
+		dir:      dir,
+		options:  options,
+		lock:     &sync.Mutex{},
+		segments: make([]*fileSegment, 0),
+		index:    make(map[K]*fileLocation),
+		order:    list.New(),
+	}
+	if err := storage.load(); err != nil {
+		storage.closeSegments()
+		return nil, err
+	}
+	return storage, nil
+}
+
+func (f *FileStorage[K, V]) Get(k K) (CacheEntry[K, V], bool, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return CacheEntry[K, V]{}, false, ErrClosed
+	}
+
+	location, exists := f.index[k]
+	if !exists {
+		return CacheEntry[K, V]{}, false, nil
+	}
+	if f.isExpired(location) {
+		f.removeFromIndex(k)
+		f.compact()
+		return CacheEntry[K, V]{}, false, nil
+	}
+
+	data := make([]byte, location.length)
+	if _, err := location.segment.file.ReadAt(data, location.offset); err != nil {
+		return CacheEntry[K, V]{}, false, err
+	}
+	record := fileRecord{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil {
+		return CacheEntry[K, V]{}, false, err
+	}
+	value, err := f.options.Codec.DecodeValue(record.Value)
+	if err != nil {
+		return CacheEntry[K, V]{}, false, err
+	}
+	return CacheEntry[K, V]{
+		Key:            k,
+		Value:          value,
+		InsertTime:     record.InsertTime,
+		LastAccessTime: record.LastAccessTime,
+		LastUpdateTime: record.LastUpdateTime,
+	}, true, nil
+}
+
+func (f *FileStorage[K, V]) Put(entries []CacheEntry[K, V]) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+
+	for _, entry := range entries {
+		key, err := f.options.Codec.EncodeKey(entry.Key)
+		if err != nil {
+			return err
+		}
+		value, err := f.options.Codec.EncodeValue(entry.Value)
+		if err != nil {
+			return err
+		}
+		record := fileRecord{
+			Key:            key,
+			Value:          value,
+			StoredAt:       f.options.Clock.Now(),
+			InsertTime:     entry.InsertTime,
+			LastAccessTime: entry.LastAccessTime,
+			LastUpdateTime: entry.LastUpdateTime,
+		}
+		if err := f.append(entry.Key, record); err != nil {
+			return err
+		}
+	}
+
+	for len(f.index) > f.options.MaxEntries {
+		if err := f.delete(f.order.Front().Value.(K)); err != nil {
+			return err
+		}
+	}
+	f.compact()
+	return nil
+}
+
+func (f *FileStorage[K, V]) Delete(k K) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+	if _, exists := f.index[k]; !exists {
+		return nil
+	}
+	if err := f.delete(k); err != nil {
+		return err
+	}
+	f.compact()
+	return nil
+}
+
+func (f *FileStorage[K, V]) Clear() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+
+	for _, segment := range f.segments {
+		segment.file.Close()
+		if err := os.Remove(segment.file.Name()); err != nil {
+			return err
+		}
+	}
+	f.segments = make([]*fileSegment, 0)
+	f.index = make(map[K]*fileLocation)
+	f.order = list.New()
+	return f.startSegment(1)
+}
+
+// Len returns the number of entries in the storage, including entries that have expired but were not removed yet.
+func (f *FileStorage[K, V]) Len() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.index)
+}
+
+// Close closes the segment files, the storage can not be used after it is closed.
+func (f *FileStorage[K, V]) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+	f.closed = true
+	return f.closeSegments()
+}
+
+// delete writes a record for the key that it was deleted, so the entry is not put back in the index when the segment files are loaded again
+func (f *FileStorage[K, V]) delete(k K) error {
+	key, err := f.options.Codec.EncodeKey(k)
+	if err != nil {
+		return err
+	}
+	return f.append(k, fileRecord{Key: key, Deleted: true, StoredAt: f.options.Clock.Now()})
+}
+
+// append writes the record to the last segment and updates the index
+func (f *FileStorage[K, V]) append(k K, record fileRecord) error {
+	payload := &bytes.Buffer{}
+	if err := gob.NewEncoder(payload).Encode(record); err != nil {
+		return err
+	}
+	data := make([]byte, recordHeaderSize+payload.Len())
+	binary.BigEndian.PutUint32(data[0:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(data[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+	copy(data[recordHeaderSize:], payload.Bytes())
+
+	segment := f.segments[len(f.segments)-1]
+	if _, err := segment.file.WriteAt(data, segment.size); err != nil {
+		return err
+	}
+	offset := segment.size + recordHeaderSize
+	segment.size += int64(len(data))
+	f.applyRecord(k, record, segment, offset, payload.Len())
+
+	if segment.size >= f.options.SegmentSize {
+		return f.startSegment(segment.id + 1)
+	}
+	return nil
+}
+
+// applyRecord applies a record written at the offset of the segment to the index
+func (f *FileStorage[K, V]) applyRecord(k K, record fileRecord, segment *fileSegment, offset int64, length int) {
+	f.removeFromIndex(k)
+	if record.Deleted {
+		return
+	}
+	segment.live++
+	f.index[k] = &fileLocation{
+		segment:  segment,
+		offset:   offset,
+		length:   length,
+		storedAt: record.StoredAt,
+		element:  f.order.PushBack(k),
+	}
+}
+
+func (f *FileStorage[K, V]) removeFromIndex(k K) {
+	location, exists := f.index[k]
+	if !exists {
+		return
+	}
+	location.segment.live--
+	f.order.Remove(location.element)
+	delete(f.index, k)
+}
+
+func (f *FileStorage[K, V]) isExpired(location *fileLocation) bool {
+	if f.options.TTL < 1 {
+		return false
+	}
+	return f.options.Clock.Now().After(location.storedAt.Add(f.options.TTL))
+}
+
+// compact removes the oldest segments as long as none of their entries are in the index.
+// Only the oldest segments are removed, so a record marking a key as deleted is never removed while an older record for the key still exists.
+func (f *FileStorage[K, V]) compact() {
+	for len(f.segments) > 1 && f.segments[0].live == 0 {
+		segment := f.segments[0]
+		segment.file.Close()
+		os.Remove(segment.file.Name())
+		f.segments = f.segments[1:]
+	}
+}
+
+func (f *FileStorage[K, V]) segmentPath(id int) string {
+	return filepath.Join(f.dir, fmt.Sprintf("segment-%08d.log", id))
+}
+
+func (f *FileStorage[K, V]) startSegment(id int) error {
+	file, err := os.OpenFile(f.segmentPath(id), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	f.segments = append(f.segments, &fileSegment{id: id, file: file})
+	return nil
+}
+
+// load rebuilds the index from the segment files in the directory
+func (f *FileStorage[K, V]) load() error {
+	paths, err := filepath.Glob(filepath.Join(f.dir, "segment-*.log"))
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(paths))
+	for _, path := range paths {
+		id := 0
+		if _, err := fmt.Sscanf(filepath.Base(path), "segment-%08d.log", &id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		file, err := os.OpenFile(f.segmentPath(id), os.O_RDWR, 0o644)
+		if err != nil {
+			return err
+		}
+		segment := &fileSegment{id: id, file: file}
+		f.segments = append(f.segments, segment)
+		if err := f.loadSegment(segment); err != nil {
+			return err
+		}
+	}
+
+	if len(f.segments) == 0 {
+		if err := f.startSegment(1); err != nil {
+			return err
+		}
+	}
+
+	for k, location := range f.index {
+		if f.isExpired(location) {
+			f.removeFromIndex(k)
+		}
+	}
+	for len(f.index) > f.options.MaxEntries {
+		f.removeFromIndex(f.order.Front().Value.(K))
+	}
+	f.compact()
+	return nil
+}
+
+// loadSegment reads all records of the segment into the index. A record that was only partly written or is corrupt, for example because the process crashed,
+// is cut off with all records after it.
+func (f *FileStorage[K, V]) loadSegment(segment *fileSegment) error {
+	info, err := segment.file.Stat()
+	if err != nil {
+		return err
+	}
+	reader := io.NewSectionReader(segment.file, 0, info.Size())
+	header := make([]byte, recordHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return segment.file.Truncate(segment.size)
+		}
+		// the length of a corrupt header is not trusted beyond the end of the file
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		if length > info.Size()-segment.size-recordHeaderSize {
+			return segment.file.Truncate(segment.size)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil || crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			return segment.file.Truncate(segment.size)
+		}
+
+		record := fileRecord{}
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&record); err != nil {
+			return segment.file.Truncate(segment.size)
+		}
+		k, err := f.options.Codec.DecodeKey(record.Key)
+		if err != nil {
+			return err
+		}
+		f.applyRecord(k, record, segment, segment.size+recordHeaderSize, int(length))
+		segment.size += recordHeaderSize + length
+	}
+}
+
+func (f *FileStorage[K, V]) closeSegments() error {
+	var closeErr error
+	for _, segment := range f.segments {
+		if err := segment.file.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
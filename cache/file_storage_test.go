@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestFileStorage(t *testing.T, dir string, options FileStorageOptions[string, string]) *FileStorage[string, string] {
+	storage, err := NewFileStorage[string, string](dir, options)
+	if err != nil {
+		t.Fatalf("Expected storage to open, got %v", err)
+	}
+	return storage
+}
+
+func storageEntry(k string, v string) CacheEntry[string, string] {
+	return CacheEntry[string, string]{Key: k, Value: v, LastUpdateTime: time.Unix(1000, 0)}
+}
+
+func TestFileStoragePutGetAndDelete(t *testing.T) {
+	// setup
+	storage := newTestFileStorage(t, t.TempDir(), FileStorageOptions[string, string]{})
+	defer storage.Close()
+
+	// execute
+	putErr := storage.Put([]CacheEntry[string, string]{storageEntry("a", "first"), storageEntry("b", "second")})
+	entry, found, getErr := storage.Get("a")
+	deleteErr := storage.Delete("a")
+	_, foundAfterDelete, _ := storage.Get("a")
+
+	// verify
+	if putErr != nil || getErr != nil || deleteErr != nil {
+		t.Fatalf("Expected storage operations to succeed")
+	}
+	if !found || entry.Value != "first" || !entry.LastUpdateTime.Equal(time.Unix(1000, 0)) {
+		t.Errorf("Expected entry 'a' with its timestamps")
+	}
+	if foundAfterDelete {
+		t.Errorf("Expected 'a' to be deleted")
+	}
+	if storage.Len() != 1 {
+		t.Errorf("Expected 1 entry left, got %d", storage.Len())
+	}
+}
+
+func TestFileStorageRebuildsIndexWhenOpenedAgain(t *testing.T) {
+	// setup
+	dir := t.TempDir()
+	storage := newTestFileStorage(t, dir, FileStorageOptions[string, string]{SegmentSize: 200})
+	storage.Put([]CacheEntry[string, string]{storageEntry("a", "first"), storageEntry("b", "second"), storageEntry("c", "third")})
+	storage.Put([]CacheEntry[string, string]{storageEntry("b", "updated")})
+	storage.Delete("a")
+	storage.Close()
+
+	// execute
+	reopened := newTestFileStorage(t, dir, FileStorageOptions[string, string]{SegmentSize: 200})
+	defer reopened.Close()
+	_, aFound, _ := reopened.Get("a")
+	b, bFound, _ := reopened.Get("b")
+	c, cFound, _ := reopened.Get("c")
+
+	// verify
+	if aFound {
+		t.Errorf("Expected deleted entry to stay deleted")
+	}
+	if !bFound || b.Value != "updated" {
+		t.Errorf("Expected 'b' to be 'updated'")
+	}
+	if !cFound || c.Value != "third" {
+		t.Errorf("Expected 'c' to be 'third'")
+	}
+}
+
+func TestFileStorageRemovesOldestEntriesWhenFull(t *testing.T) {
+	// setup
+	storage := newTestFileStorage(t, t.TempDir(), FileStorageOptions[string, string]{MaxEntries: 2})
+	defer storage.Close()
+
+	// execute
+	storage.Put([]CacheEntry[string, string]{storageEntry("a", "1"), storageEntry("b", "2"), storageEntry("c", "3")})
+	_, aFound, _ := storage.Get("a")
+	_, cFound, _ := storage.Get("c")
+
+	// verify
+	if aFound {
+		t.Errorf("Expected oldest entry to be removed")
+	}
+	if !cFound {
+		t.Errorf("Expected newest entry to be kept")
+	}
+}
+
+func TestFileStorageExpiresEntriesAfterTTL(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	storage := newTestFileStorage(t, t.TempDir(), FileStorageOptions[string, string]{TTL: time.Second, Clock: clock})
+	defer storage.Close()
+	storage.Put([]CacheEntry[string, string]{storageEntry("a", "1")})
+
+	// execute
+	clock.Set(time.Unix(1002, 0))
+	_, found, _ := storage.Get("a")
+
+	// verify
+	if found {
+		t.Errorf("Expected entry to be expired")
+	}
+}
+
+func TestFileStorageRemovesSegmentsWithoutEntries(t *testing.T) {
+	// setup
+	dir := t.TempDir()
+	storage := newTestFileStorage(t, dir, FileStorageOptions[string, string]{SegmentSize: 1})
+	defer storage.Close()
+	storage.Put([]CacheEntry[string, string]{storageEntry("a", "1"), storageEntry("b", "2")})
+
+	// execute
+	storage.Delete("a")
+	storage.Delete("b")
+	segments, _ := filepath.Glob(filepath.Join(dir, "segment-*.log"))
+
+	// verify
+	if len(segments) != 1 {
+		t.Errorf("Expected only the active segment to be left, got %d segments", len(segments))
+	}
+}
+
+func TestFileStorageIgnoresPartlyWrittenRecord(t *testing.T) {
+	// setup
+	dir := t.TempDir()
+	storage := newTestFileStorage(t, dir, FileStorageOptions[string, string]{})
+	storage.Put([]CacheEntry[string, string]{storageEntry("a", "1")})
+	storage.Close()
+	segment := filepath.Join(dir, "segment-00000001.log")
+	file, _ := os.OpenFile(segment, os.O_APPEND|os.O_WRONLY, 0o644)
+	file.Write([]byte{0, 0, 1, 0, 1, 2})
+	file.Close()
+
+	// execute
+	reopened := newTestFileStorage(t, dir, FileStorageOptions[string, string]{})
+	defer reopened.Close()
+	_, found, _ := reopened.Get("a")
+	putErr := reopened.Put([]CacheEntry[string, string]{storageEntry("b", "2")})
+	_, bFound, _ := reopened.Get("b")
+
+	// verify
+	if !found || !bFound || putErr != nil {
+		t.Errorf("Expected entries before and after the partly written record to be readable")
+	}
+}
+
+func TestFileStorageCutsOffCorruptRecords(t *testing.T) {
+	for name, record := range map[string][]byte{
+		// a length of 4GB that would be allocated before the checksum is checked
+		"length beyond the end of the file": {0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 1, 2},
+		// a payload with a valid checksum that is not a gob record
+		"payload that can not be decoded": {0, 0, 0, 2, 0xff, 0xff, 0, 0, 0xff, 0xff},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// setup
+			dir := t.TempDir()
+			storage := newTestFileStorage(t, dir, FileStorageOptions[string, string]{})
+			storage.Put([]CacheEntry[string, string]{storageEntry("a", "1")})
+			storage.Close()
+			segment := filepath.Join(dir, "segment-00000001.log")
+			file, _ := os.OpenFile(segment, os.O_APPEND|os.O_WRONLY, 0o644)
+			file.Write(record)
+			file.Close()
+
+			// execute
+			reopened, err := NewFileStorage[string, string](dir, FileStorageOptions[string, string]{})
+			if err != nil {
+				t.Fatalf("Expected storage to open, got %v", err)
+			}
+			defer reopened.Close()
+			_, found, _ := reopened.Get("a")
+			putErr := reopened.Put([]CacheEntry[string, string]{storageEntry("b", "2")})
+			_, bFound, _ := reopened.Get("b")
+
+			// verify
+			if !found || !bFound || putErr != nil {
+				t.Errorf("Expected entries before and after the corrupt record to be readable")
+			}
+		})
+	}
+}
+
+func TestEvictedEntriesAreLoadedFromStorageBeforeTheLoader(t *testing.T) {
+	// setup
+	storage := newTestFileStorage(t, t.TempDir(), FileStorageOptions[string, string]{})
+	defer storage.Close()
+	storageHits := 0
+	loads := 0
+	tieredCache := NewCacheBuilder[string, string]().
+		SetMaxSize(2).
+		SetEvictionPercent(50).
+		SetStorage(storage).
+		SetHooks(CacheHooks[string]{
+			OnStorageHit: func(k string) { storageHits++ },
+		}).
+		Build(func(k string) (string, error) {
+			loads++
+			return "loaded " + k, nil
+		})
+	tieredCache.Get("a")
+	tieredCache.Get("b")
+	tieredCache.Get("c")
+
+	// execute
+	value, exists := tieredCache.Get("a")
+
+	// verify
+	if !exists || value != "loaded a" {
+		t.Errorf("Expected 'a' to be 'loaded a'")
+	}
+	if loads != 3 {
+		t.Errorf("Expected loader to be called 3 times, got %d", loads)
+	}
+	if storageHits != 1 {
+		t.Errorf("Expected 1 storage hit, got %d", storageHits)
+	}
+}
+
+func TestRemovedEntriesAreDeletedFromStorage(t *testing.T) {
+	// setup
+	storage := newTestFileStorage(t, t.TempDir(), FileStorageOptions[string, string]{})
+	defer storage.Close()
+	storage.Put([]CacheEntry[string, string]{storageEntry("a", "stored")})
+	tieredCache := NewCacheBuilder[string, string]().
+		SetStorage(storage).
+		Build(func(k string) (string, error) {
+			return "loaded", nil
+		})
+
+	// execute
+	tieredCache.Remove("a")
+	value, _ := tieredCache.Get("a")
+
+	// verify
+	if value != "loaded" {
+		t.Errorf("Expected removed entry to be loaded, got '%s'", value)
+	}
+}
@@ -0,0 +1,228 @@
+// Package grpccache caches the responses of unary gRPC calls in a cache.Cache with a client interceptor.
+//
+// Responses are cached by the full method name and the deterministically marshaled request, for the methods configured with a TTL.
+// Errors are not cached, and calls can bypass the cache with the Bypass call option or the BypassMetadataKey metadata.
+//
+// Calls are made through the loading path of the cache, so concurrent calls with the same request share one call made with the context
+// and the call options of the first one, and its result or error, including the error of a call whose context was cancelled.
+// A response past its TTL stays in the cache until the request is made again or it is evicted by the max size of the cache.
+//
+// Outgoing metadata is not part of the key unless it is listed in Options.KeyMetadata, so a response loaded with the credentials of one caller
+// is served to every caller making the same request. List the metadata carrying credentials, like "authorization", when callers must not share responses.
+// Calls with per-RPC credentials call options bypass the cache, the credentials of the connection are shared by all its callers anyway.
+package grpccache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// BypassMetadataKey is the outgoing metadata key of calls that bypass the cache, any value bypasses the cache
+const BypassMetadataKey = "x-cache-bypass"
+
+// CachedResponse is a marshaled response stored in the cache.
+type CachedResponse struct {
+	Body []byte
+	// StoredAt is when the response was received
+	StoredAt time.Time
+	// TTL is how long after StoredAt the response is used
+	TTL time.Duration
+}
+
+func (c *CachedResponse) fresh(now time.Time) bool {
+	return now.Before(c.StoredAt.Add(c.TTL))
+}
+
+// MethodConfig configures the caching of one method
+type MethodConfig struct {
+	// TTL is how long responses of the method are cached, responses of methods with a TTL of 0 are not cached
+	TTL time.Duration
+}
+
+// Options configures an Interceptor.
+type Options struct {
+	// Methods are the full method names, for example "/grpc.health.v1.Health/Check", with their config. Calls of other methods are not cached
+	Methods map[string]MethodConfig
+	// Clock is used for the TTL of responses. Defaults to cache.LocalClock
+	Clock cache.Clock
+	// OnError is called when a request or a response could not be marshaled or a cached response could not be unmarshaled, the call is made without the cache
+	OnError func(method string, err error)
+	// KeyMetadata are the outgoing metadata keys whose values are part of the cache key, so calls with different values do not share responses.
+	// Defaults to none, responses are shared by all calls with the same request
+	KeyMetadata []string
+}
+
+// Interceptor caches the responses of unary calls of the configured methods.
+type Interceptor struct {
+	cache   cache.Cache[string, *CachedResponse]
+	loader  *cache.CallLoader[string, *CachedResponse]
+	options Options
+}
+
+// NewInterceptor creates an interceptor caching responses in a cache built with the configuration of the builder.
+// The cache expiration should be 0 or longer than the TTL of the methods. Close the interceptor when it is no longer used.
+func NewInterceptor(builder cache.CacheBuilder[string, *CachedResponse], options Options) *Interceptor {
+	if options.Clock == nil {
+		options.Clock = cache.LocalClock{}
+	}
+	loader := cache.NewCallLoader[string, *CachedResponse](nil)
+	return &Interceptor{
+		cache:   builder.Build(loader.Load),
+		loader:  loader,
+		options: options,
+	}
+}
+
+// marshalError - a response that could not be marshaled, the call is made again without the cache
+type marshalError struct {
+	err error
+}
+
+func (m marshalError) Error() string {
+	return m.err.Error()
+}
+
+// bypassOption - a call option that only marks the call for the interceptor
+type bypassOption struct {
+	grpc.EmptyCallOption
+}
+
+// Bypass returns a call option making the call without reading or writing the cache.
+func Bypass() grpc.CallOption {
+	return bypassOption{}
+}
+
+// Unary returns the interceptor, pass it to grpc.WithUnaryInterceptor or grpc.WithChainUnaryInterceptor.
+// Calls with header or trailer call options bypass the cache, a response served from the cache has no header and trailer to fill them with.
+// Calls with per-RPC credentials call options bypass the cache as well, see the package doc.
+func (i *Interceptor) Unary() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		config, configured := i.options.Methods[method]
+		if !configured || config.TTL <= 0 || bypassed(ctx, opts) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		replyMessage, isMessage := reply.(proto.Message)
+		key, err := i.key(ctx, method, req)
+		if err != nil || !isMessage {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		// the call fills a reply of its own, the reply of the caller is only filled from the cached response
+		load := func() (*CachedResponse, error) {
+			loaded := replyMessage.ProtoReflect().New().Interface()
+			if err := invoker(ctx, method, req, loaded, cc, opts...); err != nil {
+				return nil, err
+			}
+			body, err := proto.Marshal(loaded)
+			if err != nil {
+				return nil, marshalError{err: err}
+			}
+			return &CachedResponse{Body: body, StoredAt: i.options.Clock.Now(), TTL: config.TTL}, nil
+		}
+		cached, err := i.loader.Get(i.cache, key, load)
+		if err == nil && !cached.fresh(i.options.Clock.Now()) {
+			i.removeStale(key, cached)
+			cached, err = i.loader.Get(i.cache, key, load)
+		}
+		if err == nil {
+			err := proto.Unmarshal(cached.Body, replyMessage)
+			if err == nil {
+				return nil
+			}
+			i.failed(method, err)
+			// the reply may be partly filled by the cached response
+			proto.Reset(replyMessage)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		var marshalErr marshalError
+		if errors.As(err, &marshalErr) {
+			i.failed(method, marshalErr.err)
+		} else if !errors.Is(err, cache.ErrNotLoaded) {
+			return err
+		}
+		// the cache could not be used, for example because it is closed
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// Invalidate removes the cached response of the method for the request, with the values of the KeyMetadata in the outgoing metadata of ctx.
+// Returns true if there was a response.
+func (i *Interceptor) Invalidate(ctx context.Context, method string, req proto.Message) bool {
+	key, err := i.key(ctx, method, req)
+	if err != nil {
+		return false
+	}
+	return i.cache.Remove(key)
+}
+
+// InvalidateAll removes all cached responses.
+func (i *Interceptor) InvalidateAll() {
+	i.cache.InvalidateAll()
+}
+
+// Close releases the cache, see cache.Cache.Close.
+func (i *Interceptor) Close() error {
+	return i.cache.Close()
+}
+
+// removeStale removes the response once it is past its TTL, unless it was already replaced by a newer response
+func (i *Interceptor) removeStale(key string, stale *CachedResponse) {
+	i.cache.ComputeIfPresent(key, func(_ string, old *CachedResponse) (*CachedResponse, cache.Action) {
+		if old != stale {
+			return old, cache.Keep
+		}
+		return old, cache.Delete
+	})
+}
+
+// key - the method, the deterministically marshaled request and the values of the KeyMetadata, so equal requests have the same key
+func (i *Interceptor) key(ctx context.Context, method string, req any) (string, error) {
+	message, isMessage := req.(proto.Message)
+	if !isMessage {
+		return "", errors.New("grpccache: request is not a proto message")
+	}
+	marshaled, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		i.failed(method, err)
+		return "", err
+	}
+	builder := strings.Builder{}
+	builder.WriteString(method)
+	builder.WriteString("\x00")
+	builder.Write(marshaled)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for _, name := range i.options.KeyMetadata {
+		// values are written with their length, so the key of every combination of values is different
+		for _, value := range md.Get(name) {
+			fmt.Fprintf(&builder, "\x00%d:%s", len(value), value)
+		}
+		builder.WriteString("\x00")
+	}
+	return builder.String(), nil
+}
+
+func (i *Interceptor) failed(method string, err error) {
+	if i.options.OnError != nil {
+		i.options.OnError(method, err)
+	}
+}
+
+func bypassed(ctx context.Context, opts []grpc.CallOption) bool {
+	for _, opt := range opts {
+		switch opt.(type) {
+		case bypassOption, grpc.HeaderCallOption, grpc.TrailerCallOption, grpc.PerRPCCredsCallOption:
+			return true
+		}
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return len(md.Get(BypassMetadataKey)) > 0
+}
@@ -0,0 +1,277 @@
+package grpccache
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const checkMethod = "/grpc.health.v1.Health/Check"
+
+// healthServer - a health service on an in-memory listener counting the calls it receives, calls wait for release when it is set
+type healthServer struct {
+	health  *health.Server
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func newHealthClient(t *testing.T, options Options) (healthpb.HealthClient, *healthServer, *Interceptor) {
+	listener := bufconn.Listen(1 << 20)
+	server := &healthServer{health: health.NewServer()}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		server.calls.Add(1)
+		if server.release != nil {
+			<-server.release
+		}
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(grpcServer, server.health)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	interceptor := NewInterceptor(cache.NewCacheBuilder[string, *CachedResponse]().SetMaxSize(100), options)
+	t.Cleanup(func() {
+		interceptor.Close()
+	})
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(interceptor.Unary()),
+	)
+	if err != nil {
+		t.Fatalf("Expected client to be created, got %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return healthpb.NewHealthClient(conn), server, interceptor
+}
+
+func check(t *testing.T, client healthpb.HealthClient, ctx context.Context, service string, opts ...grpc.CallOption) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	response, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service}, opts...)
+	if err != nil {
+		t.Fatalf("Expected check to succeed, got %v", err)
+	}
+	return response.Status
+}
+
+func TestResponsesOfConfiguredMethodsAreCachedForTheirTTL(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	client, server, _ := newHealthClient(t, Options{
+		Methods: map[string]MethodConfig{checkMethod: {TTL: time.Minute}},
+		Clock:   clock,
+	})
+	server.health.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+	ctx := context.Background()
+
+	// execute
+	first := check(t, client, ctx, "users")
+	server.health.SetServingStatus("users", healthpb.HealthCheckResponse_NOT_SERVING)
+	cached := check(t, client, ctx, "users")
+	check(t, client, ctx, "")
+	clock.Advance(time.Minute)
+	expired := check(t, client, ctx, "users")
+
+	// verify
+	if first != healthpb.HealthCheckResponse_SERVING || cached != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected the cached SERVING status, got %s", cached)
+	}
+	if expired != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected the response to expire after the TTL, got %s", expired)
+	}
+	if server.calls.Load() != 3 {
+		t.Errorf("Expected 3 calls to the server, got %d", server.calls.Load())
+	}
+}
+
+func TestMethodsWithoutConfigAreNotCached(t *testing.T) {
+	// setup
+	client, server, _ := newHealthClient(t, Options{})
+
+	// execute
+	check(t, client, context.Background(), "")
+	check(t, client, context.Background(), "")
+
+	// verify
+	if server.calls.Load() != 2 {
+		t.Errorf("Expected every call to reach the server, got %d", server.calls.Load())
+	}
+}
+
+func TestCallsCanBypassTheCache(t *testing.T) {
+	// setup
+	client, server, _ := newHealthClient(t, Options{
+		Methods: map[string]MethodConfig{checkMethod: {TTL: time.Minute}},
+	})
+	check(t, client, context.Background(), "")
+
+	// execute
+	check(t, client, context.Background(), "", Bypass())
+	check(t, client, metadata.AppendToOutgoingContext(context.Background(), BypassMetadataKey, "true"), "")
+	check(t, client, context.Background(), "")
+
+	// verify
+	if server.calls.Load() != 3 {
+		t.Errorf("Expected the bypassing calls to reach the server, got %d calls", server.calls.Load())
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	// setup
+	client, server, _ := newHealthClient(t, Options{
+		Methods: map[string]MethodConfig{checkMethod: {TTL: time.Minute}},
+	})
+
+	// execute
+	_, firstErr := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	_, secondErr := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+
+	// verify
+	if firstErr == nil || secondErr == nil {
+		t.Errorf("Expected checks of an unknown service to fail")
+	}
+	if server.calls.Load() != 2 {
+		t.Errorf("Expected the error not to be cached, got %d calls", server.calls.Load())
+	}
+}
+
+func TestInvalidateRemovesTheCachedResponse(t *testing.T) {
+	// setup
+	client, server, interceptor := newHealthClient(t, Options{
+		Methods: map[string]MethodConfig{checkMethod: {TTL: time.Minute}},
+	})
+	server.health.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+	check(t, client, context.Background(), "users")
+	server.health.SetServingStatus("users", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	// execute
+	invalidated := interceptor.Invalidate(context.Background(), checkMethod, &healthpb.HealthCheckRequest{Service: "users"})
+	status := check(t, client, context.Background(), "users")
+
+	// verify
+	if !invalidated || status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected the response to be loaded again after invalidate, got %s", status)
+	}
+}
+
+func TestConcurrentCallsShareOneCall(t *testing.T) {
+	// setup
+	client, server, _ := newHealthClient(t, Options{
+		Methods: map[string]MethodConfig{checkMethod: {TTL: time.Minute}},
+	})
+	server.health.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+	server.release = make(chan struct{})
+
+	// execute
+	wait := &sync.WaitGroup{}
+	statuses := make([]healthpb.HealthCheckResponse_ServingStatus, 10)
+	for i := range statuses {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			statuses[i] = check(t, client, context.Background(), "users")
+		}(i)
+	}
+	for server.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(time.Millisecond * 50)
+	close(server.release)
+	wait.Wait()
+
+	// verify
+	if server.calls.Load() != 1 {
+		t.Errorf("Expected concurrent calls to share one call, got %d calls", server.calls.Load())
+	}
+	for i, status := range statuses {
+		if status != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Expected call %d to get SERVING, got %s", i, status)
+		}
+	}
+}
+
+func TestCallsWithHeaderOrTrailerOptionsBypassTheCache(t *testing.T) {
+	// setup
+	client, server, _ := newHealthClient(t, Options{
+		Methods: map[string]MethodConfig{checkMethod: {TTL: time.Minute}},
+	})
+	check(t, client, context.Background(), "")
+	header := metadata.MD{}
+	trailer := metadata.MD{}
+
+	// execute
+	check(t, client, context.Background(), "", grpc.Header(&header))
+	check(t, client, context.Background(), "", grpc.Trailer(&trailer))
+
+	// verify
+	if server.calls.Load() != 3 {
+		t.Errorf("Expected the calls with header and trailer options to reach the server, got %d calls", server.calls.Load())
+	}
+	if len(header.Get("content-type")) == 0 {
+		t.Errorf("Expected the header to be filled, got %v", header)
+	}
+}
+
+func TestKeyMetadataSeparatesTheResponsesOfCallers(t *testing.T) {
+	// setup
+	client, server, interceptor := newHealthClient(t, Options{
+		Methods:     map[string]MethodConfig{checkMethod: {TTL: time.Minute}},
+		KeyMetadata: []string{"authorization"},
+	})
+	sam := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer sam", "x-request-id", "1")
+	samAgain := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer sam", "x-request-id", "2")
+	alex := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer alex")
+
+	// execute
+	check(t, client, sam, "")
+	check(t, client, samAgain, "")
+	check(t, client, alex, "")
+	invalidated := interceptor.Invalidate(alex, checkMethod, &healthpb.HealthCheckRequest{})
+	check(t, client, sam, "")
+
+	// verify
+	if server.calls.Load() != 2 {
+		t.Errorf("Expected a call per authorization, got %d calls", server.calls.Load())
+	}
+	if !invalidated {
+		t.Errorf("Expected the response of alex to be invalidated")
+	}
+}
+
+func TestCachedResponsesThatCanNotBeUnmarshaledAreLoadedAgain(t *testing.T) {
+	// setup
+	errs := []error{}
+	client, server, interceptor := newHealthClient(t, Options{
+		Methods: map[string]MethodConfig{checkMethod: {TTL: time.Minute}},
+		OnError: func(method string, err error) { errs = append(errs, err) },
+	})
+	server.health.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+	key, _ := interceptor.key(context.Background(), checkMethod, &healthpb.HealthCheckRequest{Service: "users"})
+	interceptor.cache.Put(key, &CachedResponse{Body: []byte{0xff}, StoredAt: time.Now(), TTL: time.Minute})
+
+	// execute
+	status := check(t, client, context.Background(), "users")
+
+	// verify
+	if status != healthpb.HealthCheckResponse_SERVING || server.calls.Load() != 1 {
+		t.Errorf("Expected the call to be made, got %s after %d calls", status, server.calls.Load())
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected the unmarshal error to be reported, got %v", errs)
+	}
+}
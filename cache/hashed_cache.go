@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// errUnknownHashedKey is returned by the loader of a hashed cache when the key of a hash is no longer known, the next Get of the key loads it again
+var errUnknownHashedKey = errors.New("cache: key of hash is unknown")
+
+const minRememberedKeys = 64
+
+// KeyFunc serializes a key to bytes. Two keys have to be serialized to the same bytes exactly when they are equal.
+type KeyFunc[K any] func(k K) []byte
+
+// DefaultKeyFunc serializes a key with its Go syntax representation, map entries are sorted so equal maps are serialized the same way.
+// Pointers nested in the key are serialized as their address, so they are only equal if they point to the same value.
+func DefaultKeyFunc[K any](k K) []byte {
+	return []byte(fmt.Sprintf("%#v", k))
+}
+
+// Hasher maps keys of any type, including keys that are not comparable, to a comparable fingerprint. Keys with the same fingerprint are told apart with Equal.
+type Hasher[K any] interface {
+	Hash(k K) uint64
+	Equal(a K, b K) bool
+}
+
+// keyFuncHasher - hashes the serialized key with fnv and compares the serialized keys
+type keyFuncHasher[K any] struct {
+	keyFunc KeyFunc[K]
+}
+
+// NewKeyFuncHasher creates a Hasher using the serialized keys of the KeyFunc, if keyFunc is nil DefaultKeyFunc is used.
+func NewKeyFuncHasher[K any](keyFunc KeyFunc[K]) Hasher[K] {
+	if keyFunc == nil {
+		keyFunc = DefaultKeyFunc[K]
+	}
+	return keyFuncHasher[K]{keyFunc: keyFunc}
+}
+
+func (h keyFuncHasher[K]) Hash(k K) uint64 {
+	hash := fnv.New64a()
+	hash.Write(h.keyFunc(k))
+	return hash.Sum64()
+}
+
+func (h keyFuncHasher[K]) Equal(a K, b K) bool {
+	return bytes.Equal(h.keyFunc(a), h.keyFunc(b))
+}
+
+// HashedEntry is the value stored for a hash in the cache behind a HashedCache, the key is kept to tell apart keys with the same hash.
+type HashedEntry[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+// HashedCache is a cache for keys that are not comparable, for example structs with slices or maps. Keys are stored by their hash in a Cache,
+// keys with the same hash replace each other in the cache but a value is never returned for a different key.
+type HashedCache[K any, V any] interface {
+	// Get returns the value associated with the key k, loading it if needed. The second return value will be false if the key was not able to be loaded.
+	Get(k K) (V, bool)
+	// Put inserts a value into the cache associated with the key k.
+	Put(k K, v V)
+	// Remove removes the key k from the cache. The return value will be true if the key was removed.
+	Remove(k K) bool
+	// GetIfPresent returns the value associated with the key k without loading it.
+	GetIfPresent(k K) (V, bool)
+	// Contains reports whether there is an entry for the key k that has not expired.
+	Contains(k K) bool
+	// Len returns the number of entries in the cache that have not expired.
+	Len() int
+	// InvalidateAll removes every entry from the cache.
+	InvalidateAll()
+	// Close closes the cache, see Cache.Close.
+	Close() error
+	// Shutdown closes the cache, see Cache.Shutdown.
+	Shutdown(ctx context.Context) error
+}
+
+// hashedCache - a cache keyed by the hash of its keys
+type hashedCache[K any, V any] struct {
+	cache  Cache[uint64, HashedEntry[K, V]]
+	hasher Hasher[K]
+	loader func(k K) (V, error)
+
+	// keysLock guards keys, the last key seen for every hash so the loader of the cache can load the key of a hash,
+	// and inUse, the number of Gets and Puts in progress for every hash so their keys are not pruned before they are loaded
+	keysLock *sync.Mutex
+	keys     map[uint64]K
+	inUse    map[uint64]int
+	// pruneAt is the number of keys at which keys of hashes that are no longer in the cache are dropped
+	pruneAt int
+}
+
+// BuildHashed builds a cache for keys that are not comparable with the configuration of the builder and the loader. The builder configures the Cache keyed by the hashes,
+// so hooks are called with hashes. If hasher is nil a KeyFunc hasher with DefaultKeyFunc is used.
+func BuildHashed[K any, V any](builder CacheBuilder[uint64, HashedEntry[K, V]], hasher Hasher[K], loader func(k K) (V, error)) HashedCache[K, V] {
+	if hasher == nil {
+		hasher = NewKeyFuncHasher[K](nil)
+	}
+	hashed := &hashedCache[K, V]{
+		hasher:   hasher,
+		loader:   loader,
+		keysLock: &sync.Mutex{},
+		keys:     make(map[uint64]K),
+		inUse:    make(map[uint64]int),
+		pruneAt:  minRememberedKeys,
+	}
+	hashed.cache = builder.Build(hashed.loadHash)
+	return hashed
+}
+
+func (h *hashedCache[K, V]) Get(k K) (V, bool) {
+	hash := h.hasher.Hash(k)
+	h.remember(hash, k)
+	defer h.release(hash)
+	entry, exists := h.cache.Get(hash)
+	if exists && !h.hasher.Equal(entry.Key, k) {
+		entry, exists = h.replace(hash, k)
+	}
+	if !exists {
+		var defaultValue V
+		return defaultValue, false
+	}
+	return entry.Value, true
+}
+
+func (h *hashedCache[K, V]) Put(k K, v V) {
+	hash := h.hasher.Hash(k)
+	h.remember(hash, k)
+	defer h.release(hash)
+	h.cache.Put(hash, HashedEntry[K, V]{Key: k, Value: v})
+}
+
+func (h *hashedCache[K, V]) Remove(k K) bool {
+	hash := h.hasher.Hash(k)
+	removed := false
+	h.cache.ComputeIfPresent(hash, func(_ uint64, old HashedEntry[K, V]) (HashedEntry[K, V], Action) {
+		if !h.hasher.Equal(old.Key, k) {
+			return old, Keep
+		}
+		removed = true
+		return old, Delete
+	})
+	if removed {
+		h.forget(hash)
+	}
+	return removed
+}
+
+func (h *hashedCache[K, V]) GetIfPresent(k K) (V, bool) {
+	entry, exists := h.cache.GetIfPresent(h.hasher.Hash(k))
+	if !exists || !h.hasher.Equal(entry.Key, k) {
+		var defaultValue V
+		return defaultValue, false
+	}
+	return entry.Value, true
+}
+
+func (h *hashedCache[K, V]) Contains(k K) bool {
+	_, exists := h.GetIfPresent(k)
+	return exists
+}
+
+func (h *hashedCache[K, V]) Len() int {
+	return h.cache.Len()
+}
+
+func (h *hashedCache[K, V]) InvalidateAll() {
+	h.cache.InvalidateAll()
+	h.keysLock.Lock()
+	defer h.keysLock.Unlock()
+	for hash := range h.keys {
+		if h.inUse[hash] == 0 {
+			delete(h.keys, hash)
+		}
+	}
+}
+
+func (h *hashedCache[K, V]) Close() error {
+	return h.cache.Close()
+}
+
+func (h *hashedCache[K, V]) Shutdown(ctx context.Context) error {
+	return h.cache.Shutdown(ctx)
+}
+
+// loadHash is the loader of the cache keyed by hashes, it loads the last key seen for the hash
+func (h *hashedCache[K, V]) loadHash(hash uint64) (HashedEntry[K, V], error) {
+	h.keysLock.Lock()
+	k, known := h.keys[hash]
+	h.keysLock.Unlock()
+	if !known {
+		return HashedEntry[K, V]{}, errUnknownHashedKey
+	}
+
+	value, err := h.loader(k)
+	return HashedEntry[K, V]{Key: k, Value: value}, err
+}
+
+// replace loads the key k through the cache when the entry for its hash belongs to a different key, the loaded entry replaces the entry of the other key.
+// If a colliding key replaced the entry again in the meantime nothing is returned
+func (h *hashedCache[K, V]) replace(hash uint64, k K) (HashedEntry[K, V], bool) {
+	h.keysLock.Lock()
+	h.keys[hash] = k
+	h.keysLock.Unlock()
+
+	h.cache.ComputeIfPresent(hash, func(_ uint64, old HashedEntry[K, V]) (HashedEntry[K, V], Action) {
+		if h.hasher.Equal(old.Key, k) {
+			return old, Keep
+		}
+		return old, Delete
+	})
+	entry, exists := h.cache.Get(hash)
+	if !exists || !h.hasher.Equal(entry.Key, k) {
+		return HashedEntry[K, V]{}, false
+	}
+	return entry, true
+}
+
+// remember remembers the key of the hash until release is called, a remembered key is not pruned while it is in use
+func (h *hashedCache[K, V]) remember(hash uint64, k K) {
+	h.keysLock.Lock()
+	defer h.keysLock.Unlock()
+	h.keys[hash] = k
+	h.inUse[hash]++
+
+	// evicted entries are not removed from keys, so keys of hashes that are no longer in the cache are dropped whenever the number of keys doubled
+	if len(h.keys) > h.pruneAt {
+		for rememberedHash := range h.keys {
+			if h.inUse[rememberedHash] == 0 && !h.cache.Contains(rememberedHash) {
+				delete(h.keys, rememberedHash)
+			}
+		}
+		h.pruneAt = 2*len(h.keys) + minRememberedKeys
+	}
+}
+
+func (h *hashedCache[K, V]) release(hash uint64) {
+	h.keysLock.Lock()
+	defer h.keysLock.Unlock()
+	h.inUse[hash]--
+	if h.inUse[hash] == 0 {
+		delete(h.inUse, hash)
+	}
+}
+
+func (h *hashedCache[K, V]) forget(hash uint64) {
+	h.keysLock.Lock()
+	defer h.keysLock.Unlock()
+	if h.inUse[hash] == 0 {
+		delete(h.keys, hash)
+	}
+}
@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type queryKey struct {
+	Table   string
+	Columns []string
+	Filters map[string]string
+}
+
+// constantHasher - a hasher where all keys collide
+type constantHasher struct {
+}
+
+func (c constantHasher) Hash(k queryKey) uint64 {
+	return 1
+}
+
+func (c constantHasher) Equal(a queryKey, b queryKey) bool {
+	return NewKeyFuncHasher[queryKey](nil).Equal(a, b)
+}
+
+func buildHashedTestCache(builder CacheBuilder[uint64, HashedEntry[queryKey, string]], hasher Hasher[queryKey], loads *int) HashedCache[queryKey, string] {
+	return BuildHashed[queryKey, string](builder, hasher, func(k queryKey) (string, error) {
+		*loads++
+		return "rows of " + k.Table + " " + k.Filters["id"], nil
+	})
+}
+
+func TestHashedCacheAcceptsKeysThatAreNotComparable(t *testing.T) {
+	// setup
+	loads := 0
+	hashedCache := buildHashedTestCache(NewCacheBuilder[uint64, HashedEntry[queryKey, string]](), nil, &loads)
+	first := queryKey{Table: "users", Columns: []string{"name"}, Filters: map[string]string{"id": "1", "active": "true"}}
+	equal := queryKey{Table: "users", Columns: []string{"name"}, Filters: map[string]string{"active": "true", "id": "1"}}
+
+	// execute
+	hashedCache.Get(first)
+	value, exists := hashedCache.Get(equal)
+
+	// verify
+	if !exists || value != "rows of users 1" {
+		t.Errorf("Expected 'rows of users 1', got '%s'", value)
+	}
+	if loads != 1 {
+		t.Errorf("Expected equal keys to be loaded once, got %d loads", loads)
+	}
+}
+
+func TestHashedCacheNeverReturnsTheValueOfACollidingKey(t *testing.T) {
+	// setup
+	loads := 0
+	hashedCache := buildHashedTestCache(NewCacheBuilder[uint64, HashedEntry[queryKey, string]](), constantHasher{}, &loads)
+	first := queryKey{Table: "users", Filters: map[string]string{"id": "1"}}
+	second := queryKey{Table: "users", Filters: map[string]string{"id": "2"}}
+
+	// execute
+	firstValue, _ := hashedCache.Get(first)
+	secondValue, _ := hashedCache.Get(second)
+	_, firstPresent := hashedCache.GetIfPresent(first)
+	firstRemoved := hashedCache.Remove(first)
+
+	// verify
+	if firstValue != "rows of users 1" || secondValue != "rows of users 2" {
+		t.Errorf("Expected each key to get its own value, got '%s' and '%s'", firstValue, secondValue)
+	}
+	if firstPresent {
+		t.Errorf("Expected the first key to be replaced by the colliding key")
+	}
+	if firstRemoved || !hashedCache.Contains(second) {
+		t.Errorf("Expected removing the first key to keep the colliding key")
+	}
+}
+
+func TestHashedCacheReloadsExpiredKeys(t *testing.T) {
+	// setup
+	loads := 0
+	clock := NewFakeClock(time.Now())
+	builder := NewCacheBuilderWithFactory[uint64, HashedEntry[queryKey, string]](CacheTypeCacheFactory[uint64, HashedEntry[queryKey, string]]{
+		Clock: clock,
+	}).SetExpiration(time.Second)
+	hashedCache := buildHashedTestCache(builder, nil, &loads)
+	key := queryKey{Table: "users", Filters: map[string]string{"id": "1"}}
+	hashedCache.Get(key)
+
+	// execute
+	clock.Advance(time.Second * 2)
+	value, exists := hashedCache.Get(key)
+
+	// verify
+	if !exists || value != "rows of users 1" {
+		t.Errorf("Expected 'rows of users 1', got '%s'", value)
+	}
+	if loads != 2 {
+		t.Errorf("Expected expired key to be loaded again, got %d loads", loads)
+	}
+}
+
+func TestHashedCacheLoadsCollidingKeysThroughTheCache(t *testing.T) {
+	// setup
+	loads := 0
+	loadDurations := 0
+	builder := NewCacheBuilder[uint64, HashedEntry[queryKey, string]]().
+		SetHooks(CacheHooks[uint64]{
+			OnCacheLoadDuration: func(k uint64, d time.Duration) { loadDurations++ },
+		})
+	hashedCache := buildHashedTestCache(builder, constantHasher{}, &loads)
+
+	// execute
+	hashedCache.Get(queryKey{Table: "users", Filters: map[string]string{"id": "1"}})
+	value, exists := hashedCache.Get(queryKey{Table: "users", Filters: map[string]string{"id": "2"}})
+
+	// verify
+	if !exists || value != "rows of users 2" {
+		t.Errorf("Expected 'rows of users 2', got '%s'", value)
+	}
+	if loads != 2 || loadDurations != 2 {
+		t.Errorf("Expected both keys to be loaded by the cache, got %d loads and %d load hooks", loads, loadDurations)
+	}
+}
+
+func TestHashedCacheKeepsTheKeysOfLoadsInProgress(t *testing.T) {
+	// setup
+	hashedCache := BuildHashed[[]int, string](NewCacheBuilder[uint64, HashedEntry[[]int, string]]().SetMaxSize(10), nil, func(k []int) (string, error) {
+		time.Sleep(time.Microsecond * 100)
+		return fmt.Sprint(k), nil
+	})
+	defer hashedCache.Close()
+	failures := make(chan string, 1000)
+
+	// execute
+	wait := &sync.WaitGroup{}
+	for worker := 0; worker < 10; worker++ {
+		wait.Add(1)
+		go func(worker int) {
+			defer wait.Done()
+			for i := 0; i < 100; i++ {
+				key := []int{worker, i}
+				if value, exists := hashedCache.Get(key); !exists || value != fmt.Sprint(key) {
+					failures <- fmt.Sprint(key)
+				}
+			}
+		}(worker)
+	}
+	wait.Wait()
+	close(failures)
+
+	// verify
+	for key := range failures {
+		t.Errorf("Expected %s to be loaded while keys were pruned", key)
+	}
+}
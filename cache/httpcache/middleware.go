@@ -0,0 +1,270 @@
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+// Middleware caches the responses of handlers to GET requests. It is a shared cache, so responses marked private are not cached
+// and s-maxage is preferred over max-age. Responses to GET requests are buffered before they are written.
+// Stale responses with validators are revalidated by calling the handler with a conditional request, a handler answering 304 Not Modified keeps the cached body.
+type Middleware struct {
+	responses *responses
+}
+
+// NewMiddleware creates a middleware caching responses in a cache built with the configuration of the builder.
+// The cache expiration should be 0 or longer than the freshness of the responses. Close the middleware when it is no longer used.
+func NewMiddleware(builder cache.CacheBuilder[string, *CachedResponse], options Options) *Middleware {
+	return &Middleware{
+		responses: newResponses(builder, options, true),
+	}
+}
+
+// Handler returns a handler caching the responses of next, the handlers of a middleware share its cache.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return &cachingHandler{
+		next:      next,
+		responses: m.responses,
+	}
+}
+
+// Close releases the cache, see cache.Cache.Close.
+func (m *Middleware) Close() error {
+	return m.responses.cache.Close()
+}
+
+type cachingHandler struct {
+	next      http.Handler
+	responses *responses
+}
+
+func (h *cachingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := requestKey(http.MethodGet, r.Host+r.URL.RequestURI())
+	if r.Method != http.MethodGet {
+		if !isUnsafeMethod(r.Method) {
+			h.next.ServeHTTP(w, r)
+			return
+		}
+		status := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h.next.ServeHTTP(status, r)
+		if status.status < http.StatusBadRequest {
+			h.responses.remove(key)
+		}
+		return
+	}
+
+	directives := parseCacheControl(r.Header)
+	if directives.has("no-store") {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	variantKey, cached := h.responses.lookup(r, key)
+	now := h.responses.now()
+	if cached != nil && !directives.has("no-cache") {
+		if cached.fresh(now) {
+			writeCached(w, r, cached, now)
+			return
+		}
+		if cached.servableWhileRevalidating(now) {
+			background := r.Clone(context.WithoutCancel(r.Context()))
+			h.responses.revalidateInBackground(variantKey, func() error {
+				h.serve(background, key, cached)
+				return nil
+			})
+			writeCached(w, r, cached, now)
+			return
+		}
+	}
+	if canRevalidate(r, cached) {
+		h.revalidate(w, r, key, variantKey, cached)
+		return
+	}
+
+	recorded, revalidated := h.serve(r, key, cached)
+	h.write(w, r, recorded, revalidated)
+}
+
+// served - the response of the handler to a request, see serve
+type served struct {
+	recorded    *responseRecorder
+	revalidated *CachedResponse
+}
+
+// revalidate calls the handler with a conditional request for the stale response, or waits for the revalidation of a concurrent request for the variant
+func (h *cachingHandler) revalidate(w http.ResponseWriter, r *http.Request, key string, variantKey string, stale *CachedResponse) {
+	// own is set when the revalidation of this request was made, it can run on the goroutine of another request
+	own := atomic.Pointer[served]{}
+	revalidated, err := h.responses.revalidate(variantKey, stale, func() (*CachedResponse, error) {
+		recorded, revalidated := h.serve(r, key, stale)
+		own.Store(&served{recorded: recorded, revalidated: revalidated})
+		return h.responses.stored(variantKey, stale), nil
+	})
+	if response := own.Load(); response != nil {
+		h.write(w, r, response.recorded, response.revalidated)
+		return
+	}
+	if err != nil {
+		// the revalidation was not shared, the handler is called for this request
+		recorded, revalidated := h.serve(r, key, stale)
+		h.write(w, r, recorded, revalidated)
+		return
+	}
+	writeCached(w, r, revalidated, h.responses.now())
+}
+
+// write writes the revalidated response if there is one and the recorded response otherwise
+func (h *cachingHandler) write(w http.ResponseWriter, r *http.Request, recorded *responseRecorder, revalidated *CachedResponse) {
+	if revalidated != nil {
+		writeCached(w, r, revalidated, h.responses.now())
+		return
+	}
+	recorded.writeTo(w)
+}
+
+// serve calls the handler, with a conditional request if the cached response can be revalidated, and caches the response.
+// Returns the cached response if the handler answered 304 Not Modified and the recorded response otherwise.
+func (h *cachingHandler) serve(r *http.Request, key string, cached *CachedResponse) (*responseRecorder, *CachedResponse) {
+	incoming := r
+	conditional := canRevalidate(r, cached)
+	if conditional {
+		incoming = r.Clone(r.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			incoming.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+			incoming.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	recorder := newResponseRecorder()
+	h.next.ServeHTTP(recorder, incoming)
+	now := h.responses.now()
+
+	if conditional && recorder.status == http.StatusNotModified {
+		revalidated := cached.revalidated(recorder.header, now)
+		if revalidated.updateFreshness(r, true, h.responses.options.DefaultTTL) {
+			h.responses.store(r, key, revalidated)
+		} else {
+			h.responses.remove(key)
+		}
+		return nil, revalidated
+	}
+
+	if int64(recorder.body.Len()) > h.responses.options.MaxBodySize {
+		return recorder, nil
+	}
+	if toCache, cacheable := newCachedResponse(r, recorder.status, recorder.header, recorder.body.Bytes(), now, true, h.responses.options.DefaultTTL); cacheable {
+		h.responses.store(r, key, toCache)
+	} else if cached != nil {
+		h.responses.remove(key)
+	}
+	return recorder, nil
+}
+
+// writeCached writes the cached response, or 304 Not Modified if the request validators match it
+func writeCached(w http.ResponseWriter, r *http.Request, cached *CachedResponse, now time.Time) {
+	header := w.Header()
+	for name, values := range cached.header(now) {
+		header[name] = values
+	}
+	if notModified(r, cached) {
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(cached.StatusCode)
+	w.Write(cached.Body)
+}
+
+func notModified(r *http.Request, cached *CachedResponse) bool {
+	if cached.StatusCode != http.StatusOK {
+		return false
+	}
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		etag := cached.Header.Get("ETag")
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || (etag != "" && strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/")) {
+				return true
+			}
+		}
+		return false
+	}
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(cached.Header.Get("Last-Modified"))
+	return err == nil && !lastModified.After(ifModifiedSince)
+}
+
+// responseRecorder - buffers the response of the handler
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        *bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{
+		header: http.Header{},
+		status: http.StatusOK,
+		body:   &bytes.Buffer{},
+	}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = status
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+func (r *responseRecorder) writeTo(w http.ResponseWriter) {
+	header := w.Header()
+	for name, values := range r.header {
+		header[name] = values
+	}
+	w.WriteHeader(r.status)
+	w.Write(r.body.Bytes())
+}
+
+// statusWriter - records the status written to the response writer
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusWriter) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.wroteHeader = true
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusWriter) Write(p []byte) (int, error) {
+	if !s.wroteHeader {
+		s.WriteHeader(http.StatusOK)
+	}
+	return s.ResponseWriter.Write(p)
+}
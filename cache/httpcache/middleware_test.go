@@ -0,0 +1,217 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+// newCachedHandler returns the handler wrapped by the middleware and the number of calls of the handler
+func newCachedHandler(t *testing.T, clock cache.Clock, handler http.HandlerFunc) (http.Handler, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	middleware := NewMiddleware(newResponseCacheBuilder(), Options{Clock: clock})
+	t.Cleanup(func() {
+		middleware.Close()
+	})
+	return middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	})), calls
+}
+
+func serve(handler http.Handler, method string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/users", nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestMiddlewareServesCachedResponses(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	handler, calls := newCachedHandler(t, clock, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("users"))
+	})
+
+	// execute
+	first := serve(handler, http.MethodGet)
+	clock.Advance(time.Second * 5)
+	second := serve(handler, http.MethodGet)
+
+	// verify
+	if first.Body.String() != "users" || second.Body.String() != "users" {
+		t.Errorf("Expected both responses to be 'users'")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected the handler to be called once, got %d", calls.Load())
+	}
+	if second.Header().Get("Content-Type") != "text/plain" || second.Header().Get("Age") != "5" {
+		t.Errorf("Expected the cached headers with an age of 5, got %v", second.Header())
+	}
+}
+
+func TestMiddlewareDoesNotCachePrivateResponses(t *testing.T) {
+	// setup
+	handler, calls := newCachedHandler(t, cache.NewFakeClock(time.Unix(1000, 0)), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "private, max-age=60")
+		w.Write([]byte("my account"))
+	})
+
+	// execute
+	serve(handler, http.MethodGet)
+	serve(handler, http.MethodGet)
+
+	// verify
+	if calls.Load() != 2 {
+		t.Errorf("Expected private responses not to be cached, got %d calls", calls.Load())
+	}
+}
+
+func TestMiddlewarePrefersSharedMaxAge(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	handler, calls := newCachedHandler(t, clock, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10, s-maxage=60")
+		w.Write([]byte("users"))
+	})
+	serve(handler, http.MethodGet)
+
+	// execute
+	clock.Advance(time.Second * 30)
+	serve(handler, http.MethodGet)
+
+	// verify
+	if calls.Load() != 1 {
+		t.Errorf("Expected s-maxage to be used, got %d calls", calls.Load())
+	}
+}
+
+func TestMiddlewareAnswersMatchingClientValidatorsWithNotModified(t *testing.T) {
+	// setup
+	handler, _ := newCachedHandler(t, cache.NewFakeClock(time.Unix(1000, 0)), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("users"))
+	})
+	serve(handler, http.MethodGet)
+
+	// execute
+	matching := serve(handler, http.MethodGet, "If-None-Match", `W/"v1"`)
+	other := serve(handler, http.MethodGet, "If-None-Match", `"v0"`)
+
+	// verify
+	if matching.Code != http.StatusNotModified || matching.Body.Len() != 0 {
+		t.Errorf("Expected 304 Not Modified, got %d", matching.Code)
+	}
+	if other.Code != http.StatusOK || other.Body.String() != "users" {
+		t.Errorf("Expected the cached response, got %d", other.Code)
+	}
+}
+
+func TestMiddlewareRevalidatesStaleResponsesWithTheHandler(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	lastModified := time.Unix(500, 0).UTC()
+	handler, calls := newCachedHandler(t, clock, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("users"))
+	})
+	serve(handler, http.MethodGet)
+	clock.Advance(time.Second * 11)
+
+	// execute
+	revalidated := serve(handler, http.MethodGet)
+	fresh := serve(handler, http.MethodGet)
+
+	// verify
+	if revalidated.Code != http.StatusOK || revalidated.Body.String() != "users" {
+		t.Errorf("Expected the cached body after revalidation, got %d '%s'", revalidated.Code, revalidated.Body.String())
+	}
+	if fresh.Body.String() != "users" || calls.Load() != 2 {
+		t.Errorf("Expected the revalidated response to be fresh, got %d calls", calls.Load())
+	}
+}
+
+func TestMiddlewareRemovesCachedResponseAfterUnsafeRequest(t *testing.T) {
+	// setup
+	handler, calls := newCachedHandler(t, cache.NewFakeClock(time.Unix(1000, 0)), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(r.Method))
+	})
+	serve(handler, http.MethodGet)
+
+	// execute
+	deleted := serve(handler, http.MethodDelete)
+	serve(handler, http.MethodGet)
+
+	// verify
+	if deleted.Body.String() != http.MethodDelete {
+		t.Errorf("Expected the delete to reach the handler")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected the get to call the handler again, got %d calls", calls.Load())
+	}
+}
+
+func TestMiddlewareSharesTheRevalidationOfAStaleResponse(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	release := make(chan struct{})
+	conditional := atomic.Int32{}
+	handler, calls := newCachedHandler(t, clock, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			<-release
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("users"))
+	})
+	serve(handler, http.MethodGet)
+	clock.Advance(time.Second * 11)
+
+	// execute
+	wait := &sync.WaitGroup{}
+	responses := make([]*httptest.ResponseRecorder, 10)
+	for i := range responses {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			responses[i] = serve(handler, http.MethodGet)
+		}(i)
+	}
+	for conditional.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wait.Wait()
+
+	// verify
+	if calls.Load() != 2 {
+		t.Errorf("Expected one conditional call of the handler, got %d calls", calls.Load())
+	}
+	for i, response := range responses {
+		if response.Code != http.StatusOK || response.Body.String() != "users" {
+			t.Errorf("Expected response %d to be the cached 'users', got %d '%s'", i, response.Code, response.Body.String())
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package httpcache caches HTTP responses in a cache.Cache, with a client Transport and a server Middleware.
+//
+// Responses are cached following their Cache-Control, Expires and Vary headers. Stale responses with an ETag or Last-Modified header
+// are revalidated with a conditional request, and responses with stale-while-revalidate are served stale while they are revalidated in the background.
+//
+// Revalidations are loaded through the cache with a cache.CallLoader, so concurrent requests for a stale response share one conditional request,
+// made with the headers and the context of the first one. The freshness of a response comes from its own headers, so the cache is built as a Blocking cache
+// and never refreshes responses in the background by itself. Requests for responses that are not cached are not shared.
+package httpcache
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XFromCache is set on responses served from the cache
+const XFromCache = "X-From-Cache"
+
+// CachedResponse is a response stored in the cache.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// StoredAt is when the response was received or last revalidated
+	StoredAt time.Time
+	// TTL is how long after StoredAt the response is fresh, a response with a TTL of 0 is revalidated every time it is used
+	TTL time.Duration
+	// StaleWhileRevalidate is how long after the response turned stale it is still served while it is revalidated in the background
+	StaleWhileRevalidate time.Duration
+	// Vary are the request headers the response varies by
+	Vary []string
+}
+
+func (c *CachedResponse) fresh(now time.Time) bool {
+	return now.Before(c.StoredAt.Add(c.TTL))
+}
+
+func (c *CachedResponse) servableWhileRevalidating(now time.Time) bool {
+	return c.StaleWhileRevalidate > 0 && now.Before(c.StoredAt.Add(c.TTL+c.StaleWhileRevalidate))
+}
+
+func (c *CachedResponse) hasValidators() bool {
+	return c.Header.Get("ETag") != "" || c.Header.Get("Last-Modified") != ""
+}
+
+// response returns a new response for the request with the cached status, header and body
+func (c *CachedResponse) response(req *http.Request, now time.Time) *http.Response {
+	header := c.header(now)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.StatusCode, http.StatusText(c.StatusCode)),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
+
+// header returns a copy of the cached header with the Age and XFromCache headers
+func (c *CachedResponse) header(now time.Time) http.Header {
+	header := c.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	age := now.Sub(c.StoredAt)
+	if age < 0 {
+		age = 0
+	}
+	header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	header.Set(XFromCache, "1")
+	return header
+}
+
+// revalidated returns a copy of the response updated with the header of a 304 Not Modified response
+func (c *CachedResponse) revalidated(notModified http.Header, now time.Time) *CachedResponse {
+	header := c.Header.Clone()
+	for name, values := range notModified {
+		if name == "Content-Length" {
+			continue
+		}
+		header[name] = values
+	}
+	return &CachedResponse{
+		StatusCode: c.StatusCode,
+		Header:     header,
+		Body:       c.Body,
+		StoredAt:   now,
+	}
+}
+
+// cacheControl - the directives of the Cache-Control headers, directives without a value map to ""
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	directives := cacheControl{}
+	for _, line := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return directives
+}
+
+func (c cacheControl) has(directive string) bool {
+	_, exists := c[directive]
+	return exists
+}
+
+// duration returns the value of a directive in seconds
+func (c cacheControl) duration(directive string) (time.Duration, bool) {
+	value, exists := c[directive]
+	if !exists {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// cacheableStatusCodes - the status codes that can be cached without explicit freshness, other status codes are not cached
+var cacheableStatusCodes = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// newCachedResponse returns the response to cache for the request, or false if the response can not be cached.
+// Shared caches do not store private responses and prefer s-maxage over max-age.
+func newCachedResponse(req *http.Request, statusCode int, header http.Header, body []byte, now time.Time, shared bool, defaultTTL time.Duration) (*CachedResponse, bool) {
+	if !cacheableStatusCodes[statusCode] {
+		return nil, false
+	}
+	cached := &CachedResponse{
+		StatusCode: statusCode,
+		Header:     header.Clone(),
+		Body:       body,
+		StoredAt:   now,
+	}
+	return cached, cached.updateFreshness(req, shared, defaultTTL)
+}
+
+// updateFreshness sets the TTL, StaleWhileRevalidate and Vary from the header, returns false if the response can not be cached
+func (c *CachedResponse) updateFreshness(req *http.Request, shared bool, defaultTTL time.Duration) bool {
+	directives := parseCacheControl(c.Header)
+	if directives.has("no-store") || (shared && directives.has("private")) {
+		return false
+	}
+	// shared caches only store responses to authorized requests that are explicitly allowed
+	if shared && req.Header.Get("Authorization") != "" && !directives.has("public") && !directives.has("s-maxage") && !directives.has("must-revalidate") {
+		return false
+	}
+
+	c.Vary = nil
+	for _, line := range c.Header.Values("Vary") {
+		for _, name := range strings.Split(line, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return false
+			}
+			if name != "" {
+				c.Vary = append(c.Vary, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	ttl, explicit := directives.duration("max-age")
+	if shared {
+		if sharedTTL, exists := directives.duration("s-maxage"); exists {
+			ttl, explicit = sharedTTL, true
+		}
+	}
+	if !explicit {
+		ttl, explicit = expiresTTL(c.Header, c.StoredAt)
+	}
+	if !explicit {
+		ttl = defaultTTL
+	}
+	if directives.has("no-cache") {
+		ttl = 0
+	}
+	// a response that is never fresh is only useful if it can be revalidated
+	if ttl <= 0 && !c.hasValidators() {
+		return false
+	}
+	c.TTL = ttl
+	c.StaleWhileRevalidate, _ = directives.duration("stale-while-revalidate")
+	if directives.has("must-revalidate") || (shared && directives.has("proxy-revalidate")) {
+		c.StaleWhileRevalidate = 0
+	}
+	return true
+}
+
+// expiresTTL returns the time from the Date header, or now if there is none, to the Expires header
+func expiresTTL(header http.Header, now time.Time) (time.Duration, bool) {
+	expiresHeader := header.Get("Expires")
+	if expiresHeader == "" {
+		return 0, false
+	}
+	expires, err := http.ParseTime(expiresHeader)
+	if err != nil {
+		// invalid dates like "0" mean the response is already expired
+		return 0, true
+	}
+	date := now
+	if parsed, err := http.ParseTime(header.Get("Date")); err == nil {
+		date = parsed
+	}
+	ttl := expires.Sub(date)
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl, true
+}
+
+// readBody reads at most maxSize bytes, the second return value is false if the body is larger
+func readBody(body io.Reader, maxSize int64) ([]byte, bool, error) {
+	read, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	return read, int64(len(read)) <= maxSize, nil
+}
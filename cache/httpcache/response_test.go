@@ -0,0 +1,63 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFreshnessFollowsCacheControl(t *testing.T) {
+	// setup
+	now := time.Unix(1000, 0)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	authorized := httptest.NewRequest(http.MethodGet, "/", nil)
+	authorized.Header.Set("Authorization", "Bearer token")
+	tests := []struct {
+		name      string
+		header    http.Header
+		req       *http.Request
+		shared    bool
+		cacheable bool
+		ttl       time.Duration
+		stale     time.Duration
+	}{
+		{"max-age", http.Header{"Cache-Control": {"max-age=60"}}, req, false, true, time.Minute, 0},
+		{"s-maxage in shared cache", http.Header{"Cache-Control": {"max-age=60, s-maxage=120"}}, req, true, true, time.Minute * 2, 0},
+		{"s-maxage in private cache", http.Header{"Cache-Control": {"max-age=60, s-maxage=120"}}, req, false, true, time.Minute, 0},
+		{"no-store", http.Header{"Cache-Control": {"no-store"}}, req, false, false, 0, 0},
+		{"private in shared cache", http.Header{"Cache-Control": {"private, max-age=60"}}, req, true, false, 0, 0},
+		{"no-cache with validator", http.Header{"Cache-Control": {"no-cache, max-age=60"}, "Etag": {`"v1"`}}, req, false, true, 0, 0},
+		{"no-cache without validator", http.Header{"Cache-Control": {"no-cache"}}, req, false, false, 0, 0},
+		{"no freshness", http.Header{}, req, false, false, 0, 0},
+		{"vary all", http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"*"}}, req, false, false, 0, 0},
+		{"stale-while-revalidate", http.Header{"Cache-Control": {"max-age=60, stale-while-revalidate=30"}}, req, false, true, time.Minute, time.Second * 30},
+		{"must-revalidate", http.Header{"Cache-Control": {"max-age=60, stale-while-revalidate=30, must-revalidate"}}, req, false, true, time.Minute, 0},
+		{"authorized in shared cache", http.Header{"Cache-Control": {"max-age=60"}}, authorized, true, false, 0, 0},
+		{"authorized and public in shared cache", http.Header{"Cache-Control": {"public, max-age=60"}}, authorized, true, true, time.Minute, 0},
+	}
+
+	for _, test := range tests {
+		// execute
+		cached, cacheable := newCachedResponse(test.req, http.StatusOK, test.header, nil, now, test.shared, 0)
+
+		// verify
+		if cacheable != test.cacheable {
+			t.Errorf("%s: expected cacheable to be %v", test.name, test.cacheable)
+			continue
+		}
+		if cacheable && (cached.TTL != test.ttl || cached.StaleWhileRevalidate != test.stale) {
+			t.Errorf("%s: expected a ttl of %s and %s stale, got %s and %s", test.name, test.ttl, test.stale, cached.TTL, cached.StaleWhileRevalidate)
+		}
+	}
+}
+
+func TestErrorStatusCodesAreNotCached(t *testing.T) {
+	// execute
+	_, cacheable := newCachedResponse(httptest.NewRequest(http.MethodGet, "/", nil), http.StatusInternalServerError, http.Header{"Cache-Control": {"max-age=60"}}, nil, time.Unix(1000, 0), false, 0)
+
+	// verify
+	if cacheable {
+		t.Errorf("Expected a 500 response not to be cached")
+	}
+}
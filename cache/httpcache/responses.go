@@ -0,0 +1,269 @@
+package httpcache
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+const defaultMaxBodySize = 1 << 20
+const defaultExecutorWorkers = 4
+const defaultExecutorQueueSize = 100
+const minIndexedVariants = 64
+
+// revalidationKeyPrefix - the prefix of the keys the revalidations of stale responses are loaded with, request keys start with a method
+const revalidationKeyPrefix = "revalidate "
+
+// errNotShared is returned by the revalidation of a stale response that was not cached, every request then uses its own response
+var errNotShared = fmt.Errorf("httpcache: revalidated response is not cached: %w", cache.ErrNotLoadable)
+
+// Options configures a Transport or a Middleware.
+type Options struct {
+	// DefaultTTL is how long responses without Cache-Control max-age or an Expires header are fresh. Defaults to 0, such responses are only cached if they can be revalidated.
+	DefaultTTL time.Duration
+	// MaxBodySize is the size of the largest body that is cached. Defaults to 1MB
+	MaxBodySize int64
+	// Clock is used for the freshness of responses. Defaults to cache.LocalClock
+	Clock cache.Clock
+	// Executor revalidates stale-while-revalidate responses in the background. Defaults to a cache.BoundedExecutor
+	Executor cache.Executor
+	// OnError is called when a background revalidation fails
+	OnError func(err error)
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxBodySize < 1 {
+		o.MaxBodySize = defaultMaxBodySize
+	}
+	if o.Clock == nil {
+		o.Clock = cache.LocalClock{}
+	}
+	if o.Executor == nil {
+		o.Executor = cache.NewBoundedExecutor(defaultExecutorWorkers, defaultExecutorQueueSize)
+	}
+	return o
+}
+
+// responses - the cache of a transport or a middleware
+// a response with a Vary header is stored under a variant key with the values of the varying request headers,
+// the entry under the request key is an index without a body that only tells which headers the response varies by
+type responses struct {
+	cache   cache.Cache[string, *CachedResponse]
+	loader  *cache.CallLoader[string, *CachedResponse]
+	options Options
+	shared  bool
+
+	lock *sync.Mutex
+	// revalidating - the variant keys of responses revalidated in the background
+	revalidating map[string]bool
+	// variants - the variant keys stored for every request key, so a request with an unsafe method removes them without scanning the cache
+	variants        map[string]map[string]bool
+	indexedVariants int
+	// pruneAt is the number of indexed variants at which variants that are no longer in the cache are dropped
+	pruneAt int
+}
+
+// newResponses builds the cache as a Blocking cache, a background refresh has no request to revalidate a response with
+func newResponses(builder cache.CacheBuilder[string, *CachedResponse], options Options, shared bool) *responses {
+	loader := cache.NewCallLoader[string, *CachedResponse](nil)
+	return &responses{
+		cache:        builder.SetCacheType(cache.Blocking).Build(loader.Load),
+		loader:       loader,
+		options:      options.withDefaults(),
+		shared:       shared,
+		lock:         &sync.Mutex{},
+		revalidating: make(map[string]bool),
+		variants:     make(map[string]map[string]bool),
+		pruneAt:      minIndexedVariants,
+	}
+}
+
+func (r *responses) now() time.Time {
+	return r.options.Clock.Now()
+}
+
+// lookup returns the variant key of the request and the response cached for it, nil if there is none
+func (r *responses) lookup(req *http.Request, key string) (string, *CachedResponse) {
+	cached, exists := r.cache.GetIfPresent(key)
+	if !exists {
+		return key, nil
+	}
+	if len(cached.Vary) == 0 {
+		return key, cached
+	}
+	variantKey := variantKey(req, key, cached.Vary)
+	variant, exists := r.cache.GetIfPresent(variantKey)
+	if !exists {
+		return variantKey, nil
+	}
+	return variantKey, variant
+}
+
+// store caches the response of the request
+func (r *responses) store(req *http.Request, key string, cached *CachedResponse) {
+	if len(cached.Vary) == 0 {
+		r.cache.Put(key, cached)
+		return
+	}
+	variantKey := variantKey(req, key, cached.Vary)
+	r.indexVariant(key, variantKey)
+	r.cache.Put(variantKey, cached)
+	r.cache.Put(key, &CachedResponse{Vary: cached.Vary})
+}
+
+// remove removes the response of the request with the key and all variants
+func (r *responses) remove(key string) {
+	r.lock.Lock()
+	variants := r.variants[key]
+	delete(r.variants, key)
+	r.indexedVariants -= len(variants)
+	r.lock.Unlock()
+
+	keys := []string{key}
+	for variantKey := range variants {
+		keys = append(keys, variantKey)
+	}
+	r.cache.RemoveAll(keys)
+}
+
+func (r *responses) indexVariant(key string, variantKey string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	variants, exists := r.variants[key]
+	if !exists {
+		variants = make(map[string]bool)
+		r.variants[key] = variants
+	}
+	if !variants[variantKey] {
+		variants[variantKey] = true
+		r.indexedVariants++
+	}
+	r.pruneLocked(variantKey)
+}
+
+// pruneLocked drops the variants that were evicted from the cache from the index, except the variant that is being stored
+func (r *responses) pruneLocked(storing string) {
+	if r.indexedVariants <= r.pruneAt {
+		return
+	}
+	for key, variants := range r.variants {
+		for variantKey := range variants {
+			if variantKey != storing && !r.cache.Contains(variantKey) {
+				delete(variants, variantKey)
+				r.indexedVariants--
+			}
+		}
+		if len(variants) == 0 {
+			delete(r.variants, key)
+		}
+	}
+	r.pruneAt = 2*r.indexedVariants + minIndexedVariants
+}
+
+// revalidate revalidates the stale response of the variant through the loading path of the cache, so concurrent requests for the variant
+// share one revalidation. fetch makes the conditional request and returns the response stored for the variant, nil if none was stored.
+// Returns errNotShared if no response was stored, and cache.ErrNotLoaded if the cache could not load, for example because it is closed.
+func (r *responses) revalidate(variantKey string, stale *CachedResponse, fetch func() (*CachedResponse, error)) (*CachedResponse, error) {
+	revalidationKey := revalidationKeyPrefix + variantKey
+	revalidated, err := r.loader.Get(r.cache, revalidationKey, func() (*CachedResponse, error) {
+		// a request that looked up the stale response before may start its revalidation after the previous one finished
+		if stored := r.stored(variantKey, stale); stored != nil {
+			return stored, nil
+		}
+		stored, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		if stored == nil {
+			return nil, errNotShared
+		}
+		return stored, nil
+	})
+	// the revalidated response is stored under the variant key, the entry of the revalidation is only used by the requests sharing it
+	r.cache.ComputeIfPresent(revalidationKey, func(_ string, old *CachedResponse) (*CachedResponse, cache.Action) {
+		if old != revalidated {
+			return old, cache.Keep
+		}
+		return old, cache.Delete
+	})
+	return revalidated, err
+}
+
+// stored returns the response cached for the variant if it replaced the stale response, nil otherwise. An index of varying headers is not a response.
+func (r *responses) stored(variantKey string, stale *CachedResponse) *CachedResponse {
+	stored, exists := r.cache.GetIfPresent(variantKey)
+	if !exists || stored == stale || stored.StatusCode == 0 {
+		return nil
+	}
+	return stored
+}
+
+// revalidateInBackground runs revalidate on the executor unless the variant is already being revalidated
+func (r *responses) revalidateInBackground(variantKey string, revalidate func() error) {
+	r.lock.Lock()
+	if r.revalidating[variantKey] {
+		r.lock.Unlock()
+		return
+	}
+	r.revalidating[variantKey] = true
+	r.lock.Unlock()
+
+	done := func() {
+		r.lock.Lock()
+		delete(r.revalidating, variantKey)
+		r.lock.Unlock()
+	}
+	err := r.options.Executor.Execute(func() {
+		defer done()
+		if err := revalidate(); err != nil {
+			r.failed(err)
+		}
+	})
+	if err != nil {
+		done()
+		r.failed(err)
+	}
+}
+
+func (r *responses) failed(err error) {
+	if r.options.OnError != nil {
+		r.options.OnError(err)
+	}
+}
+
+// requestKey - the key of a request without the varying headers
+func requestKey(method string, url string) string {
+	return method + " " + url
+}
+
+func variantKey(req *http.Request, key string, vary []string) string {
+	builder := strings.Builder{}
+	builder.WriteString(key)
+	for _, name := range vary {
+		builder.WriteString("\n")
+		builder.WriteString(name)
+		builder.WriteString(": ")
+		builder.WriteString(strings.Join(req.Header.Values(name), ", "))
+	}
+	return builder.String()
+}
+
+// canRevalidate returns true if the cached response can be revalidated with a conditional request,
+// requests with their own validators are passed on, a 304 Not Modified response is for their validators
+func canRevalidate(req *http.Request, cached *CachedResponse) bool {
+	return cached != nil && cached.hasValidators() &&
+		req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == ""
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,163 @@
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+// Transport is an http.RoundTripper caching the responses of GET requests for a single client, so responses marked private are cached as well.
+type Transport struct {
+	transport http.RoundTripper
+	responses *responses
+}
+
+// NewTransport creates a transport caching the responses of transport, http.DefaultTransport if transport is nil, in a cache built with the configuration of the builder.
+// The cache expiration should be 0 or longer than the freshness of the responses. Close the transport when it is no longer used.
+func NewTransport(builder cache.CacheBuilder[string, *CachedResponse], transport http.RoundTripper, options Options) *Transport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Transport{
+		transport: transport,
+		responses: newResponses(builder, options, false),
+	}
+}
+
+// Close releases the cache, see cache.Cache.Close.
+func (t *Transport) Close() error {
+	return t.responses.cache.Close()
+}
+
+// Client returns an http.Client using the transport.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// RoundTrip returns the cached response for GET requests while it is fresh, and revalidates or replaces it once it is stale.
+// Successful requests with unsafe methods like POST remove the cached response of the URL.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := requestKey(http.MethodGet, req.URL.String())
+	if req.Method != http.MethodGet {
+		resp, err := t.transport.RoundTrip(req)
+		if err == nil && isUnsafeMethod(req.Method) && resp.StatusCode < http.StatusBadRequest {
+			t.responses.remove(key)
+		}
+		return resp, err
+	}
+
+	directives := parseCacheControl(req.Header)
+	if directives.has("no-store") {
+		return t.transport.RoundTrip(req)
+	}
+
+	variantKey, cached := t.responses.lookup(req, key)
+	now := t.responses.now()
+	if cached != nil && !directives.has("no-cache") {
+		if cached.fresh(now) {
+			return cached.response(req, now), nil
+		}
+		if cached.servableWhileRevalidating(now) {
+			background := req.Clone(context.WithoutCancel(req.Context()))
+			t.responses.revalidateInBackground(variantKey, func() error {
+				resp, err := t.fetch(background, key, cached)
+				if err != nil {
+					return err
+				}
+				io.Copy(io.Discard, resp.Body)
+				return resp.Body.Close()
+			})
+			return cached.response(req, now), nil
+		}
+	}
+	if canRevalidate(req, cached) {
+		return t.revalidate(req, key, variantKey, cached)
+	}
+	return t.fetch(req, key, cached)
+}
+
+// revalidate makes the conditional request for the stale response, or waits for the revalidation of a concurrent request for the variant
+func (t *Transport) revalidate(req *http.Request, key string, variantKey string, stale *CachedResponse) (*http.Response, error) {
+	// own is set when the revalidation of this request was made, it can run on the goroutine of another request
+	own := atomic.Pointer[http.Response]{}
+	revalidated, err := t.responses.revalidate(variantKey, stale, func() (*CachedResponse, error) {
+		resp, err := t.fetch(req, key, stale)
+		if err != nil {
+			return nil, err
+		}
+		own.Store(resp)
+		return t.responses.stored(variantKey, stale), nil
+	})
+	if resp := own.Load(); resp != nil {
+		return resp, nil
+	}
+	if errors.Is(err, errNotShared) || errors.Is(err, cache.ErrNotLoaded) {
+		return t.fetch(req, key, stale)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return revalidated.response(req, t.responses.now()), nil
+}
+
+// fetch makes the request, conditional if the cached response can be revalidated, and caches the response
+func (t *Transport) fetch(req *http.Request, key string, cached *CachedResponse) (*http.Response, error) {
+	outgoing := req
+	conditional := canRevalidate(req, cached)
+	if conditional {
+		outgoing = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			outgoing.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+			outgoing.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	now := t.responses.now()
+
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		revalidated := cached.revalidated(resp.Header, now)
+		if revalidated.updateFreshness(req, false, t.responses.options.DefaultTTL) {
+			t.responses.store(req, key, revalidated)
+		} else {
+			t.responses.remove(key)
+		}
+		return revalidated.response(req, now), nil
+	}
+
+	body, complete, err := readBody(resp.Body, t.responses.options.MaxBodySize)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if !complete {
+		// too large to cache, the caller reads the rest of the body
+		resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if toCache, cacheable := newCachedResponse(req, resp.StatusCode, resp.Header, body, now, false, t.responses.options.DefaultTTL); cacheable {
+		t.responses.store(req, key, toCache)
+	} else if cached != nil {
+		t.responses.remove(key)
+	}
+	return resp, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,345 @@
+package httpcache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+func newResponseCacheBuilder() cache.CacheBuilder[string, *CachedResponse] {
+	return cache.NewCacheBuilder[string, *CachedResponse]().SetMaxSize(100)
+}
+
+// origin - a server counting its requests
+type origin struct {
+	requests atomic.Int32
+	server   *httptest.Server
+}
+
+func newOrigin(t *testing.T, handler http.HandlerFunc) *origin {
+	o := &origin{}
+	o.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		o.requests.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(o.server.Close)
+	return o
+}
+
+func newTestClient(t *testing.T, clock cache.Clock) *http.Client {
+	transport := NewTransport(newResponseCacheBuilder(), nil, Options{Clock: clock})
+	t.Cleanup(func() {
+		transport.Close()
+	})
+	return transport.Client()
+}
+
+// get returns the body and whether the response was served from the cache
+func get(t *testing.T, client *http.Client, url string, header ...string) (string, bool) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Expected request to succeed, got %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body), resp.Header.Get(XFromCache) == "1"
+}
+
+func TestFreshResponsesAreServedFromTheCache(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	server := newOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("users"))
+	})
+	client := newTestClient(t, clock)
+
+	// execute
+	first, firstFromCache := get(t, client, server.server.URL+"/users")
+	second, secondFromCache := get(t, client, server.server.URL+"/users")
+	clock.Advance(time.Minute)
+	third, thirdFromCache := get(t, client, server.server.URL+"/users")
+
+	// verify
+	if first != "users" || second != "users" || third != "users" {
+		t.Errorf("Expected every response to be 'users'")
+	}
+	if firstFromCache || !secondFromCache || thirdFromCache {
+		t.Errorf("Expected only the second response to be served from the cache")
+	}
+	if server.requests.Load() != 2 {
+		t.Errorf("Expected 2 requests to the origin, got %d", server.requests.Load())
+	}
+}
+
+func TestNoStoreResponsesAreNotCached(t *testing.T) {
+	// setup
+	server := newOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store, max-age=60")
+		w.Write([]byte("secret"))
+	})
+	client := newTestClient(t, cache.NewFakeClock(time.Unix(1000, 0)))
+
+	// execute
+	get(t, client, server.server.URL)
+	_, fromCache := get(t, client, server.server.URL)
+
+	// verify
+	if fromCache || server.requests.Load() != 2 {
+		t.Errorf("Expected no-store response not to be cached")
+	}
+}
+
+func TestStaleResponsesAreRevalidatedWithETag(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	var conditional atomic.Int32
+	server := newOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("version 1"))
+	})
+	client := newTestClient(t, clock)
+	get(t, client, server.server.URL)
+	clock.Advance(time.Second * 11)
+
+	// execute
+	revalidated, revalidatedFromCache := get(t, client, server.server.URL)
+	fresh, freshFromCache := get(t, client, server.server.URL)
+
+	// verify
+	if revalidated != "version 1" || !revalidatedFromCache {
+		t.Errorf("Expected the cached body after a 304, got '%s'", revalidated)
+	}
+	if conditional.Load() != 1 {
+		t.Errorf("Expected one conditional request, got %d", conditional.Load())
+	}
+	if fresh != "version 1" || !freshFromCache || server.requests.Load() != 2 {
+		t.Errorf("Expected the revalidated response to be fresh again")
+	}
+}
+
+func TestConcurrentRequestsForAStaleResponseShareOneRevalidation(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	var conditional atomic.Int32
+	release := make(chan struct{})
+	server := newOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			<-release
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("version 1"))
+	})
+	client := newTestClient(t, clock)
+	get(t, client, server.server.URL)
+	clock.Advance(time.Second * 11)
+
+	// execute
+	wait := &sync.WaitGroup{}
+	bodies := make([]string, 10)
+	for i := range bodies {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			bodies[i], _ = get(t, client, server.server.URL)
+		}(i)
+	}
+	for conditional.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wait.Wait()
+
+	// verify
+	if conditional.Load() != 1 || server.requests.Load() != 2 {
+		t.Errorf("Expected one conditional request, got %d of %d requests", conditional.Load(), server.requests.Load())
+	}
+	for i, body := range bodies {
+		if body != "version 1" {
+			t.Errorf("Expected request %d to get 'version 1', got '%s'", i, body)
+		}
+	}
+}
+
+func TestResponsesAreCachedPerVaryingHeader(t *testing.T) {
+	// setup
+	server := newOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte("hello in " + r.Header.Get("Accept-Language")))
+	})
+	client := newTestClient(t, cache.NewFakeClock(time.Unix(1000, 0)))
+
+	// execute
+	english, _ := get(t, client, server.server.URL, "Accept-Language", "en")
+	german, germanFromCache := get(t, client, server.server.URL, "Accept-Language", "de")
+	englishAgain, englishFromCache := get(t, client, server.server.URL, "Accept-Language", "en")
+
+	// verify
+	if english != "hello in en" || german != "hello in de" || englishAgain != "hello in en" {
+		t.Errorf("Expected a response per language, got '%s', '%s' and '%s'", english, german, englishAgain)
+	}
+	if germanFromCache || !englishFromCache {
+		t.Errorf("Expected only the second english response to be served from the cache")
+	}
+}
+
+func TestVaryingResponsesAreIndexedByTheirRequestKey(t *testing.T) {
+	// setup
+	server := newOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte("hello in " + r.Header.Get("Accept-Language")))
+	})
+	transport := NewTransport(newResponseCacheBuilder(), nil, Options{Clock: cache.NewFakeClock(time.Unix(1000, 0))})
+	defer transport.Close()
+	responseCache := transport.responses.cache
+	client := transport.Client()
+	get(t, client, server.server.URL, "Accept-Language", "en")
+	get(t, client, server.server.URL, "Accept-Language", "de")
+
+	// execute
+	index, indexed := responseCache.GetIfPresent(requestKey(http.MethodGet, server.server.URL))
+	cachedBeforePost := responseCache.Len()
+	resp, err := client.Post(server.server.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("Expected post to succeed, got %v", err)
+	}
+	resp.Body.Close()
+
+	// verify
+	if !indexed || index.Body != nil || len(index.Vary) != 1 || index.Vary[0] != "Accept-Language" {
+		t.Errorf("Expected the request key to only store the varying headers, got %+v", index)
+	}
+	if cachedBeforePost != 3 {
+		t.Errorf("Expected the index and 2 variants to be cached, got %d entries", cachedBeforePost)
+	}
+	if responseCache.Len() != 0 {
+		t.Errorf("Expected the post to remove the index and all variants, got %d entries", responseCache.Len())
+	}
+}
+
+func TestStaleWhileRevalidateServesStaleResponseAndRefreshesInBackground(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	var version atomic.Int32
+	server := newOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10, stale-while-revalidate=60")
+		fmt.Fprintf(w, "version %d", version.Add(1))
+	})
+	client := newTestClient(t, clock)
+	get(t, client, server.server.URL)
+	clock.Advance(time.Second * 20)
+
+	// execute
+	stale, staleFromCache := get(t, client, server.server.URL)
+	for server.requests.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	var refreshed string
+	for refreshed != "version 2" {
+		refreshed, _ = get(t, client, server.server.URL)
+	}
+
+	// verify
+	if stale != "version 1" || !staleFromCache {
+		t.Errorf("Expected the stale response to be served, got '%s'", stale)
+	}
+	if server.requests.Load() != 2 {
+		t.Errorf("Expected one background request, got %d requests", server.requests.Load())
+	}
+}
+
+func TestUnsafeRequestsRemoveTheCachedResponse(t *testing.T) {
+	// setup
+	server := newOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept")
+		w.Write([]byte(r.Method))
+	})
+	client := newTestClient(t, cache.NewFakeClock(time.Unix(1000, 0)))
+	get(t, client, server.server.URL+"/users/sam", "Accept", "text/plain")
+
+	// execute
+	resp, err := client.Post(server.server.URL+"/users/sam", "text/plain", strings.NewReader("sam"))
+	if err != nil {
+		t.Fatalf("Expected post to succeed, got %v", err)
+	}
+	resp.Body.Close()
+	_, fromCache := get(t, client, server.server.URL+"/users/sam", "Accept", "text/plain")
+
+	// verify
+	if fromCache || server.requests.Load() != 3 {
+		t.Errorf("Expected the response to be loaded again after the post")
+	}
+}
+
+func TestResponsesExpireWithTheExpiresHeader(t *testing.T) {
+	// setup
+	clock := cache.NewFakeClock(time.Unix(1000, 0))
+	server := newOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Unix(1000, 0).UTC().Format(http.TimeFormat))
+		w.Header().Set("Expires", time.Unix(1030, 0).UTC().Format(http.TimeFormat))
+		w.Write([]byte("users"))
+	})
+	client := newTestClient(t, clock)
+	get(t, client, server.server.URL)
+
+	// execute
+	clock.Advance(time.Second * 29)
+	_, beforeExpiry := get(t, client, server.server.URL)
+	clock.Advance(time.Second)
+	_, afterExpiry := get(t, client, server.server.URL)
+
+	// verify
+	if !beforeExpiry || afterExpiry {
+		t.Errorf("Expected the response to expire after 30 seconds")
+	}
+}
+
+func TestLargeBodiesAreNotCached(t *testing.T) {
+	// setup
+	server := newOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(strings.Repeat("x", 100)))
+	})
+	transport := NewTransport(newResponseCacheBuilder(), nil, Options{MaxBodySize: 10})
+	defer transport.Close()
+	client := transport.Client()
+
+	// execute
+	body, _ := get(t, client, server.server.URL)
+	_, fromCache := get(t, client, server.server.URL)
+
+	// verify
+	if len(body) != 100 {
+		t.Errorf("Expected the whole body, got %d bytes", len(body))
+	}
+	if fromCache {
+		t.Errorf("Expected a body over MaxBodySize not to be cached")
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Invalidation is published on an InvalidationBus when entries of a cache are removed or replaced, so other instances of the cache drop their copies.
+type Invalidation[K comparable] struct {
+	// Source is the id of the cache instance that published the invalidation, an instance ignores its own invalidations
+	Source string
+	// Keys are the invalidated keys
+	Keys []K
+	// All is true when all entries were invalidated, Keys is empty then
+	All bool
+}
+
+// InvalidationBus publishes invalidations to all instances of a cache. Implementations have to be safe for concurrent use.
+type InvalidationBus[K comparable] interface {
+	// Publish sends the invalidation to all subscribers of the bus.
+	Publish(ctx context.Context, invalidation Invalidation[K]) error
+	// Subscribe calls the handler for every invalidation published on the bus, including the invalidations published by the subscriber itself.
+	// The returned function ends the subscription.
+	Subscribe(handler func(invalidation Invalidation[K])) (func(), error)
+}
+
+// invalidationTier - the invalidation bus of a cache with the id of the cache instance, the timeout and hooks of the cache
+type invalidationTier[K comparable] struct {
+	bus     InvalidationBus[K]
+	id      string
+	timeout time.Duration
+	onError func(err error)
+
+	unsubscribe func()
+}
+
+func newInvalidationTier[K comparable, V any](cacheInfo CacheInfo[K, V]) *invalidationTier[K] {
+	if cacheInfo.InvalidationBus == nil {
+		return nil
+	}
+	return &invalidationTier[K]{
+		bus:     cacheInfo.InvalidationBus,
+		id:      newInstanceID(),
+		timeout: cacheInfo.LoadTimeout,
+		onError: cacheInfo.Hooks.OnInvalidationError,
+	}
+}
+
+// subscribe calls invalidate for the invalidations published by other instances
+func (t *invalidationTier[K]) subscribe(invalidate func(invalidation Invalidation[K])) {
+	if t == nil {
+		return
+	}
+
+	unsubscribe, err := t.bus.Subscribe(func(invalidation Invalidation[K]) {
+		if invalidation.Source == t.id {
+			return
+		}
+		invalidate(invalidation)
+	})
+	if err != nil {
+		t.failed(err)
+		return
+	}
+	t.unsubscribe = unsubscribe
+}
+
+func (t *invalidationTier[K]) publish(keys ...K) {
+	if t == nil || len(keys) == 0 {
+		return
+	}
+	t.send(Invalidation[K]{Source: t.id, Keys: keys})
+}
+
+func (t *invalidationTier[K]) publishAll() {
+	if t == nil {
+		return
+	}
+	t.send(Invalidation[K]{Source: t.id, All: true})
+}
+
+func (t *invalidationTier[K]) close() {
+	if t == nil || t.unsubscribe == nil {
+		return
+	}
+	t.unsubscribe()
+}
+
+func (t *invalidationTier[K]) send(invalidation Invalidation[K]) {
+	ctx, cancel := timeoutContext(t.timeout)
+	defer cancel()
+	if err := t.bus.Publish(ctx, invalidation); err != nil {
+		t.failed(err)
+	}
+}
+
+func (t *invalidationTier[K]) failed(err error) {
+	if t.onError != nil {
+		t.onError(err)
+	}
+}
+
+// newInstanceID returns a random id for a cache instance
+func newInstanceID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryInvalidationBus is an InvalidationBus for caches in the same process, for example in tests. Publish calls the subscribers before it returns.
+type MemoryInvalidationBus[K comparable] struct {
+	lock        *sync.RWMutex
+	subscribers map[int]func(invalidation Invalidation[K])
+	nextID      int
+}
+
+func NewMemoryInvalidationBus[K comparable]() *MemoryInvalidationBus[K] {
+	return &MemoryInvalidationBus[K]{
+		lock:        &sync.RWMutex{},
+		subscribers: make(map[int]func(invalidation Invalidation[K])),
+	}
+}
+
+func (m *MemoryInvalidationBus[K]) Publish(ctx context.Context, invalidation Invalidation[K]) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.deliver(invalidation)
+	return nil
+}
+
+func (m *MemoryInvalidationBus[K]) Subscribe(handler func(invalidation Invalidation[K])) (func(), error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	id := m.nextID
+	m.nextID++
+	m.subscribers[id] = handler
+
+	return func() {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		delete(m.subscribers, id)
+	}, nil
+}
+
+// deliver calls the subscribers without holding the lock, so a subscriber can publish or unsubscribe
+func (m *MemoryInvalidationBus[K]) deliver(invalidation Invalidation[K]) {
+	m.lock.RLock()
+	handlers := make([]func(invalidation Invalidation[K]), 0, len(m.subscribers))
+	for _, handler := range m.subscribers {
+		handlers = append(handlers, handler)
+	}
+	m.lock.RUnlock()
+
+	for _, handler := range handlers {
+		handler(invalidation)
+	}
+}
+
+// ChannelInvalidationBus is an InvalidationBus connecting caches to any message system through channels. Published invalidations are sent to the out channel,
+// and every invalidation received on the in channel is delivered to the subscribers until the in channel is closed.
+// The message system has to deliver invalidations published by a cache back to it as well, caches ignore their own invalidations.
+type ChannelInvalidationBus[K comparable] struct {
+	out         chan<- Invalidation[K]
+	subscribers *MemoryInvalidationBus[K]
+}
+
+func NewChannelInvalidationBus[K comparable](out chan<- Invalidation[K], in <-chan Invalidation[K]) *ChannelInvalidationBus[K] {
+	bus := &ChannelInvalidationBus[K]{
+		out:         out,
+		subscribers: NewMemoryInvalidationBus[K](),
+	}
+	go func() {
+		for invalidation := range in {
+			bus.subscribers.deliver(invalidation)
+		}
+	}()
+	return bus
+}
+
+// Publish sends the invalidation to the out channel, waiting until the channel accepts it or the context is done.
+func (c *ChannelInvalidationBus[K]) Publish(ctx context.Context, invalidation Invalidation[K]) error {
+	select {
+	case c.out <- invalidation:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *ChannelInvalidationBus[K]) Subscribe(handler func(invalidation Invalidation[K])) (func(), error) {
+	return c.subscribers.Subscribe(handler)
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func buildInvalidatedTestCache(bus InvalidationBus[string], loads *int) Cache[string, string] {
+	return NewCacheBuilder[string, string]().
+		SetInvalidationBus(bus).
+		Build(func(k string) (string, error) {
+			*loads++
+			return "loaded " + k, nil
+		})
+}
+
+func TestRemoveIsPublishedToOtherInstances(t *testing.T) {
+	// setup
+	bus := NewMemoryInvalidationBus[string]()
+	loads := 0
+	first := buildInvalidatedTestCache(bus, &loads)
+	second := buildInvalidatedTestCache(bus, &loads)
+	first.Get("a")
+	second.Get("a")
+
+	// execute
+	first.Remove("a")
+
+	// verify
+	if second.Contains("a") {
+		t.Errorf("Expected 'a' to be removed from the second instance")
+	}
+}
+
+func TestPutRemovesStaleCopiesButKeepsTheNewValue(t *testing.T) {
+	// setup
+	bus := NewMemoryInvalidationBus[string]()
+	loads := 0
+	first := buildInvalidatedTestCache(bus, &loads)
+	second := buildInvalidatedTestCache(bus, &loads)
+	second.Get("a")
+
+	// execute
+	first.Put("a", "new")
+
+	// verify
+	if value, _ := first.GetIfPresent("a"); value != "new" {
+		t.Errorf("Expected 'a' to be 'new' on the first instance, got '%s'", value)
+	}
+	if second.Contains("a") {
+		t.Errorf("Expected stale 'a' to be removed from the second instance")
+	}
+}
+
+func TestInvalidateAllIsPublishedToOtherInstances(t *testing.T) {
+	// setup
+	bus := NewMemoryInvalidationBus[string]()
+	loads := 0
+	first := buildInvalidatedTestCache(bus, &loads)
+	second := buildInvalidatedTestCache(bus, &loads)
+	second.Get("a")
+	second.Get("b")
+
+	// execute
+	first.InvalidateAll()
+
+	// verify
+	if second.Len() != 0 {
+		t.Errorf("Expected all entries to be removed from the second instance, got %d", second.Len())
+	}
+}
+
+func TestClosedCacheStopsReceivingInvalidations(t *testing.T) {
+	// setup
+	bus := NewMemoryInvalidationBus[string]()
+	received := 0
+	bus.Subscribe(func(invalidation Invalidation[string]) { received++ })
+	loads := 0
+	closed := buildInvalidatedTestCache(bus, &loads)
+
+	// execute
+	closed.Close()
+
+	// verify
+	if len(bus.subscribers) != 1 {
+		t.Errorf("Expected closed cache to unsubscribe, got %d subscribers", len(bus.subscribers))
+	}
+}
+
+func TestChannelInvalidationBusDeliversReceivedInvalidations(t *testing.T) {
+	// setup
+	messages := make(chan Invalidation[string])
+	defer close(messages)
+	bus := NewChannelInvalidationBus[string](messages, messages)
+	loads := 0
+	first := buildInvalidatedTestCache(bus, &loads)
+	second := buildInvalidatedTestCache(bus, &loads)
+	second.Get("a")
+
+	// execute
+	first.Remove("a")
+
+	// verify
+	// invalidations are delivered in the background
+	deadline := time.Now().Add(time.Second)
+	for second.Contains("a") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if second.Contains("a") {
+		t.Errorf("Expected 'a' to be removed from the second instance")
+	}
+}
+
+func TestChannelInvalidationBusPublishStopsWithContext(t *testing.T) {
+	// setup
+	bus := NewChannelInvalidationBus[string](make(chan Invalidation[string]), make(chan Invalidation[string]))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// execute
+	err := bus.Publish(ctx, Invalidation[string]{Keys: []string{"a"}})
+
+	// verify
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned from the loading path when the cache has been closed, and from Close when the cache was already closed.
+var ErrClosed = errors.New("cache: cache is closed")
+
+// lifecycle - tracks the in flight loads of a cache so closing the cache can reject new loads and wait for the running ones
+type lifecycle struct {
+	lock     *sync.Mutex
+	closed   bool
+	inFlight int
+	// idle is closed once the lifecycle is closed and there are no more loads in flight
+	idle chan struct{}
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		lock: &sync.Mutex{},
+		idle: make(chan struct{}),
+	}
+}
+
+// begin registers a new load, returns ErrClosed if the cache is closed
+// every successful call to begin has to be followed by a call to end
+func (l *lifecycle) begin() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	l.inFlight++
+	return nil
+}
+
+func (l *lifecycle) end() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.inFlight--
+	if l.closed && l.inFlight == 0 {
+		close(l.idle)
+	}
+}
+
+// close stops new loads from starting, returns ErrClosed if it was already closed
+func (l *lifecycle) close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	l.closed = true
+	if l.inFlight == 0 {
+		close(l.idle)
+	}
+	return nil
+}
+
+// wait waits for the in flight loads to finish or for the context to be done
+func (l *lifecycle) wait(ctx context.Context) error {
+	select {
+	case <-l.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
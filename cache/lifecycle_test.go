@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCloseWaitsForInFlightLoads(t *testing.T) {
+	// setup
+	started := make(chan struct{})
+	release := make(chan struct{})
+	closingCache := BuildTestCacheByType[string, string](Blocking, func(k string) (string, error) {
+		close(started)
+		<-release
+		return "value", nil
+	}, LocalClock{})
+	go closingCache.Get("key")
+	<-started
+
+	// execute
+	closed := make(chan error)
+	go func() {
+		closed <- closingCache.Close()
+	}()
+
+	// verify
+	select {
+	case <-closed:
+		t.Fatalf("Expected close to wait for the in flight load")
+	case <-time.After(time.Millisecond * 10):
+	}
+	close(release)
+	if err := <-closed; err != nil {
+		t.Errorf("Expected close to succeed, got %v", err)
+	}
+}
+
+func TestShutdownReturnsContextErrorWhenLoadsDoNotFinishInTime(t *testing.T) {
+	// setup
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	closingCache := BuildTestCacheByType[string, string](Refresh, func(k string) (string, error) {
+		close(started)
+		<-release
+		return "value", nil
+	}, LocalClock{})
+	go closingCache.Get("key")
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	// execute
+	err := closingCache.Shutdown(ctx)
+
+	// verify
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected shutdown to time out, got %v", err)
+	}
+}
+
+func TestWhenCacheIsClosedNewLoadsAreRejected(t *testing.T) {
+	// setup
+	initTests()
+	cache.Close()
+
+	// execute
+	_, exists := cache.Get("key")
+	err := cache.Close()
+
+	// verify
+	if exists {
+		t.Errorf("Expected get on a closed cache to fail")
+	}
+	if len(cacheLoader.KeysRequests) != 0 {
+		t.Errorf("Expected loader not to be called")
+	}
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected second close to return ErrClosed")
+	}
+}
+
+func TestWhenCacheIsClosedRemoveHookIsCalledForRemainingEntries(t *testing.T) {
+	// setup
+	removed := make([]string, 0)
+	closingCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{
+		Clock: LocalClock{},
+	}).
+		SetHooks(CacheHooks[string]{
+			OnCacheRemove: func(k string) {
+				removed = append(removed, k)
+			},
+		}).
+		Build(func(k string) (string, error) {
+			return "value", nil
+		})
+	closingCache.Put("key", "value")
+
+	// execute
+	closingCache.Close()
+	_, exists := closingCache.Get("key")
+
+	// verify
+	if len(removed) != 1 || removed[0] != "key" {
+		t.Errorf("Expected remove hook to be called for 'key'")
+	}
+	if exists {
+		t.Errorf("Expected entries to be released")
+	}
+}
+
+// queuedExecutor - an executor whose tasks only run when run is called
+type queuedExecutor struct {
+	tasks []func()
+}
+
+func (q *queuedExecutor) Execute(task func()) error {
+	q.tasks = append(q.tasks, task)
+	return nil
+}
+
+func (q *queuedExecutor) Shutdown(ctx context.Context) error {
+	q.run()
+	return nil
+}
+
+func (q *queuedExecutor) run() {
+	for _, task := range q.tasks {
+		task()
+	}
+	q.tasks = nil
+}
+
+func TestRefreshesRunAfterCloseAreNotLoadFailures(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Now())
+	registry := NewRegistry()
+	executor := &queuedExecutor{}
+	failures := 0
+	closingCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{Clock: clock}).
+		SetCacheType(Refresh).
+		SetExpiration(time.Second).
+		SetExecutor(executor).
+		SetName("closing").
+		SetRegistry(registry).
+		SetHooks(CacheHooks[string]{
+			OnFailedToLoadEntry: func(k string) { failures++ },
+		}).
+		Build(func(k string) (string, error) {
+			return "value", nil
+		})
+	registered, _ := registry.Get("closing")
+	closingCache.Get("a")
+	clock.Advance(time.Second * 2)
+	closingCache.Get("a")
+
+	// execute
+	closingCache.Close()
+	executor.run()
+
+	// verify
+	if failures != 0 || registered.Stats().LoadFailures != 0 {
+		t.Errorf("Expected the refresh after close not to be a load failure, got %d failures", failures)
+	}
+}
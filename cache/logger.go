@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type LogConfig struct {
+	// LoadFailureLevel is the level failed loads are logged at.
+	// Defaults to slog.LevelWarn
+	LoadFailureLevel slog.Leveler
+	// SlowLoadLevel is the level loads slower than the SlowLoadThreshold are logged at.
+	// Defaults to slog.LevelWarn
+	SlowLoadLevel slog.Leveler
+	// SlowLoadThreshold is the duration of a load above which it is logged as slow.
+	// Defaults to 0, slow loads are not logged
+	SlowLoadThreshold time.Duration
+	// EvictionLevel is the level evictions of entries from a full cache are logged at.
+	// Defaults to slog.LevelDebug
+	EvictionLevel slog.Leveler
+	// RefreshLevel is the level the start and the outcome of background reloads are logged at, the failed load of a reload is logged at the LoadFailureLevel.
+	// Defaults to slog.LevelDebug
+	RefreshLevel slog.Leveler
+	// RedactKey is logged instead of a key, for example HashKey to keep personal data out of the logs.
+	// Defaults to logging keys as they are
+	RedactKey func(key any) string
+}
+
+func (config LogConfig) withDefaults() LogConfig {
+	if config.LoadFailureLevel == nil {
+		config.LoadFailureLevel = slog.LevelWarn
+	}
+	if config.SlowLoadLevel == nil {
+		config.SlowLoadLevel = slog.LevelWarn
+	}
+	if config.EvictionLevel == nil {
+		config.EvictionLevel = slog.LevelDebug
+	}
+	if config.RefreshLevel == nil {
+		config.RefreshLevel = slog.LevelDebug
+	}
+	return config
+}
+
+// HashKey returns the start of the sha256 hash of the key, so log lines of the same key can be matched without logging the key.
+func HashKey(key any) string {
+	hash := sha256.Sum256([]byte(fmt.Sprint(key)))
+	return "sha256:" + hex.EncodeToString(hash[:8])
+}
+
+// cacheLogger - logs the events of a cache with its configured levels
+type cacheLogger[K comparable] struct {
+	logger *slog.Logger
+	config LogConfig
+}
+
+func newCacheLogger[K comparable, V any](cacheInfo CacheInfo[K, V]) *cacheLogger[K] {
+	if cacheInfo.Logger == nil {
+		return nil
+	}
+	logger := cacheInfo.Logger
+	if cacheInfo.Name != "" {
+		logger = logger.With(slog.String("cache", cacheInfo.Name))
+	}
+	return &cacheLogger[K]{
+		logger: logger,
+		config: cacheInfo.LogConfig.withDefaults(),
+	}
+}
+
+// loaded logs a failed or slow call to the cache loader
+func (l *cacheLogger[K]) loaded(k K, duration time.Duration, err error) {
+	if l == nil {
+		return
+	}
+	if err != nil {
+		l.log(l.config.LoadFailureLevel, "cache load failed", func() []slog.Attr {
+			return []slog.Attr{l.key(k), slog.Duration("duration", duration), slog.Any("error", err)}
+		})
+		return
+	}
+	if l.config.SlowLoadThreshold > 0 && duration > l.config.SlowLoadThreshold {
+		l.log(l.config.SlowLoadLevel, "cache load slow", func() []slog.Attr {
+			return []slog.Attr{l.key(k), slog.Duration("duration", duration), slog.Duration("threshold", l.config.SlowLoadThreshold)}
+		})
+	}
+}
+
+// loadRejected logs a load that failed before the cache loader was called, for example because the circuit is open
+func (l *cacheLogger[K]) loadRejected(k K, err error) {
+	if l == nil {
+		return
+	}
+	l.log(l.config.LoadFailureLevel, "cache load failed", func() []slog.Attr {
+		return []slog.Attr{l.key(k), slog.Any("error", err)}
+	})
+}
+
+func (l *cacheLogger[K]) evicted(count int) {
+	if l == nil || count == 0 {
+		return
+	}
+	l.log(l.config.EvictionLevel, "cache entries evicted", func() []slog.Attr {
+		return []slog.Attr{slog.Int("count", count)}
+	})
+}
+
+func (l *cacheLogger[K]) refreshStarted(k K) {
+	if l == nil {
+		return
+	}
+	l.log(l.config.RefreshLevel, "cache refresh started", func() []slog.Attr {
+		return []slog.Attr{l.key(k)}
+	})
+}
+
+// refreshFinished logs the outcome of a refresh, its failed load was already logged by loaded or loadRejected
+func (l *cacheLogger[K]) refreshFinished(k K, err error) {
+	if l == nil {
+		return
+	}
+	if errors.Is(err, ErrNotLoadable) || errors.Is(err, ErrClosed) {
+		l.log(l.config.RefreshLevel, "cache refresh skipped", func() []slog.Attr {
+			return []slog.Attr{l.key(k), slog.Any("reason", err)}
+		})
+		return
+	}
+	if err != nil {
+		l.log(l.config.RefreshLevel, "cache refresh failed", func() []slog.Attr {
+			return []slog.Attr{l.key(k), slog.Any("error", err)}
+		})
+		return
+	}
+	l.log(l.config.RefreshLevel, "cache refresh finished", func() []slog.Attr {
+		return []slog.Attr{l.key(k)}
+	})
+}
+
+func (l *cacheLogger[K]) refreshRejected(k K, err error) {
+	if l == nil {
+		return
+	}
+	l.log(l.config.LoadFailureLevel, "cache refresh rejected", func() []slog.Attr {
+		return []slog.Attr{l.key(k), slog.Any("error", err)}
+	})
+}
+
+// log builds the attributes only if the level is enabled
+func (l *cacheLogger[K]) log(level slog.Leveler, message string, attrs func() []slog.Attr) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level.Level()) {
+		return
+	}
+	l.logger.LogAttrs(ctx, level.Level(), message, attrs()...)
+}
+
+func (l *cacheLogger[K]) key(k K) slog.Attr {
+	if l.config.RedactKey != nil {
+		return slog.String("key", l.config.RedactKey(k))
+	}
+	return slog.Any("key", k)
+}
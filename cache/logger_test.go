@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logRecorder - a concurrency safe buffer for a JSON slog handler
+type logRecorder struct {
+	lock   *sync.Mutex
+	buffer *bytes.Buffer
+}
+
+func (l *logRecorder) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.buffer.Write(p)
+}
+
+func (l *logRecorder) records() []map[string]any {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	records := []map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(l.buffer.String()), "\n") {
+		record := map[string]any{}
+		if json.Unmarshal([]byte(line), &record) == nil {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func (l *logRecorder) find(message string) (map[string]any, bool) {
+	for _, record := range l.records() {
+		if record["msg"] == message {
+			return record, true
+		}
+	}
+	return nil, false
+}
+
+func newTestLogger() (*slog.Logger, *logRecorder) {
+	recorder := &logRecorder{lock: &sync.Mutex{}, buffer: &bytes.Buffer{}}
+	return slog.New(slog.NewJSONHandler(recorder, &slog.HandlerOptions{Level: slog.LevelDebug})), recorder
+}
+
+func TestLoadFailuresAreLoggedWithTheError(t *testing.T) {
+	// setup
+	logger, recorder := newTestLogger()
+	loggedCache := NewCacheBuilder[string, string]().
+		SetName("logged-failures").
+		SetRegistry(NewRegistry()).
+		SetLogger(logger).
+		Build(func(k string) (string, error) {
+			return "", errors.New("database unavailable")
+		})
+
+	// execute
+	loggedCache.Get("a")
+
+	// verify
+	record, found := recorder.find("cache load failed")
+	if !found {
+		t.Fatalf("Expected the load failure to be logged")
+	}
+	if record["level"] != "WARN" || record["key"] != "a" || record["error"] != "database unavailable" || record["cache"] != "logged-failures" {
+		t.Errorf("Expected a warning with the key, error and cache name, got %v", record)
+	}
+}
+
+func TestSlowLoadsAndEvictionsAreLoggedWithRedactedKeys(t *testing.T) {
+	// setup
+	logger, recorder := newTestLogger()
+	loggedCache := NewCacheBuilder[string, string]().
+		SetMaxSize(1).
+		SetEvictionPercent(100).
+		SetLogger(logger).
+		SetLogConfig(LogConfig{
+			SlowLoadThreshold: time.Nanosecond,
+			RedactKey:         HashKey,
+		}).
+		Build(func(k string) (string, error) {
+			time.Sleep(time.Millisecond)
+			return "loaded", nil
+		})
+
+	// execute
+	loggedCache.Get("secret")
+	loggedCache.Get("other")
+
+	// verify
+	slow, found := recorder.find("cache load slow")
+	if !found || slow["key"] != HashKey("secret") {
+		t.Errorf("Expected the slow load to be logged with the hashed key, got %v", slow)
+	}
+	evicted, found := recorder.find("cache entries evicted")
+	if !found || evicted["level"] != "DEBUG" || evicted["count"] != float64(1) {
+		t.Errorf("Expected 1 eviction to be logged at debug, got %v", evicted)
+	}
+}
+
+func TestBackgroundRefreshIsLogged(t *testing.T) {
+	// setup
+	logger, recorder := newTestLogger()
+	clock := NewFakeClock(time.Now())
+	loads := 0
+	loggedCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{Clock: clock}).
+		SetCacheType(Refresh).
+		SetExpiration(time.Second).
+		SetLogger(logger).
+		Build(func(k string) (string, error) {
+			loads++
+			if loads > 1 {
+				return "", errors.New("refresh failed")
+			}
+			return "loaded", nil
+		})
+	loggedCache.Get("a")
+
+	// execute
+	clock.Advance(time.Second * 2)
+	loggedCache.Get("a")
+	defer loggedCache.Close()
+
+	// verify
+	// the refresh runs in the background
+	deadline := time.Now().Add(time.Second)
+	for _, found := recorder.find("cache refresh failed"); !found && time.Now().Before(deadline); _, found = recorder.find("cache refresh failed") {
+		time.Sleep(time.Millisecond)
+	}
+	if _, found := recorder.find("cache refresh started"); !found {
+		t.Errorf("Expected the start of the refresh to be logged")
+	}
+	if record, found := recorder.find("cache refresh failed"); !found || record["error"] != "refresh failed" || record["level"] != "DEBUG" {
+		t.Errorf("Expected the failed refresh to be logged at debug with its error, got %v", record)
+	}
+	failures := 0
+	for _, record := range recorder.records() {
+		if record["level"] == "WARN" {
+			failures++
+		}
+	}
+	if failures != 1 {
+		t.Errorf("Expected the failed load of the refresh to be logged once as a failure, got %d", failures)
+	}
+}
+
+func TestRefreshesThatCanNotLoadAreNotLoggedAsFailures(t *testing.T) {
+	// setup
+	logger, recorder := newTestLogger()
+	clock := NewFakeClock(time.Now())
+	loads := 0
+	loggedCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{Clock: clock}).
+		SetCacheType(Refresh).
+		SetExpiration(time.Second).
+		SetLogger(logger).
+		Build(func(k string) (string, error) {
+			loads++
+			if loads > 1 {
+				return "", ErrNotLoadable
+			}
+			return "loaded", nil
+		})
+	defer loggedCache.Close()
+	loggedCache.Get("a")
+
+	// execute
+	clock.Advance(time.Second * 2)
+	loggedCache.Get("a")
+
+	// verify
+	deadline := time.Now().Add(time.Second)
+	for _, found := recorder.find("cache refresh skipped"); !found && time.Now().Before(deadline); _, found = recorder.find("cache refresh skipped") {
+		time.Sleep(time.Millisecond)
+	}
+	if record, found := recorder.find("cache refresh skipped"); !found || record["level"] != "DEBUG" {
+		t.Errorf("Expected the refresh to be logged as skipped at debug, got %v", record)
+	}
+	if _, found := recorder.find("cache refresh failed"); found {
+		t.Errorf("Expected the refresh not to be logged as failed")
+	}
+}
@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// MemoizeOption configures the cache of a memoized function
+type MemoizeOption func(options *memoizeOptions)
+
+type memoizeOptions struct {
+	maxSize         *int
+	evictionPercent *int
+	expiration      time.Duration
+	cacheType       CacheType
+	name            string
+	registry        *Registry
+	// cacheError - nil if errors are not cached
+	cacheError func(err error) bool
+}
+
+// MemoizeMaxSize sets the maximum number of results the memoized function keeps, see CacheBuilder.SetMaxSize.
+func MemoizeMaxSize(size int) MemoizeOption {
+	return func(options *memoizeOptions) {
+		options.maxSize = &size
+	}
+}
+
+// MemoizeEvictionPercent sets the percent of the results that are removed when the memoized function keeps the maximum number of results, see CacheBuilder.SetEvictionPercent.
+func MemoizeEvictionPercent(evictionPercent int) MemoizeOption {
+	return func(options *memoizeOptions) {
+		options.evictionPercent = &evictionPercent
+	}
+}
+
+// MemoizeExpiration sets how long results are kept before the function is called again, see CacheBuilder.SetExpiration.
+func MemoizeExpiration(expiration time.Duration) MemoizeOption {
+	return func(options *memoizeOptions) {
+		options.expiration = expiration
+	}
+}
+
+// MemoizeCacheType sets the type of the cache, with Refresh expired results are returned while the function is called in the background.
+func MemoizeCacheType(cacheType CacheType) MemoizeOption {
+	return func(options *memoizeOptions) {
+		options.cacheType = cacheType
+	}
+}
+
+// MemoizeName names the cache and registers it in the registry, DefaultRegistry if registry is nil, see CacheBuilder.SetName.
+func MemoizeName(name string, registry *Registry) MemoizeOption {
+	return func(options *memoizeOptions) {
+		options.name = name
+		options.registry = registry
+	}
+}
+
+// MemoizeErrors caches the errors for which cacheError returns true like results, so the function is not called again for them until they expire.
+// A nil cacheError caches all errors. By default errors are not cached and the next call calls the function again.
+func MemoizeErrors(cacheError func(err error) bool) MemoizeOption {
+	return func(options *memoizeOptions) {
+		if cacheError == nil {
+			cacheError = func(err error) bool {
+				return true
+			}
+		}
+		options.cacheError = cacheError
+	}
+}
+
+// memoResult - the result of one call of a memoized function, err is only set for errors that are cached
+type memoResult[V any] struct {
+	value V
+	err   error
+}
+
+// Memoized is a function with its results cached by a Cache. It is safe for concurrent use, concurrent calls for a key without a cached result share one call of the function.
+type Memoized[K comparable, V any] struct {
+	cache  Cache[K, memoResult[V]]
+	loader *CallLoader[K, memoResult[V]]
+	load   CacheLoader[K, memoResult[V]]
+}
+
+// Memoize caches the results of fn by their key. Close the memoized function when it is no longer used.
+func Memoize[K comparable, V any](fn func(k K) (V, error), options ...MemoizeOption) *Memoized[K, V] {
+	memoizeOptions := memoizeOptions{}
+	for _, option := range options {
+		option(&memoizeOptions)
+	}
+
+	builder := NewCacheBuilder[K, memoResult[V]]().
+		SetCacheType(memoizeOptions.cacheType).
+		SetExpiration(memoizeOptions.expiration)
+	if memoizeOptions.maxSize != nil {
+		builder.SetMaxSize(*memoizeOptions.maxSize)
+	}
+	if memoizeOptions.evictionPercent != nil {
+		builder.SetEvictionPercent(*memoizeOptions.evictionPercent)
+	}
+	if memoizeOptions.name != "" {
+		builder.SetName(memoizeOptions.name)
+	}
+	if memoizeOptions.registry != nil {
+		builder.SetRegistry(memoizeOptions.registry)
+	}
+	// errors that are not cached fail the load, so they are not stored and a refresh keeps the previous result
+	cacheError := memoizeOptions.cacheError
+	load := func(k K) (memoResult[V], error) {
+		value, err := fn(k)
+		if err != nil && (cacheError == nil || !cacheError(err)) {
+			return memoResult[V]{}, err
+		}
+		return memoResult[V]{value: value, err: err}, nil
+	}
+	loader := NewCallLoader[K, memoResult[V]](load)
+	return &Memoized[K, V]{
+		cache:  builder.Build(loader.Load),
+		loader: loader,
+		load:   load,
+	}
+}
+
+// Call returns the cached result for the key, or calls the function if there is none. Returns ErrClosed once the memoized function is closed.
+func (m *Memoized[K, V]) Call(k K) (V, error) {
+	result, err := m.loader.Get(m.cache, k, func() (memoResult[V], error) {
+		return m.load(k)
+	})
+	// memoized functions have no circuit breaker or load timeout, the cache only skips the function when it is closed
+	if errors.Is(err, ErrNotLoaded) {
+		err = ErrClosed
+	}
+	if err != nil {
+		var defaultValue V
+		return defaultValue, err
+	}
+	return result.value, result.err
+}
+
+// Invalidate removes the cached result for the key, so the next call calls the function. Returns true if there was a result.
+func (m *Memoized[K, V]) Invalidate(k K) bool {
+	return m.cache.Remove(k)
+}
+
+// InvalidateAll removes all cached results.
+func (m *Memoized[K, V]) InvalidateAll() {
+	m.cache.InvalidateAll()
+}
+
+// Len returns the number of cached results.
+func (m *Memoized[K, V]) Len() int {
+	return m.cache.Len()
+}
+
+// Close releases the cache, see Cache.Close.
+func (m *Memoized[K, V]) Close() error {
+	return m.cache.Close()
+}
+
+// Tuple2 is the key of a memoized function with two arguments
+type Tuple2[A comparable, B comparable] struct {
+	A A
+	B B
+}
+
+// Memoized2 is a function with two arguments with its results cached by a Cache, see Memoized.
+type Memoized2[A comparable, B comparable, V any] struct {
+	memoized *Memoized[Tuple2[A, B], V]
+}
+
+// Memoize2 caches the results of fn by both arguments, see Memoize.
+func Memoize2[A comparable, B comparable, V any](fn func(a A, b B) (V, error), options ...MemoizeOption) *Memoized2[A, B, V] {
+	return &Memoized2[A, B, V]{
+		memoized: Memoize(func(k Tuple2[A, B]) (V, error) {
+			return fn(k.A, k.B)
+		}, options...),
+	}
+}
+
+// Call returns the cached result for the arguments, or calls the function if there is none.
+func (m *Memoized2[A, B, V]) Call(a A, b B) (V, error) {
+	return m.memoized.Call(Tuple2[A, B]{A: a, B: b})
+}
+
+// Invalidate removes the cached result for the arguments. Returns true if there was a result.
+func (m *Memoized2[A, B, V]) Invalidate(a A, b B) bool {
+	return m.memoized.Invalidate(Tuple2[A, B]{A: a, B: b})
+}
+
+// InvalidateAll removes all cached results.
+func (m *Memoized2[A, B, V]) InvalidateAll() {
+	m.memoized.InvalidateAll()
+}
+
+// Len returns the number of cached results.
+func (m *Memoized2[A, B, V]) Len() int {
+	return m.memoized.Len()
+}
+
+// Close releases the cache, see Cache.Close.
+func (m *Memoized2[A, B, V]) Close() error {
+	return m.memoized.Close()
+}
+
+// Tuple3 is the key of a memoized function with three arguments
+type Tuple3[A comparable, B comparable, C comparable] struct {
+	A A
+	B B
+	C C
+}
+
+// Memoized3 is a function with three arguments with its results cached by a Cache, see Memoized.
+type Memoized3[A comparable, B comparable, C comparable, V any] struct {
+	memoized *Memoized[Tuple3[A, B, C], V]
+}
+
+// Memoize3 caches the results of fn by all three arguments, see Memoize.
+func Memoize3[A comparable, B comparable, C comparable, V any](fn func(a A, b B, c C) (V, error), options ...MemoizeOption) *Memoized3[A, B, C, V] {
+	return &Memoized3[A, B, C, V]{
+		memoized: Memoize(func(k Tuple3[A, B, C]) (V, error) {
+			return fn(k.A, k.B, k.C)
+		}, options...),
+	}
+}
+
+// Call returns the cached result for the arguments, or calls the function if there is none.
+func (m *Memoized3[A, B, C, V]) Call(a A, b B, c C) (V, error) {
+	return m.memoized.Call(Tuple3[A, B, C]{A: a, B: b, C: c})
+}
+
+// Invalidate removes the cached result for the arguments. Returns true if there was a result.
+func (m *Memoized3[A, B, C, V]) Invalidate(a A, b B, c C) bool {
+	return m.memoized.Invalidate(Tuple3[A, B, C]{A: a, B: b, C: c})
+}
+
+// InvalidateAll removes all cached results.
+func (m *Memoized3[A, B, C, V]) InvalidateAll() {
+	m.memoized.InvalidateAll()
+}
+
+// Len returns the number of cached results.
+func (m *Memoized3[A, B, C, V]) Len() int {
+	return m.memoized.Len()
+}
+
+// Close releases the cache, see Cache.Close.
+func (m *Memoized3[A, B, C, V]) Close() error {
+	return m.memoized.Close()
+}
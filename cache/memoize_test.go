@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errNotFound = errors.New("not found")
+
+func TestMemoizedFunctionIsCalledOncePerKey(t *testing.T) {
+	// setup
+	calls := 0
+	square := Memoize(func(k int) (int, error) {
+		calls++
+		return k * k, nil
+	})
+	defer square.Close()
+
+	// execute
+	first, firstErr := square.Call(3)
+	second, secondErr := square.Call(3)
+	other, _ := square.Call(4)
+
+	// verify
+	if firstErr != nil || secondErr != nil || first != 9 || second != 9 || other != 16 {
+		t.Errorf("Expected 9, 9 and 16, got %d, %d and %d", first, second, other)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestMemoizedErrorsAreNotCachedByDefault(t *testing.T) {
+	// setup
+	calls := 0
+	find := Memoize(func(k string) (string, error) {
+		calls++
+		if calls == 1 {
+			return "", errNotFound
+		}
+		return "found " + k, nil
+	})
+	defer find.Close()
+
+	// execute
+	_, firstErr := find.Call("sam")
+	value, err := find.Call("sam")
+
+	// verify
+	if !errors.Is(firstErr, errNotFound) {
+		t.Errorf("Expected the first call to return errNotFound, got %v", firstErr)
+	}
+	if err != nil || value != "found sam" {
+		t.Errorf("Expected the error not to be cached, got '%s' and %v", value, err)
+	}
+}
+
+func TestMemoizedErrorsAreCachedWhenConfigured(t *testing.T) {
+	// setup
+	calls := 0
+	find := Memoize(func(k string) (string, error) {
+		calls++
+		if k == "missing" {
+			return "", errNotFound
+		}
+		return "", errors.New("timeout")
+	}, MemoizeErrors(func(err error) bool {
+		return errors.Is(err, errNotFound)
+	}))
+	defer find.Close()
+
+	// execute
+	find.Call("missing")
+	_, missingErr := find.Call("missing")
+	find.Call("slow")
+	find.Call("slow")
+
+	// verify
+	if !errors.Is(missingErr, errNotFound) {
+		t.Errorf("Expected the cached error, got %v", missingErr)
+	}
+	if calls != 3 {
+		t.Errorf("Expected only errNotFound to be cached, got %d calls", calls)
+	}
+}
+
+func TestMemoizedErrorsThatAreNotCachedAreLoadFailures(t *testing.T) {
+	// setup
+	registry := NewRegistry()
+	failing := false
+	find := Memoize(func(k string) (string, error) {
+		if failing {
+			return "", errors.New("timeout")
+		}
+		return "found " + k, nil
+	}, MemoizeCacheType(Refresh), MemoizeExpiration(time.Millisecond), MemoizeName("find", registry))
+	defer find.Close()
+	find.Call("sam")
+	failing = true
+	time.Sleep(time.Millisecond * 5)
+	registered, _ := registry.Get("find")
+
+	// execute
+	staleValue, staleErr := find.Call("sam")
+	// the refresh fails in the background
+	deadline := time.Now().Add(time.Second * 5)
+	for registered.Stats().LoadFailures == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	refreshFailures := registered.Stats().LoadFailures
+	value, err := find.Call("sam")
+	_, missingErr := find.Call("missing")
+
+	// verify
+	if staleErr != nil || staleValue != "found sam" || err != nil || value != "found sam" {
+		t.Errorf("Expected the previous result to be kept after a failed refresh, got '%s' and %v", value, err)
+	}
+	if missingErr == nil || missingErr.Error() != "timeout" {
+		t.Errorf("Expected the error of the call, got %v", missingErr)
+	}
+	if refreshFailures != 1 {
+		t.Errorf("Expected the failed refresh to be a load failure, got %d", refreshFailures)
+	}
+}
+
+func TestMemoizedFunctionIsCalledAgainAfterInvalidate(t *testing.T) {
+	// setup
+	calls := 0
+	lookup := Memoize2(func(user string, id int) (int, error) {
+		calls++
+		return calls, nil
+	}, MemoizeMaxSize(100))
+	defer lookup.Close()
+	lookup.Call("sam", 1)
+	lookup.Call("sam", 2)
+
+	// execute
+	invalidated := lookup.Invalidate("sam", 1)
+	again, _ := lookup.Call("sam", 1)
+	kept, _ := lookup.Call("sam", 2)
+	lookup.InvalidateAll()
+
+	// verify
+	if !invalidated || again != 3 {
+		t.Errorf("Expected ('sam', 1) to be called again, got %d", again)
+	}
+	if kept != 2 {
+		t.Errorf("Expected ('sam', 2) to be kept, got %d", kept)
+	}
+	if lookup.Len() != 0 {
+		t.Errorf("Expected InvalidateAll to remove all results, got %d", lookup.Len())
+	}
+}
+
+func TestMemoizedFunctionWithThreeArgumentsCanBeCalledConcurrently(t *testing.T) {
+	// setup
+	sum := Memoize3(func(a int, b int, c int) (int, error) {
+		return a + b + c, nil
+	}, MemoizeMaxSize(100))
+	defer sum.Close()
+
+	// execute
+	wait := &sync.WaitGroup{}
+	results := make([]int, 10)
+	for i := range results {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			results[i], _ = sum.Call(i, 2, 3)
+		}(i)
+	}
+	wait.Wait()
+
+	// verify
+	for i, result := range results {
+		if result != i+5 {
+			t.Errorf("Expected %d, got %d", i+5, result)
+		}
+	}
+	if sum.Len() != 10 {
+		t.Errorf("Expected 10 cached results, got %d", sum.Len())
+	}
+}
+
+func TestClosedMemoizedFunctionReturnsErrClosed(t *testing.T) {
+	// setup
+	double := Memoize(func(k int) (int, error) {
+		return k * 2, nil
+	})
+	double.Close()
+
+	// execute
+	_, err := double.Call(1)
+
+	// verify
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
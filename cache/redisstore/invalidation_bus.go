@@ -0,0 +1,74 @@
+package redisstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+type InvalidationBusOptions struct {
+	// Channel is the redis pub/sub channel the invalidations are published on, every cache sharing invalidations needs its own channel.
+	Channel string
+	// OnError is called when a received invalidation could not be decoded.
+	// Defaults to ignoring the invalidation
+	OnError func(err error)
+}
+
+// InvalidationBus is a cache.InvalidationBus using redis pub/sub. Invalidations are encoded with encoding/gob, so keys have to be encodable by gob.
+// Redis pub/sub does not keep messages, an instance that is disconnected misses the invalidations published in the meantime.
+type InvalidationBus[K comparable] struct {
+	client  redis.UniversalClient
+	channel string
+	onError func(err error)
+}
+
+// NewInvalidationBus creates a bus using the redis client, the client is not closed by the bus.
+func NewInvalidationBus[K comparable](client redis.UniversalClient, options InvalidationBusOptions) *InvalidationBus[K] {
+	return &InvalidationBus[K]{
+		client:  client,
+		channel: options.Channel,
+		onError: options.OnError,
+	}
+}
+
+func (b *InvalidationBus[K]) Publish(ctx context.Context, invalidation cache.Invalidation[K]) error {
+	buffer := &bytes.Buffer{}
+	if err := gob.NewEncoder(buffer).Encode(invalidation); err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, buffer.Bytes()).Err()
+}
+
+// Subscribe returns after redis confirmed the subscription, so invalidations published after Subscribe returns are received.
+func (b *InvalidationBus[K]) Subscribe(handler func(invalidation cache.Invalidation[K])) (func(), error) {
+	ctx := context.Background()
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	messages := pubsub.Channel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for message := range messages {
+			invalidation := cache.Invalidation[K]{}
+			if err := gob.NewDecoder(bytes.NewReader([]byte(message.Payload))).Decode(&invalidation); err != nil {
+				if b.onError != nil {
+					b.onError(err)
+				}
+				continue
+			}
+			handler(invalidation)
+		}
+	}()
+
+	return func() {
+		pubsub.Close()
+		<-done
+	}, nil
+}
@@ -0,0 +1,102 @@
+package redisstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestInvalidationsArePublishedThroughRedis(t *testing.T) {
+	// setup
+	server := miniredis.RunT(t)
+	build := func() cache.Cache[string, string] {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() {
+			client.Close()
+		})
+		bus := NewInvalidationBus[string](client, InvalidationBusOptions{Channel: "users"})
+		return cache.NewCacheBuilder[string, string]().
+			SetInvalidationBus(bus).
+			Build(func(k string) (string, error) {
+				return "loaded " + k, nil
+			})
+	}
+	first := build()
+	second := build()
+	defer first.Close()
+	defer second.Close()
+	first.Get("a")
+	second.Get("a")
+
+	// execute
+	first.Remove("a")
+
+	// verify
+	// invalidations are received in the background
+	deadline := time.Now().Add(time.Second * 5)
+	for second.Contains("a") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if second.Contains("a") {
+		t.Errorf("Expected 'a' to be removed from the second instance")
+	}
+	if first.Contains("a") {
+		t.Errorf("Expected 'a' to be removed from the first instance")
+	}
+}
+
+func TestUpdatesAreSharedThroughTheStoreAndTheBus(t *testing.T) {
+	// setup
+	server := miniredis.RunT(t)
+	build := func() cache.Cache[string, string] {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() {
+			client.Close()
+		})
+		return cache.NewCacheBuilder[string, string]().
+			SetRemoteStore(New[string, string](client, Options[string, string]{Prefix: "users:"}), 0).
+			SetInvalidationBus(NewInvalidationBus[string](client, InvalidationBusOptions{Channel: "users"})).
+			Build(func(k string) (string, error) {
+				return "loaded " + k, nil
+			})
+	}
+	first := build()
+	second := build()
+	defer first.Close()
+	defer second.Close()
+	second.Get("a")
+	second.Get("b")
+
+	// execute
+	first.Put("a", "put")
+	first.Replace("a", "replaced")
+	first.Compute("b", func(old string, exists bool) (string, cache.Action) {
+		return "computed", cache.Store
+	})
+	waitUntilRemoved(t, second, "a")
+	waitUntilRemoved(t, second, "b")
+	a, _ := second.Get("a")
+	b, _ := second.Get("b")
+
+	// verify
+	if a != "replaced" {
+		t.Errorf("Expected the second instance to get the replaced value, got '%s'", a)
+	}
+	if b != "computed" {
+		t.Errorf("Expected the second instance to get the computed value, got '%s'", b)
+	}
+}
+
+// waitUntilRemoved waits for the invalidation of the key to be received in the background
+func waitUntilRemoved(t *testing.T, c cache.Cache[string, string], k string) {
+	deadline := time.Now().Add(time.Second * 5)
+	for c.Contains(k) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if c.Contains(k) {
+		t.Fatalf("Expected '%s' to be removed from the second instance", k)
+	}
+}
@@ -0,0 +1,173 @@
+// Package redisstore provides a cache.RemoteStore backed by redis, so several instances of a cache can share loaded values.
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+type Options[K comparable, V any] struct {
+	// Prefix is put in front of every key, so several caches can share a redis database.
+	Prefix string
+	// Codec is used to encode the keys and values stored in redis.
+	// Defaults to cache.GobCodec
+	Codec cache.Codec[K, V]
+	// KeyFunc serializes the keys for the redis keys instead of the Codec, for example cache.DefaultKeyFunc for readable redis keys.
+	// Defaults to Codec.EncodeKey
+	KeyFunc cache.KeyFunc[K]
+}
+
+// Store is a cache.RemoteStore storing values in redis.
+// With a cluster client the batch operations need all keys in one hash slot, which can be done with a hash tag in the prefix, for example "{users}:".
+type Store[K comparable, V any] struct {
+	client  redis.UniversalClient
+	prefix  string
+	codec   cache.Codec[K, V]
+	keyFunc cache.KeyFunc[K]
+}
+
+// New creates a store using the redis client, the client is not closed by the store.
+func New[K comparable, V any](client redis.UniversalClient, options Options[K, V]) *Store[K, V] {
+	codec := options.Codec
+	if codec == nil {
+		codec = cache.GobCodec[K, V]{}
+	}
+	return &Store[K, V]{
+		client:  client,
+		prefix:  options.Prefix,
+		codec:   codec,
+		keyFunc: options.KeyFunc,
+	}
+}
+
+func (s *Store[K, V]) Get(ctx context.Context, k K) (V, bool, error) {
+	var defaultValue V
+	key, err := s.key(k)
+	if err != nil {
+		return defaultValue, false, err
+	}
+
+	data, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return defaultValue, false, nil
+	}
+	if err != nil {
+		return defaultValue, false, err
+	}
+
+	value, err := s.codec.DecodeValue(data)
+	if err != nil {
+		return defaultValue, false, err
+	}
+	return value, true, nil
+}
+
+func (s *Store[K, V]) Set(ctx context.Context, k K, v V, ttl time.Duration) error {
+	key, err := s.key(k)
+	if err != nil {
+		return err
+	}
+	data, err := s.codec.EncodeValue(v)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, key, data, ttl).Err()
+}
+
+func (s *Store[K, V]) Delete(ctx context.Context, k K) error {
+	key, err := s.key(k)
+	if err != nil {
+		return err
+	}
+	return s.client.Del(ctx, key).Err()
+}
+
+func (s *Store[K, V]) GetMany(ctx context.Context, keys []K) (map[K]V, error) {
+	values := make(map[K]V, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	redisKeys, err := s.keys(keys)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.client.MGet(ctx, redisKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range results {
+		// missing keys are returned as nil
+		data, ok := result.(string)
+		if !ok {
+			continue
+		}
+		value, err := s.codec.DecodeValue([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		values[keys[i]] = value
+	}
+	return values, nil
+}
+
+func (s *Store[K, V]) SetMany(ctx context.Context, values map[K]V, ttl time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	pipeline := s.client.Pipeline()
+	for k, v := range values {
+		key, err := s.key(k)
+		if err != nil {
+			return err
+		}
+		data, err := s.codec.EncodeValue(v)
+		if err != nil {
+			return err
+		}
+		pipeline.Set(ctx, key, data, ttl)
+	}
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+func (s *Store[K, V]) DeleteMany(ctx context.Context, keys []K) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	redisKeys, err := s.keys(keys)
+	if err != nil {
+		return err
+	}
+	return s.client.Del(ctx, redisKeys...).Err()
+}
+
+func (s *Store[K, V]) key(k K) (string, error) {
+	if s.keyFunc != nil {
+		return s.prefix + string(s.keyFunc(k)), nil
+	}
+	encoded, err := s.codec.EncodeKey(k)
+	if err != nil {
+		return "", err
+	}
+	return s.prefix + string(encoded), nil
+}
+
+func (s *Store[K, V]) keys(keys []K) ([]string, error) {
+	redisKeys := make([]string, 0, len(keys))
+	for _, k := range keys {
+		key, err := s.key(k)
+		if err != nil {
+			return nil, err
+		}
+		redisKeys = append(redisKeys, key)
+	}
+	return redisKeys, nil
+}
@@ -0,0 +1,147 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type user struct {
+	Name string
+}
+
+func newTestStore(t *testing.T) (*Store[string, *user], *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return New[string, *user](client, Options[string, *user]{Prefix: "users:"}), server
+}
+
+func TestSetAndGet(t *testing.T) {
+	// setup
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+
+	// execute
+	setErr := store.Set(ctx, "sam", &user{Name: "Sam"}, 0)
+	value, found, getErr := store.Get(ctx, "sam")
+	_, missingFound, missingErr := store.Get(ctx, "missing")
+
+	// verify
+	if setErr != nil || getErr != nil || missingErr != nil {
+		t.Fatalf("Expected store operations to succeed")
+	}
+	if !found || value.Name != "Sam" {
+		t.Errorf("Expected 'sam' to be found")
+	}
+	if missingFound {
+		t.Errorf("Expected missing key not to be found")
+	}
+}
+
+func TestValuesExpireAfterTTL(t *testing.T) {
+	// setup
+	store, server := newTestStore(t)
+	ctx := context.Background()
+	store.Set(ctx, "sam", &user{Name: "Sam"}, time.Minute)
+
+	// execute
+	server.FastForward(time.Minute * 2)
+	_, found, _ := store.Get(ctx, "sam")
+
+	// verify
+	if found {
+		t.Errorf("Expected value to be expired")
+	}
+}
+
+func TestBatchOperations(t *testing.T) {
+	// setup
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+
+	// execute
+	setErr := store.SetMany(ctx, map[string]*user{"a": {Name: "A"}, "b": {Name: "B"}}, 0)
+	values, getErr := store.GetMany(ctx, []string{"a", "b", "missing"})
+	deleteErr := store.DeleteMany(ctx, []string{"a", "b"})
+	afterDelete, _ := store.GetMany(ctx, []string{"a", "b"})
+
+	// verify
+	if setErr != nil || getErr != nil || deleteErr != nil {
+		t.Fatalf("Expected batch operations to succeed")
+	}
+	if len(values) != 2 || values["a"].Name != "A" || values["b"].Name != "B" {
+		t.Errorf("Expected 'a' and 'b' to be found")
+	}
+	if len(afterDelete) != 0 {
+		t.Errorf("Expected values to be deleted")
+	}
+}
+
+func TestCachesShareLoadedValuesThroughTheStore(t *testing.T) {
+	// setup
+	store, server := newTestStore(t)
+	loads := 0
+	build := func() cache.Cache[string, *user] {
+		return cache.NewCacheBuilder[string, *user]().
+			SetExpiration(time.Minute).
+			SetRemoteStore(store, 0).
+			Build(func(k string) (*user, error) {
+				loads++
+				return &user{Name: k}, nil
+			})
+	}
+	first := build()
+	second := build()
+
+	// execute
+	first.Get("sam")
+	value, exists := second.Get("sam")
+	ttl := server.TTL("users:" + mustKey(t, store, "sam"))
+	second.Remove("sam")
+	_, remoteFound, _ := store.Get(context.Background(), "sam")
+
+	// verify
+	if !exists || value.Name != "sam" {
+		t.Errorf("Expected 'sam' to be found")
+	}
+	if loads != 1 {
+		t.Errorf("Expected loader to be called once, got %d", loads)
+	}
+	if ttl != time.Minute {
+		t.Errorf("Expected the expiration of the cache to be used as ttl, got %s", ttl)
+	}
+	if remoteFound {
+		t.Errorf("Expected removed key to be deleted from the store")
+	}
+}
+
+func mustKey(t *testing.T, store *Store[string, *user], k string) string {
+	key, err := store.codec.EncodeKey(k)
+	if err != nil {
+		t.Fatalf("Expected key to encode")
+	}
+	return string(key)
+}
+
+func TestKeyFuncIsUsedForRedisKeys(t *testing.T) {
+	// setup
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	store := New[string, *user](client, Options[string, *user]{Prefix: "users:", KeyFunc: cache.DefaultKeyFunc[string]})
+
+	// execute
+	store.Set(context.Background(), "sam", &user{Name: "Sam"}, 0)
+
+	// verify
+	if !server.Exists(`users:"sam"`) {
+		t.Errorf("Expected redis key to be built with the key func, got %v", server.Keys())
+	}
+}
@@ -1,37 +1,64 @@
 package cache
 
-import "time"
+import (
+	"context"
+	"errors"
+	"io"
+)
 
 type refreshingExpiredCache[K comparable, V any] struct {
-	cacheInfo CacheInfo[K, V]
-	cacheData CacheData[K, V]
+	cacheInfo      CacheInfo[K, V]
+	cacheData      CacheData[K, V]
+	circuitBreaker *circuitBreaker
+	executor       Executor
+	// ownsExecutor is true if the executor was created for this cache and has to be shut down with it
+	ownsExecutor  bool
+	lifecycle     *lifecycle
+	snapshotter   *snapshotter
+	storage       *storageTier[K, V]
+	remote        *remoteTier[K, V]
+	invalidations *invalidationTier[K]
+	writer        *cacheWriter[K, V]
+	registration  *registration
+	stats         *statsCounter[K]
+	logger        *cacheLogger[K]
 
 	clock Clock
 }
 
 func (r refreshingExpiredCache[K, V]) Get(k K) (V, bool) {
 	value, exists := r.cacheData.Get(k)
+	if !exists {
+		value, exists = r.getFromStorage(k)
+	}
 	// if the value does not exist we need to load it synchronously and put in cache
 	// this should be the only time this cache will block
 	if !exists {
 		r.cacheMiss(k)
-		value, err := r.cacheInfo.CacheLoader(k)
+		value, err := r.loadCacheValue(k)
 		if err != nil {
-			r.failedToLoadEntry(k)
+			r.failedToLoadEntry(k, err)
 			return value, false
 		}
-		r.cacheData.Put(k, value)
+		r.store(k, value)
 		return value, true
 	} else if r.cacheData.IsExpired(k, r.cacheInfo.Expiration) {
 		r.cacheMiss(k)
-		go func() {
-			value, err := r.cacheInfo.CacheLoader(k)
+		// while the circuit is open the reload will fail fast and the expired value stays in the cache
+		err := r.executor.Execute(func() {
+			r.logger.refreshStarted(k)
+			value, err := r.loadCacheValue(k)
+			r.logger.refreshFinished(k, err)
 			if err != nil {
-				r.failedToLoadEntry(k)
+				r.failedToLoadEntry(k, err)
 				return
 			}
-			r.cacheData.Put(k, value)
-		}()
+			r.store(k, value)
+		})
+		if err != nil {
+			r.logger.refreshRejected(k, err)
+			r.refreshRejected(k)
+		}
 	} else {
 		r.cacheHit(k)
 	}
@@ -39,15 +66,193 @@ func (r refreshingExpiredCache[K, V]) Get(k K) (V, bool) {
 }
 
 func (r refreshingExpiredCache[K, V]) Put(k K, v V) bool {
-	if r.cacheData.GetSize() >= *r.cacheInfo.MaxSize {
-		r.cacheData.RemoveLeastRecentlyAccessed(r.cacheInfo.GetEvictionSize())
+	if err := r.writer.write(k, v); err != nil {
+		return false
 	}
-	return r.cacheData.Put(k, v)
+	stored := r.store(k, v)
+	r.remote.set(k, v)
+	r.invalidations.publish(k)
+	return stored
 }
 
 func (b refreshingExpiredCache[K, V]) Remove(k K) bool {
+	if err := b.writer.delete(k); err != nil {
+		return false
+	}
 	b.cacheRemoved(k)
-	return b.cacheData.Remove(k)
+	b.storage.delete(k)
+	b.remote.delete(k)
+	removed := b.cacheData.Remove(k)
+	if removed {
+		b.stats.removed(1)
+	}
+	b.invalidations.publish(k)
+	return removed
+}
+
+func (r refreshingExpiredCache[K, V]) GetIfPresent(k K) (V, bool) {
+	return r.cacheData.GetIfPresent(k)
+}
+
+func (r refreshingExpiredCache[K, V]) Contains(k K) bool {
+	return r.cacheData.Contains(k)
+}
+
+func (r refreshingExpiredCache[K, V]) Len() int {
+	return r.cacheData.Len()
+}
+
+func (r refreshingExpiredCache[K, V]) Keys() []K {
+	return r.cacheData.Keys()
+}
+
+func (r refreshingExpiredCache[K, V]) Range(f func(k K, v V) bool) {
+	r.cacheData.Range(f)
+}
+
+func (r refreshingExpiredCache[K, V]) RemoveAll(keys []K) int {
+	keys = r.writer.deleteAll(keys)
+	r.remote.delete(keys...)
+	removed := r.removeLocal(keys)
+	r.invalidations.publish(keys...)
+	return removed
+}
+
+func (r refreshingExpiredCache[K, V]) InvalidateAll() {
+	keys := r.cacheData.AllKeys()
+	r.remote.delete(keys...)
+	r.removeLocal(keys)
+	r.storage.clear()
+	r.invalidations.publishAll()
+}
+
+func (r refreshingExpiredCache[K, V]) Clear() {
+	r.cacheData.Clear()
+	r.storage.clear()
+}
+
+func (r refreshingExpiredCache[K, V]) Compute(k K, f func(old V, exists bool) (V, Action)) (V, bool) {
+	removed := false
+	stored := false
+	added := false
+	value, exists := r.cacheData.Compute(k, func(old V, exists bool) (V, Action) {
+		value, action := f(old, exists)
+		removed = exists && action == Delete
+		stored = action == Store
+		added = !exists && action == Store
+		return value, action
+	})
+	// the action is only known after the compute, so room is made after a new entry was added
+	if added {
+		r.evictIfOverMaxSize()
+	}
+	if removed {
+		r.deleted(k)
+	}
+	if stored && exists && !r.updated(k, value) {
+		var defaultValue V
+		return defaultValue, false
+	}
+	return value, exists
+}
+
+func (r refreshingExpiredCache[K, V]) ComputeIfAbsent(k K, f func(k K) (V, bool)) (V, bool) {
+	return r.Compute(k, computeIfAbsent(k, f))
+}
+
+func (r refreshingExpiredCache[K, V]) ComputeIfPresent(k K, f func(k K, old V) (V, Action)) (V, bool) {
+	return r.Compute(k, computeIfPresent(k, f))
+}
+
+func (r refreshingExpiredCache[K, V]) Merge(k K, v V, f func(old V, v V) (V, Action)) (V, bool) {
+	return r.Compute(k, merge(v, f))
+}
+
+func (r refreshingExpiredCache[K, V]) PutIfAbsent(k K, v V) (V, bool) {
+	existing, loaded := r.cacheData.PutIfAbsent(k, v)
+	if !loaded {
+		r.evictIfOverMaxSize()
+	}
+	if !loaded && !r.updated(k, v) {
+		var defaultValue V
+		return defaultValue, true
+	}
+	return existing, loaded
+}
+
+func (r refreshingExpiredCache[K, V]) Replace(k K, v V) (V, bool) {
+	previous, replaced := r.cacheData.Replace(k, v)
+	if replaced && !r.updated(k, v) {
+		return previous, false
+	}
+	return previous, replaced
+}
+
+func (r refreshingExpiredCache[K, V]) CompareAndSwap(k K, old V, new V) bool {
+	if !r.cacheData.CompareAndSwap(k, old, new) {
+		return false
+	}
+	return r.updated(k, new)
+}
+
+func (r refreshingExpiredCache[K, V]) CompareAndDelete(k K, old V) bool {
+	if !r.cacheData.CompareAndDelete(k, old) {
+		return false
+	}
+	r.deleted(k)
+	return true
+}
+
+func (r refreshingExpiredCache[K, V]) Snapshot(w io.Writer) error {
+	return r.cacheData.Snapshot(w)
+}
+
+func (r refreshingExpiredCache[K, V]) Restore(reader io.Reader) error {
+	if err := r.cacheData.Restore(reader); err != nil {
+		return err
+	}
+	// a snapshot of a larger cache can not be restored completely
+	if size := r.cacheData.GetSize(); size > *r.cacheInfo.MaxSize {
+		r.storage.put(r.cacheData.RemoveLeastRecentlyAccessed(size - *r.cacheInfo.MaxSize))
+	}
+	return nil
+}
+
+func (r refreshingExpiredCache[K, V]) Close() error {
+	return r.Shutdown(context.Background())
+}
+
+func (r refreshingExpiredCache[K, V]) Shutdown(ctx context.Context) error {
+	if err := r.lifecycle.close(); err != nil {
+		return err
+	}
+	r.invalidations.close()
+	r.registration.close()
+
+	// queued reloads that start after the cache is closed fail fast with ErrClosed
+	var executorErr error
+	if r.ownsExecutor {
+		executorErr = r.executor.Shutdown(ctx)
+	}
+	waitErr := r.lifecycle.wait(ctx)
+	writerErr := r.writer.close(ctx)
+	snapshotErr := r.snapshotter.stopAndWrite(r.Snapshot)
+
+	for _, k := range r.cacheData.AllKeys() {
+		r.cacheRemoved(k)
+	}
+	r.cacheData.Close()
+
+	if executorErr != nil {
+		return executorErr
+	}
+	if waitErr != nil {
+		return waitErr
+	}
+	if writerErr != nil {
+		return writerErr
+	}
+	return snapshotErr
 }
 
 func (b refreshingExpiredCache[K, V]) cacheRemoved(k K) {
@@ -56,6 +261,93 @@ func (b refreshingExpiredCache[K, V]) cacheRemoved(k K) {
 	}
 }
 
+// invalidate removes the keys invalidated by another instance of the cache, the remote store was already updated by that instance
+func (r refreshingExpiredCache[K, V]) invalidate(invalidation Invalidation[K]) {
+	if invalidation.All {
+		r.removeLocal(r.cacheData.AllKeys())
+		r.storage.clear()
+		return
+	}
+	r.removeLocal(invalidation.Keys)
+}
+
+// updated propagates a value stored by a conditional update. The value is written after the cache was updated because the update depends on the current value,
+// if a write-through write fails the entry is removed from the cache again so the cache never serves a value the system of record does not have
+func (r refreshingExpiredCache[K, V]) updated(k K, v V) bool {
+	if err := r.writer.write(k, v); err != nil {
+		r.cacheRemoved(k)
+		if r.cacheData.Remove(k) {
+			r.stats.removed(1)
+		}
+		r.remote.delete(k)
+		r.invalidations.publish(k)
+		return false
+	}
+	r.remote.set(k, v)
+	r.invalidations.publish(k)
+	return true
+}
+
+// deleted propagates an entry removed by a conditional update, the entry is already removed so a failed delete is only reported to the OnWriteError hook
+func (r refreshingExpiredCache[K, V]) deleted(k K) {
+	r.writer.delete(k)
+	r.cacheRemoved(k)
+	r.stats.removed(1)
+	r.storage.delete(k)
+	r.remote.delete(k)
+	r.invalidations.publish(k)
+}
+
+// removeLocal removes the keys from the cache and its storage
+func (r refreshingExpiredCache[K, V]) removeLocal(keys []K) int {
+	for _, k := range keys {
+		r.cacheRemoved(k)
+	}
+	r.storage.delete(keys...)
+	removed := r.cacheData.RemoveAll(keys)
+	r.stats.removed(removed)
+	return removed
+}
+
+// store puts the value in the cache, making room first if the cache has reached its max size
+func (r refreshingExpiredCache[K, V]) store(k K, v V) bool {
+	r.evictIfFull()
+	return r.cacheData.Put(k, v)
+}
+
+// evictIfFull removes the least recently accessed entries if the cache has reached its max size and moves them to the storage
+func (r refreshingExpiredCache[K, V]) evictIfFull() {
+	if r.cacheData.GetSize() >= *r.cacheInfo.MaxSize {
+		r.evict()
+	}
+}
+
+// evictIfOverMaxSize removes the least recently accessed entries if an entry added without making room first took the cache over its max size
+func (r refreshingExpiredCache[K, V]) evictIfOverMaxSize() {
+	if r.cacheData.GetSize() > *r.cacheInfo.MaxSize {
+		r.evict()
+	}
+}
+
+func (r refreshingExpiredCache[K, V]) evict() {
+	evicted := r.cacheData.RemoveLeastRecentlyAccessed(r.cacheInfo.GetEvictionSize())
+	r.logger.evicted(len(evicted))
+	r.storage.put(evicted)
+}
+
+// getFromStorage moves the entry for the key from the storage back into the cache, the entry keeps its timestamps so it can be expired
+func (r refreshingExpiredCache[K, V]) getFromStorage(k K) (V, bool) {
+	entry, found := r.storage.take(k)
+	if !found {
+		var defaultValue V
+		return defaultValue, false
+	}
+	entry.LastAccessTime = r.clock.Now()
+	r.evictIfFull()
+	r.cacheData.RestoreEntries([]CacheEntry[K, V]{entry})
+	return entry.Value, true
+}
+
 func (r refreshingExpiredCache[K, V]) cacheMiss(k K) {
 	if r.cacheInfo.Hooks.OnCacheMiss != nil {
 		r.cacheInfo.Hooks.OnCacheMiss(k)
@@ -68,17 +360,52 @@ func (r refreshingExpiredCache[K, V]) cacheHit(k K) {
 	}
 }
 
-func (r refreshingExpiredCache[K, V]) failedToLoadEntry(k K) {
-	if r.cacheInfo.Hooks.OnFailedToLoadEntry != nil {
+func (r refreshingExpiredCache[K, V]) refreshRejected(k K) {
+	if r.cacheInfo.Hooks.OnRefreshRejected != nil {
+		r.cacheInfo.Hooks.OnRefreshRejected(k)
+	}
+}
+
+// failedToLoadEntry calls the hook unless the loader can not load values or the cache is closed,
+// a miss of a cache that is only filled with Put and a refresh that starts after the cache was closed are not failures
+func (r refreshingExpiredCache[K, V]) failedToLoadEntry(k K, err error) {
+	if r.cacheInfo.Hooks.OnFailedToLoadEntry != nil && !errors.Is(err, ErrNotLoadable) && !errors.Is(err, ErrClosed) {
 		r.cacheInfo.Hooks.OnFailedToLoadEntry(k)
 	}
 }
 
 func (r refreshingExpiredCache[K, V]) loadCacheValue(k K) (V, error) {
+	if err := r.lifecycle.begin(); err != nil {
+		var defaultValue V
+		return defaultValue, err
+	}
+	defer r.lifecycle.end()
+
+	if value, found := r.remote.get(k); found {
+		return value, nil
+	}
+
+	if err := r.circuitBreaker.allow(); err != nil {
+		r.logger.loadRejected(k, err)
+		var defaultValue V
+		return defaultValue, err
+	}
+
 	startLoad := r.clock.Now()
-	value, err := r.cacheInfo.CacheLoader(k)
+	value, err := callLoader(r.cacheInfo, r.clock, k)
+	if errors.Is(err, ErrNotLoadable) {
+		// nothing was loaded, the miss is neither a load nor a failure
+		r.circuitBreaker.release()
+		return value, err
+	}
+	loadDuration := r.clock.Now().Sub(startLoad)
 	if r.cacheInfo.Hooks.OnCacheLoadDuration != nil {
-		r.cacheInfo.Hooks.OnCacheLoadDuration(k, time.Since(startLoad))
+		r.cacheInfo.Hooks.OnCacheLoadDuration(k, loadDuration)
+	}
+	r.logger.loaded(k, loadDuration, err)
+	r.circuitBreaker.record(err)
+	if err == nil {
+		r.remote.set(k, value)
 	}
 	return value, err
 }
@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRegistry is the registry named caches are registered in when no registry is set on the builder.
+var DefaultRegistry = NewRegistry()
+
+// CacheConfig is the configuration of a registered cache, independent of its key and value types.
+type CacheConfig struct {
+	Name            string
+	MaxSize         int
+	Expiration      time.Duration
+	CacheType       CacheType
+	EvictionPercent int
+	LoadTimeout     time.Duration
+	HedgeDelay      time.Duration
+	// CircuitBreaker is true if the loader is wrapped in a circuit breaker
+	CircuitBreaker bool
+	// Storage is true if the cache has a second tier storage
+	Storage bool
+	// RemoteStore is true if the cache has a remote store
+	RemoteStore bool
+	// Writer is true if the cache has a cache writer
+	Writer bool
+}
+
+// RegisteredCache is a cache in a Registry, independent of its key and value types.
+type RegisteredCache interface {
+	// Name returns the name the cache was registered with.
+	Name() string
+	// Config returns the configuration of the cache.
+	Config() CacheConfig
+	// Size returns the number of entries in the cache, including expired entries that have not been removed yet.
+	Size() int
+	// Stats returns the counters of the cache since it was built.
+	Stats() CacheStats
+	// Cache returns the registered Cache, it can be asserted to its Cache type.
+	Cache() any
+	// Lookup returns the value for the key without loading it, the key is parsed with the key parser of the cache.
+	Lookup(key string) (any, bool, error)
+	// Invalidate removes the key from the cache, the key is parsed with the key parser of the cache.
+	Invalidate(key string) (bool, error)
+	// InvalidateAll removes every entry from the cache.
+	InvalidateAll()
+}
+
+// Registry tracks the named caches that are not closed, so tools like admin handlers and metrics exporters can find them.
+type Registry struct {
+	lock   *sync.RWMutex
+	caches map[string]RegisteredCache
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		lock:   &sync.RWMutex{},
+		caches: make(map[string]RegisteredCache),
+	}
+}
+
+// Get returns the cache registered with the name.
+func (r *Registry) Get(name string) (RegisteredCache, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	registered, exists := r.caches[name]
+	return registered, exists && registered != nil
+}
+
+// List returns the registered caches sorted by name.
+func (r *Registry) List() []RegisteredCache {
+	r.lock.RLock()
+	caches := make([]RegisteredCache, 0, len(r.caches))
+	for _, registered := range r.caches {
+		// names reserved by caches that are still being built are left out
+		if registered != nil {
+			caches = append(caches, registered)
+		}
+	}
+	r.lock.RUnlock()
+
+	sort.Slice(caches, func(i, j int) bool {
+		return caches[i].Name() < caches[j].Name()
+	})
+	return caches
+}
+
+// reserve reserves the name for a cache that is being built, it panics if a cache with the same name is registered, like expvar.Publish
+func (r *Registry) reserve(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, exists := r.caches[name]; exists {
+		panic(fmt.Sprintf("cache: a cache named %q is already registered", name))
+	}
+	r.caches[name] = nil
+}
+
+// register registers the cache under the name it reserved
+func (r *Registry) register(registered RegisteredCache) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.caches[registered.Name()] = registered
+}
+
+func (r *Registry) unregister(registered RegisteredCache) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.caches[registered.Name()] == registered {
+		delete(r.caches, registered.Name())
+	}
+}
+
+// registeredCache - a cache with its configuration, data and counters
+type registeredCache[K comparable, V any] struct {
+	cacheInfo CacheInfo[K, V]
+	cache     Cache[K, V]
+	cacheData CacheData[K, V]
+	counter   *statsCounter[K]
+}
+
+func (r *registeredCache[K, V]) Name() string {
+	return r.cacheInfo.Name
+}
+
+func (r *registeredCache[K, V]) Config() CacheConfig {
+	return CacheConfig{
+		Name:            r.cacheInfo.Name,
+		MaxSize:         *r.cacheInfo.MaxSize,
+		Expiration:      r.cacheInfo.Expiration,
+		CacheType:       r.cacheInfo.CacheType,
+		EvictionPercent: *r.cacheInfo.EvictionPercent,
+		LoadTimeout:     r.cacheInfo.LoadTimeout,
+		HedgeDelay:      r.cacheInfo.HedgeDelay,
+		CircuitBreaker:  r.cacheInfo.CircuitBreaker != nil,
+		Storage:         r.cacheInfo.Storage != nil,
+		RemoteStore:     r.cacheInfo.RemoteStore != nil,
+		Writer:          r.cacheInfo.Writer != nil,
+	}
+}
+
+func (r *registeredCache[K, V]) Size() int {
+	return r.cacheData.GetSize()
+}
+
+func (r *registeredCache[K, V]) Stats() CacheStats {
+	return r.counter.stats()
+}
+
+func (r *registeredCache[K, V]) Cache() any {
+	return r.cache
+}
+
+func (r *registeredCache[K, V]) Lookup(key string) (any, bool, error) {
+	k, err := r.parseKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	value, exists := r.cache.GetIfPresent(k)
+	return value, exists, nil
+}
+
+func (r *registeredCache[K, V]) Invalidate(key string) (bool, error) {
+	k, err := r.parseKey(key)
+	if err != nil {
+		return false, err
+	}
+	return r.cache.Remove(k), nil
+}
+
+func (r *registeredCache[K, V]) InvalidateAll() {
+	r.cache.InvalidateAll()
+}
+
+func (r *registeredCache[K, V]) parseKey(key string) (K, error) {
+	if r.cacheInfo.KeyParser != nil {
+		return r.cacheInfo.KeyParser(key)
+	}
+	return defaultKeyParser[K](key)
+}
+
+// defaultKeyParser parses string keys as they are and other keys like numbers and booleans with fmt.Sscan
+func defaultKeyParser[K comparable](key string) (K, error) {
+	var k K
+	if stringKey, ok := any(&k).(*string); ok {
+		*stringKey = key
+		return k, nil
+	}
+	if _, err := fmt.Sscan(key, &k); err != nil {
+		return k, fmt.Errorf("cache: parsing key %q: %w", key, err)
+	}
+	return k, nil
+}
+
+// registration - the registration of a cache in its registry
+type registration struct {
+	registry   *Registry
+	name       string
+	registered RegisteredCache
+}
+
+func newRegistration[K comparable, V any](cacheInfo CacheInfo[K, V]) *registration {
+	if cacheInfo.Name == "" {
+		return nil
+	}
+	registry := cacheInfo.Registry
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	return &registration{registry: registry, name: cacheInfo.Name}
+}
+
+func (r *registration) reserve() {
+	if r == nil {
+		return
+	}
+	r.registry.reserve(r.name)
+}
+
+func (r *registration) register(registered RegisteredCache) {
+	if r == nil {
+		return
+	}
+	r.registered = registered
+	r.registry.register(registered)
+}
+
+func (r *registration) close() {
+	if r == nil {
+		return
+	}
+	r.registry.unregister(r.registered)
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func buildRegisteredTestCache(registry *Registry, name string) Cache[string, string] {
+	return NewCacheBuilder[string, string]().
+		SetName(name).
+		SetRegistry(registry).
+		SetMaxSize(50).
+		SetExpiration(time.Minute).
+		Build(func(k string) (string, error) {
+			if k == "fail" {
+				return "", errors.New("failed")
+			}
+			return "loaded " + k, nil
+		})
+}
+
+func TestNamedCachesAreRegisteredUntilClosed(t *testing.T) {
+	// setup
+	registry := NewRegistry()
+	users := buildRegisteredTestCache(registry, "users")
+	buildRegisteredTestCache(registry, "accounts")
+
+	// execute
+	namesBeforeClose := []string{}
+	for _, registered := range registry.List() {
+		namesBeforeClose = append(namesBeforeClose, registered.Name())
+	}
+	users.Close()
+	_, registeredAfterClose := registry.Get("users")
+
+	// verify
+	if len(namesBeforeClose) != 2 || namesBeforeClose[0] != "accounts" || namesBeforeClose[1] != "users" {
+		t.Errorf("Expected 'accounts' and 'users' to be registered, got %v", namesBeforeClose)
+	}
+	if registeredAfterClose {
+		t.Errorf("Expected closed cache to be unregistered")
+	}
+}
+
+func TestRegisteredCacheHasConfigSizeAndStats(t *testing.T) {
+	// setup
+	registry := NewRegistry()
+	users := buildRegisteredTestCache(registry, "users")
+	users.Get("a")
+	users.Get("a")
+	users.Get("fail")
+
+	// execute
+	registered, _ := registry.Get("users")
+	config := registered.Config()
+	stats := registered.Stats()
+
+	// verify
+	if config.MaxSize != 50 || config.Expiration != time.Minute || config.CacheType != Blocking {
+		t.Errorf("Expected the configuration of the cache, got %+v", config)
+	}
+	if registered.Size() != 1 {
+		t.Errorf("Expected size 1, got %d", registered.Size())
+	}
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Loads != 2 || stats.LoadFailures != 1 {
+		t.Errorf("Expected 1 hit, 2 misses, 2 loads and 1 failure, got %+v", stats)
+	}
+	if registered.Cache().(Cache[string, string]) != users {
+		t.Errorf("Expected the registered cache to be the built cache")
+	}
+}
+
+func TestRegisteringADuplicateNamePanics(t *testing.T) {
+	// setup
+	registry := NewRegistry()
+	buildRegisteredTestCache(registry, "users")
+	defer func() {
+		// verify
+		if recover() == nil {
+			t.Errorf("Expected building a second cache named 'users' to panic")
+		}
+	}()
+
+	// execute
+	buildRegisteredTestCache(registry, "users")
+}
+
+func TestRegisteringADuplicateNamePanicsBeforeTheCacheIsStarted(t *testing.T) {
+	// setup
+	registry := NewRegistry()
+	bus := NewMemoryInvalidationBus[string]()
+	build := func() Cache[string, string] {
+		return NewCacheBuilder[string, string]().
+			SetName("users").
+			SetRegistry(registry).
+			SetInvalidationBus(bus).
+			Build(func(k string) (string, error) {
+				return "loaded " + k, nil
+			})
+	}
+	build()
+
+	// execute
+	func() {
+		defer func() {
+			recover()
+		}()
+		build()
+	}()
+
+	// verify
+	if len(bus.subscribers) != 1 {
+		t.Errorf("Expected only the registered cache to subscribe to the bus, got %d subscribers", len(bus.subscribers))
+	}
+}
+
+func TestRemovalsCountOnlyRemovedEntries(t *testing.T) {
+	// setup
+	registry := NewRegistry()
+	users := buildRegisteredTestCache(registry, "users")
+	registered, _ := registry.Get("users")
+	users.Get("a")
+	users.Get("b")
+	users.Get("c")
+
+	// execute
+	users.Remove("a")
+	users.Remove("missing")
+	users.RemoveAll([]string{"b", "missing"})
+	users.Close()
+
+	// verify
+	if removals := registered.Stats().Removals; removals != 2 {
+		t.Errorf("Expected 2 removals, got %d", removals)
+	}
+}
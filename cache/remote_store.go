@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// RemoteStore is a store shared by several instances of a cache, for example redis. It is checked when a value has to be loaded before the cache loader is called,
+// and values loaded by the cache loader are written to it. Implementations have to be safe for concurrent use.
+type RemoteStore[K comparable, V any] interface {
+	// Get returns the value stored for the key k. The second return value will be false if there is no value.
+	Get(ctx context.Context, k K) (V, bool, error)
+	// Set stores the value for the key k. The value expires after ttl, a ttl of 0 means the value does not expire.
+	Set(ctx context.Context, k K, v V, ttl time.Duration) error
+	// Delete removes the value for the key k, deleting a key that is not stored is not an error.
+	Delete(ctx context.Context, k K) error
+	// GetMany returns the values stored for the keys, keys without a value are left out of the result.
+	GetMany(ctx context.Context, keys []K) (map[K]V, error)
+	// SetMany stores all values, the values expire after ttl.
+	SetMany(ctx context.Context, values map[K]V, ttl time.Duration) error
+	// DeleteMany removes the values for the keys.
+	DeleteMany(ctx context.Context, keys []K) error
+}
+
+// remoteTier - the remote store of a cache with the ttl, timeout and hooks of the cache
+type remoteTier[K comparable, V any] struct {
+	store   RemoteStore[K, V]
+	ttl     time.Duration
+	timeout time.Duration
+	hooks   CacheHooks[K]
+}
+
+func newRemoteTier[K comparable, V any](cacheInfo CacheInfo[K, V]) *remoteTier[K, V] {
+	if cacheInfo.RemoteStore == nil {
+		return nil
+	}
+	ttl := cacheInfo.RemoteTTL
+	if ttl < 1 {
+		ttl = cacheInfo.Expiration
+	}
+	return &remoteTier[K, V]{
+		store:   cacheInfo.RemoteStore,
+		ttl:     ttl,
+		timeout: cacheInfo.LoadTimeout,
+		hooks:   cacheInfo.Hooks,
+	}
+}
+
+func (t *remoteTier[K, V]) get(k K) (V, bool) {
+	if t == nil {
+		var defaultValue V
+		return defaultValue, false
+	}
+
+	ctx, cancel := timeoutContext(t.timeout)
+	defer cancel()
+	value, found, err := t.store.Get(ctx, k)
+	if err != nil {
+		t.failed(err)
+		return value, false
+	}
+	if found && t.hooks.OnRemoteStoreHit != nil {
+		t.hooks.OnRemoteStoreHit(k)
+	}
+	return value, found
+}
+
+func (t *remoteTier[K, V]) set(k K, v V) {
+	if t == nil {
+		return
+	}
+
+	ctx, cancel := timeoutContext(t.timeout)
+	defer cancel()
+	if err := t.store.Set(ctx, k, v, t.ttl); err != nil {
+		t.failed(err)
+	}
+}
+
+func (t *remoteTier[K, V]) delete(keys ...K) {
+	if t == nil || len(keys) == 0 {
+		return
+	}
+
+	ctx, cancel := timeoutContext(t.timeout)
+	defer cancel()
+	var err error
+	if len(keys) == 1 {
+		err = t.store.Delete(ctx, keys[0])
+	} else {
+		err = t.store.DeleteMany(ctx, keys)
+	}
+	if err != nil {
+		t.failed(err)
+	}
+}
+
+func (t *remoteTier[K, V]) failed(err error) {
+	if t.hooks.OnRemoteStoreError != nil {
+		t.hooks.OnRemoteStoreError(err)
+	}
+}
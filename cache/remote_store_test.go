@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingRemoteStore - a remote store where every call fails
+type failingRemoteStore[K comparable, V any] struct {
+	err error
+}
+
+func (f failingRemoteStore[K, V]) Get(ctx context.Context, k K) (V, bool, error) {
+	var defaultValue V
+	return defaultValue, false, f.err
+}
+
+func (f failingRemoteStore[K, V]) Set(ctx context.Context, k K, v V, ttl time.Duration) error {
+	return f.err
+}
+
+func (f failingRemoteStore[K, V]) Delete(ctx context.Context, k K) error {
+	return f.err
+}
+
+func (f failingRemoteStore[K, V]) GetMany(ctx context.Context, keys []K) (map[K]V, error) {
+	return nil, f.err
+}
+
+func (f failingRemoteStore[K, V]) SetMany(ctx context.Context, values map[K]V, ttl time.Duration) error {
+	return f.err
+}
+
+func (f failingRemoteStore[K, V]) DeleteMany(ctx context.Context, keys []K) error {
+	return f.err
+}
+
+func TestFailingRemoteStoreFallsBackToTheLoader(t *testing.T) {
+	// setup
+	remoteErrors := 0
+	remoteCache := NewCacheBuilder[string, string]().
+		SetRemoteStore(failingRemoteStore[string, string]{err: errors.New("connection refused")}, 0).
+		SetHooks(CacheHooks[string]{
+			OnRemoteStoreError: func(err error) { remoteErrors++ },
+		}).
+		Build(func(k string) (string, error) {
+			return "loaded " + k, nil
+		})
+
+	// execute
+	value, exists := remoteCache.Get("a")
+
+	// verify
+	if !exists || value != "loaded a" {
+		t.Errorf("Expected 'a' to be 'loaded a'")
+	}
+	// one error for the get and one for storing the loaded value
+	if remoteErrors != 2 {
+		t.Errorf("Expected 2 remote store errors, got %d", remoteErrors)
+	}
+}
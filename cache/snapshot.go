@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const snapshotVersion = 1
+
+// maxPreallocatedSnapshotEntries - the entry count of the header is not trusted, larger snapshots grow the entries while they are read
+const maxPreallocatedSnapshotEntries = 1024
+
+// CacheEntry is an entry of a cache with its timestamps, used to move entries in and out of a cache without changing when they expire.
+type CacheEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+	// InsertTime the time the key was initially inserted into the cache
+	InsertTime time.Time
+	// LastAccessTime the time the key was last accessed
+	LastAccessTime time.Time
+	// LastUpdateTime the time the key's value was last updated
+	LastUpdateTime time.Time
+}
+
+type snapshotHeader struct {
+	Version int
+	Entries int
+}
+
+type snapshotRecord struct {
+	Key            []byte
+	Value          []byte
+	InsertTime     time.Time
+	LastAccessTime time.Time
+	LastUpdateTime time.Time
+}
+
+// writeSnapshot writes the entries to w as a gob stream of a header followed by one record per entry
+func writeSnapshot[K comparable, V any](w io.Writer, codec Codec[K, V], entries []CacheEntry[K, V]) error {
+	encoder := gob.NewEncoder(w)
+	if err := encoder.Encode(snapshotHeader{Version: snapshotVersion, Entries: len(entries)}); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		key, err := codec.EncodeKey(entry.Key)
+		if err != nil {
+			return fmt.Errorf("cache: encoding key of snapshot entry: %w", err)
+		}
+		value, err := codec.EncodeValue(entry.Value)
+		if err != nil {
+			return fmt.Errorf("cache: encoding value of snapshot entry: %w", err)
+		}
+		err = encoder.Encode(snapshotRecord{
+			Key:            key,
+			Value:          value,
+			InsertTime:     entry.InsertTime,
+			LastAccessTime: entry.LastAccessTime,
+			LastUpdateTime: entry.LastUpdateTime,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSnapshot[K comparable, V any](r io.Reader, codec Codec[K, V]) ([]CacheEntry[K, V], error) {
+	decoder := gob.NewDecoder(r)
+	header := snapshotHeader{}
+	if err := decoder.Decode(&header); err != nil {
+		return nil, err
+	}
+	if header.Version != snapshotVersion {
+		return nil, fmt.Errorf("cache: unsupported snapshot version %d", header.Version)
+	}
+
+	if header.Entries < 0 {
+		return nil, fmt.Errorf("cache: invalid snapshot entry count %d", header.Entries)
+	}
+
+	entries := make([]CacheEntry[K, V], 0, min(header.Entries, maxPreallocatedSnapshotEntries))
+	for i := 0; i < header.Entries; i++ {
+		record := snapshotRecord{}
+		if err := decoder.Decode(&record); err != nil {
+			return nil, err
+		}
+		key, err := codec.DecodeKey(record.Key)
+		if err != nil {
+			return nil, fmt.Errorf("cache: decoding key of snapshot entry: %w", err)
+		}
+		value, err := codec.DecodeValue(record.Value)
+		if err != nil {
+			return nil, fmt.Errorf("cache: decoding value of snapshot entry: %w", err)
+		}
+		entries = append(entries, CacheEntry[K, V]{
+			Key:            key,
+			Value:          value,
+			InsertTime:     record.InsertTime,
+			LastAccessTime: record.LastAccessTime,
+			LastUpdateTime: record.LastUpdateTime,
+		})
+	}
+	return entries, nil
+}
+
+// snapshotter - writes the snapshot of a cache to a file periodically and when the cache is closed
+type snapshotter struct {
+	path     string
+	interval time.Duration
+	clock    Clock
+	onError  func(err error)
+
+	// writeLock makes sure only one snapshot is written to the file at a time
+	writeLock *sync.Mutex
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+func newSnapshotter(path string, interval time.Duration, clock Clock, onError func(err error)) *snapshotter {
+	if path == "" {
+		return nil
+	}
+	return &snapshotter{
+		path:      path,
+		interval:  interval,
+		clock:     clock,
+		onError:   onError,
+		writeLock: &sync.Mutex{},
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+}
+
+// restore restores the cache from the snapshot file, a missing file is not an error
+func (s *snapshotter) restore(restore func(r io.Reader) error) {
+	if s == nil {
+		return
+	}
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		s.failed(err)
+		return
+	}
+	defer file.Close()
+
+	if err := restore(file); err != nil {
+		s.failed(fmt.Errorf("cache: restoring snapshot %s: %w", s.path, err))
+	}
+}
+
+// start writes a snapshot every interval until stopped, does nothing if there is no interval
+func (s *snapshotter) start(snapshot func(w io.Writer) error) {
+	if s == nil {
+		return
+	}
+	if s.interval < 1 {
+		close(s.stopped)
+		return
+	}
+
+	go func() {
+		defer close(s.stopped)
+		ticker := s.clock.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				s.write(snapshot)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopAndWrite stops the periodic snapshots and writes a final snapshot
+func (s *snapshotter) stopAndWrite(snapshot func(w io.Writer) error) error {
+	if s == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.stopped
+	return s.write(snapshot)
+}
+
+// write writes the snapshot to a temporary file next to the snapshot file and renames it, so a crash never leaves a partial snapshot behind
+func (s *snapshotter) write(snapshot func(w io.Writer) error) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	err := s.writeFile(snapshot)
+	if err != nil {
+		err = fmt.Errorf("cache: writing snapshot %s: %w", s.path, err)
+		s.failed(err)
+	}
+	return err
+}
+
+func (s *snapshotter) writeFile(snapshot func(w io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := snapshot(file); err != nil {
+		file.Close()
+		return err
+	}
+	// the snapshot is on disk before it is renamed, so after a power loss the snapshot file is the previous or the new snapshot and never a truncated one
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), s.path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(s.path))
+	return nil
+}
+
+// syncDir persists the rename of a file in the directory. Not every platform can sync a directory, a failed sync only means the previous snapshot may be read after a power loss
+func syncDir(path string) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	dir.Sync()
+	dir.Close()
+}
+
+func (s *snapshotter) failed(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type snapshotUser struct {
+	Name string
+	Tags []string
+}
+
+func TestRestoringASnapshotKeepsEntryTimestamps(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	source := buildExpiringTestCache(clock)
+	source.Put("old", "old value")
+	clock.Set(time.Unix(1002, 0))
+	source.Put("new", "new value")
+	snapshot := &bytes.Buffer{}
+	if err := source.Snapshot(snapshot); err != nil {
+		t.Fatalf("Expected snapshot to succeed, got %v", err)
+	}
+	restored := buildExpiringTestCache(clock)
+
+	// execute
+	err := restored.Restore(snapshot)
+
+	// verify
+	if err != nil {
+		t.Fatalf("Expected restore to succeed, got %v", err)
+	}
+	if value, exists := restored.GetIfPresent("new"); !exists || value != "new value" {
+		t.Errorf("Expected 'new' to be restored")
+	}
+	if restored.Contains("old") {
+		t.Errorf("Expected 'old' to still be expired after restore")
+	}
+	if value, _ := restored.Get("old"); value != "loaded" {
+		t.Errorf("Expected expired 'old' to be reloaded")
+	}
+}
+
+func TestRestoreDoesNotReplaceMoreRecentlyUpdatedEntries(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	source := buildExpiringTestCache(clock)
+	source.Put("key", "snapshot value")
+	snapshot := &bytes.Buffer{}
+	source.Snapshot(snapshot)
+	clock.Set(time.Unix(1001, 0))
+	restored := buildExpiringTestCache(clock)
+	restored.Put("key", "newer value")
+
+	// execute
+	restored.Restore(snapshot)
+
+	// verify
+	if value, _ := restored.GetIfPresent("key"); value != "newer value" {
+		t.Errorf("Expected 'newer value' to be kept, got '%s'", value)
+	}
+}
+
+func TestSnapshotFileIsWrittenOnCloseAndRestoredOnBuild(t *testing.T) {
+	// setup
+	path := filepath.Join(t.TempDir(), "users.snapshot")
+	loads := 0
+	build := func() Cache[string, *snapshotUser] {
+		return NewCacheBuilder[string, *snapshotUser]().
+			SetSnapshotFile(path, 0).
+			SetHooks(CacheHooks[string]{
+				OnSnapshotError: func(err error) {
+					t.Errorf("Expected no snapshot errors, got %v", err)
+				},
+			}).
+			Build(func(k string) (*snapshotUser, error) {
+				loads++
+				return &snapshotUser{Name: k, Tags: []string{"loaded"}}, nil
+			})
+	}
+	first := build()
+	first.Get("sam")
+	first.Put("empty", nil)
+
+	// execute
+	closeErr := first.Close()
+	second := build()
+	user, exists := second.Get("sam")
+	empty, emptyExists := second.GetIfPresent("empty")
+
+	// verify
+	if closeErr != nil {
+		t.Errorf("Expected close to succeed, got %v", closeErr)
+	}
+	if !exists || user.Name != "sam" || len(user.Tags) != 1 {
+		t.Errorf("Expected 'sam' to be restored")
+	}
+	if !emptyExists || empty != nil {
+		t.Errorf("Expected nil value to be restored")
+	}
+	if loads != 1 {
+		t.Errorf("Expected loader to be called once, got %d", loads)
+	}
+}
+
+func TestGobCodecRoundTrip(t *testing.T) {
+	// setup
+	codec := GobCodec[int, *snapshotUser]{}
+
+	// execute
+	key, keyErr := codec.EncodeKey(42)
+	value, valueErr := codec.EncodeValue(&snapshotUser{Name: "sam"})
+	decodedKey, decodedKeyErr := codec.DecodeKey(key)
+	decodedValue, decodedValueErr := codec.DecodeValue(value)
+
+	// verify
+	if keyErr != nil || valueErr != nil || decodedKeyErr != nil || decodedValueErr != nil {
+		t.Fatalf("Expected codec to succeed")
+	}
+	if decodedKey != 42 {
+		t.Errorf("Expected key to be 42, got %d", decodedKey)
+	}
+	if decodedValue == nil || decodedValue.Name != "sam" {
+		t.Errorf("Expected value name to be 'sam'")
+	}
+}
+
+func TestRestoringASnapshotWithACorruptHeaderFails(t *testing.T) {
+	for _, entries := range []int{-1, math.MaxInt} {
+		// setup
+		snapshot := &bytes.Buffer{}
+		gob.NewEncoder(snapshot).Encode(snapshotHeader{Version: snapshotVersion, Entries: entries})
+		restored := NewCacheBuilder[string, string]().Build(func(k string) (string, error) {
+			return k, nil
+		})
+
+		// execute
+		err := restored.Restore(snapshot)
+
+		// verify
+		if err == nil {
+			t.Errorf("Expected restoring a snapshot with %d entries in the header to fail", entries)
+		}
+		if restored.Len() != 0 {
+			t.Errorf("Expected nothing to be restored, got %d entries", restored.Len())
+		}
+	}
+}
@@ -12,6 +12,7 @@ type node[K any] struct {
 	next  *node[K]
 }
 
+// LinkedSortedList is a SortedList backed by a linked list. Add changes the list, so it has to be used through a pointer.
 type LinkedSortedList[K any] struct {
 	// CompareFunc the function to use to compare the items in the list
 	// if the response is less than 0 the left item is less than the right
@@ -23,7 +24,7 @@ type LinkedSortedList[K any] struct {
 	root *node[K]
 }
 
-func (s LinkedSortedList[K]) Add(k K) {
+func (s *LinkedSortedList[K]) Add(k K) {
 	newNode := &node[K]{value: k}
 
 	// no root then set the root to the new node
@@ -48,7 +49,7 @@ func (s LinkedSortedList[K]) Add(k K) {
 	current.next = newNode
 }
 
-func (s LinkedSortedList[K]) GetAll() []K {
+func (s *LinkedSortedList[K]) GetAll() []K {
 	response := make([]K, 0)
 	current := s.root
 	for current != nil {
@@ -58,7 +59,7 @@ func (s LinkedSortedList[K]) GetAll() []K {
 	return response
 }
 
-func (s LinkedSortedList[K]) GetFirstN(idx int) []K {
+func (s *LinkedSortedList[K]) GetFirstN(idx int) []K {
 	response := make([]K, 0)
 	current := s.root
 	for current != nil && idx > 0 {
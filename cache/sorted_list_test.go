@@ -0,0 +1,32 @@
+package cache
+
+import "testing"
+
+func TestSortedListKeepsItemsInOrder(t *testing.T) {
+	// setup
+	sortedList := &LinkedSortedList[int]{
+		CompareFunc: func(left int, right int) int {
+			return left - right
+		},
+	}
+
+	// execute
+	for _, value := range []int{3, 1, 4, 1, 5} {
+		sortedList.Add(value)
+	}
+
+	// verify
+	all := sortedList.GetAll()
+	expected := []int{1, 1, 3, 4, 5}
+	if len(all) != len(expected) {
+		t.Fatalf("Expected %d items, got %d", len(expected), len(all))
+	}
+	for i := range expected {
+		if all[i] != expected[i] {
+			t.Errorf("Expected item %d to be %d, got %d", i, expected[i], all[i])
+		}
+	}
+	if first := sortedList.GetFirstN(2); len(first) != 2 || first[1] != 1 {
+		t.Errorf("Expected the first 2 items to be 1 and 1")
+	}
+}
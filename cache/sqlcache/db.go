@@ -0,0 +1,243 @@
+// Package sqlcache caches the scanned results of database/sql queries in a cache.Cache.
+//
+// Results are cached by the query text and the arguments, converted like database/sql converts them, so a pointer argument is cached by the value it points to.
+// Queries run through the loading path of the cache, so concurrent queries with the same arguments share one query and its result or error. Queries are tagged with the tables they read, and InvalidateTag removes every cached result
+// of the queries with the tag, so writes can evict the results depending on the tables they change.
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+// minIndexedKeys - the number of keys in the tag index at which keys that are no longer in the cache are dropped for the first time
+const minIndexedKeys = 64
+
+// Querier runs queries and statements, *sql.DB, *sql.Conn and *sql.Tx are queriers.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// DB caches the results of queries run with a querier.
+type DB struct {
+	querier Querier
+	cache   cache.Cache[string, any]
+	loader  *cache.CallLoader[string, any]
+
+	lock *sync.Mutex
+	// tags - the keys of the cached results of every tag
+	tags map[string]map[string]bool
+	// generations - incremented when a tag is invalidated, a result is only cached if its tags were not invalidated while the query ran
+	generations map[string]uint64
+	indexedKeys int
+	// pruneAt is the number of indexed keys at which keys that are no longer in the cache are dropped
+	pruneAt int
+}
+
+// New creates a DB caching the results of queries of querier in a cache built with the configuration of the builder, its expiration is the time to live of results.
+// The cache is always built as a Blocking cache, a background refresh has no query to run and would serve the expired result forever.
+// Close the DB when it is no longer used, the querier is not closed.
+func New(querier Querier, builder cache.CacheBuilder[string, any]) *DB {
+	loader := cache.NewCallLoader[string, any](nil)
+	return &DB{
+		querier:     querier,
+		cache:       builder.SetCacheType(cache.Blocking).Build(loader.Load),
+		loader:      loader,
+		lock:        &sync.Mutex{},
+		tags:        make(map[string]map[string]bool),
+		generations: make(map[string]uint64),
+		pruneAt:     minIndexedKeys,
+	}
+}
+
+// Query returns the rows of the query scanned by scan, from the cache if the query was run with the same arguments before.
+// The result is cached with the tags, usually the tables the query reads. The returned slice is shared with later calls and must not be modified.
+// Concurrent queries with the same arguments share the query run with the context of the first one.
+func Query[T any](ctx context.Context, db *DB, tags []string, scan func(rows *sql.Rows) (T, error), query string, args ...any) ([]T, error) {
+	key := resultKey[T](query, args)
+	generations := db.generationsOf(tags)
+	// loaded is set when the query of this call was run and cached, the load can run on the goroutine of another query
+	loaded := atomic.Bool{}
+	cached, err := db.loader.Get(db.cache, key, func() (any, error) {
+		result, err := scanAll(ctx, db.querier, scan, query, args)
+		loaded.Store(err == nil)
+		return result, err
+	})
+	if loaded.Load() {
+		db.index(key, tags, generations)
+	}
+	if errors.Is(err, cache.ErrNotLoaded) {
+		// the cache could not be used, for example because it is closed
+		return scanAll(ctx, db.querier, scan, query, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result, isResult := cached.([]T)
+	if !isResult {
+		// a result of another type with the same key, the query is run without the cache
+		return scanAll(ctx, db.querier, scan, query, args)
+	}
+	return result, nil
+}
+
+// ExecContext runs the statement and invalidates the tags if it succeeded, usually the tables the statement changes.
+func (d *DB) ExecContext(ctx context.Context, tags []string, query string, args ...any) (sql.Result, error) {
+	result, err := d.querier.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	d.InvalidateTag(tags...)
+	return result, nil
+}
+
+// InvalidateTag removes the cached results of all queries with any of the tags.
+func (d *DB) InvalidateTag(tags ...string) {
+	d.lock.Lock()
+	keys := []string{}
+	for _, tag := range tags {
+		d.generations[tag]++
+		for key := range d.tags[tag] {
+			keys = append(keys, key)
+		}
+		d.indexedKeys -= len(d.tags[tag])
+		delete(d.tags, tag)
+	}
+	d.lock.Unlock()
+	d.cache.RemoveAll(keys)
+}
+
+// Close releases the cache, see cache.Cache.Close.
+func (d *DB) Close() error {
+	return d.cache.Close()
+}
+
+// InvalidateAll removes all cached results.
+func (d *DB) InvalidateAll() {
+	d.lock.Lock()
+	for tag := range d.tags {
+		d.generations[tag]++
+	}
+	d.tags = make(map[string]map[string]bool)
+	d.indexedKeys = 0
+	d.lock.Unlock()
+	d.cache.InvalidateAll()
+}
+
+func (d *DB) generationsOf(tags []string) []uint64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	generations := make([]uint64, len(tags))
+	for i, tag := range tags {
+		generations[i] = d.generations[tag]
+	}
+	return generations
+}
+
+// index adds the key of a loaded result to its tags, or removes the result if one of its tags was invalidated since the generations were read,
+// as the invalidation may have run before the result was put in the cache
+func (d *DB) index(key string, tags []string, generations []uint64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for i, tag := range tags {
+		if d.generations[tag] != generations[i] {
+			d.cache.Remove(key)
+			return
+		}
+	}
+	for _, tag := range tags {
+		keys, exists := d.tags[tag]
+		if !exists {
+			keys = make(map[string]bool)
+			d.tags[tag] = keys
+		}
+		if !keys[key] {
+			keys[key] = true
+			d.indexedKeys++
+		}
+	}
+	// the key is indexed while holding the lock, so an invalidation of the tags can not run between the check and the indexing
+	d.pruneLocked()
+}
+
+// pruneLocked drops the keys of results that were evicted from the cache from the tag index
+func (d *DB) pruneLocked() {
+	if d.indexedKeys <= d.pruneAt {
+		return
+	}
+	for tag, keys := range d.tags {
+		for key := range keys {
+			if !d.cache.Contains(key) {
+				delete(keys, key)
+				d.indexedKeys--
+			}
+		}
+		if len(keys) == 0 {
+			delete(d.tags, tag)
+		}
+	}
+	d.pruneAt = 2*d.indexedKeys + minIndexedKeys
+}
+
+func scanAll[T any](ctx context.Context, querier Querier, scan func(rows *sql.Rows) (T, error), query string, args []any) ([]T, error) {
+	rows, err := querier.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []T{}
+	for rows.Next() {
+		row, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resultKey - the scanned type, the query and the arguments with their types, so "1" and 1 are different arguments
+func resultKey[T any](query string, args []any) string {
+	builder := strings.Builder{}
+	builder.WriteString(typeKey(reflect.TypeOf((*T)(nil)).Elem()))
+	builder.WriteString("\x00")
+	builder.WriteString(query)
+	for _, arg := range args {
+		arg = keyArg(arg)
+		fmt.Fprintf(&builder, "\x00%T:%#v", arg, arg)
+	}
+	return builder.String()
+}
+
+// typeKey - the import path and the name of named types, so types with the same name in packages with the same name have different keys
+func typeKey(t reflect.Type) string {
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// keyArg - the argument converted to the value passed to the driver, so pointers are keyed by the value they point to and not by their address
+func keyArg(arg any) any {
+	if named, isNamed := arg.(sql.NamedArg); isNamed {
+		return sql.NamedArg{Name: named.Name, Value: keyArg(named.Value)}
+	}
+	if value, err := driver.DefaultParameterConverter.ConvertValue(arg); err == nil {
+		return value
+	}
+	return arg
+}
@@ -0,0 +1,266 @@
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	htmltemplate "html/template"
+	"sync"
+	"sync/atomic"
+	"testing"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+type user struct {
+	ID   int64
+	Name string
+}
+
+func scanUser(rows *sql.Rows) (user, error) {
+	u := user{}
+	err := rows.Scan(&u.ID, &u.Name)
+	return u, err
+}
+
+func newTestDB(t *testing.T, users ...string) (*DB, *fakeDatabase) {
+	sqlDB, database := openFakeDatabase(users...)
+	db := New(sqlDB, cache.NewCacheBuilder[string, any]().SetMaxSize(100))
+	t.Cleanup(func() {
+		db.Close()
+		sqlDB.Close()
+	})
+	return db, database
+}
+
+func TestQueryResultsAreCachedByQueryAndArgs(t *testing.T) {
+	// setup
+	db, database := newTestDB(t, "sam", "alex")
+	ctx := context.Background()
+
+	// execute
+	first, firstErr := Query(ctx, db, []string{"users"}, scanUser, "SELECT id, name FROM users WHERE id = ?", 1)
+	second, _ := Query(ctx, db, []string{"users"}, scanUser, "SELECT id, name FROM users WHERE id = ?", 1)
+	other, _ := Query(ctx, db, []string{"users"}, scanUser, "SELECT id, name FROM users WHERE id = ?", 2)
+
+	// verify
+	if firstErr != nil || len(first) != 1 || first[0].Name != "sam" || len(second) != 1 || second[0].Name != "sam" {
+		t.Errorf("Expected 'sam' twice, got %v and %v", first, second)
+	}
+	if len(other) != 1 || other[0].Name != "alex" {
+		t.Errorf("Expected 'alex', got %v", other)
+	}
+	if database.queries.Load() != 2 {
+		t.Errorf("Expected 2 queries, got %d", database.queries.Load())
+	}
+}
+
+func TestInvalidateTagRemovesDependentResults(t *testing.T) {
+	// setup
+	db, database := newTestDB(t, "sam")
+	ctx := context.Background()
+	Query(ctx, db, []string{"users"}, scanUser, "SELECT id, name FROM users")
+	Query(ctx, db, []string{"orders"}, scanUser, "SELECT id, name FROM users WHERE id = ?", 1)
+
+	// execute
+	_, err := db.ExecContext(ctx, []string{"users"}, "INSERT INTO users (name) VALUES (?)", "alex")
+	users, _ := Query(ctx, db, []string{"users"}, scanUser, "SELECT id, name FROM users")
+	Query(ctx, db, []string{"orders"}, scanUser, "SELECT id, name FROM users WHERE id = ?", 1)
+
+	// verify
+	if err != nil {
+		t.Fatalf("Expected insert to succeed, got %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("Expected the insert to be visible, got %v", users)
+	}
+	if database.queries.Load() != 3 {
+		t.Errorf("Expected only the users query to run again, got %d queries", database.queries.Load())
+	}
+}
+
+func TestFailedQueriesAreNotCached(t *testing.T) {
+	// setup
+	db, database := newTestDB(t, "sam")
+	ctx := context.Background()
+
+	// execute
+	_, firstErr := Query(ctx, db, nil, scanUser, "SELECT * FROM missing")
+	_, secondErr := Query(ctx, db, nil, scanUser, "SELECT * FROM missing")
+
+	// verify
+	if firstErr == nil || secondErr == nil {
+		t.Errorf("Expected unsupported queries to fail")
+	}
+	if database.queries.Load() != 2 {
+		t.Errorf("Expected the failure not to be cached, got %d queries", database.queries.Load())
+	}
+}
+
+func TestResultsOfInvalidatedTagsAreNotCachedWhileTheQueryRuns(t *testing.T) {
+	// setup
+	db, database := newTestDB(t, "sam")
+	ctx := context.Background()
+	key := resultKey[user]("SELECT id, name FROM users", nil)
+	generations := db.generationsOf([]string{"users"})
+	db.InvalidateTag("users")
+
+	// execute
+	db.cache.Put(key, []user{{ID: 1, Name: "stale"}})
+	db.index(key, []string{"users"}, generations)
+	users, _ := Query(ctx, db, []string{"users"}, scanUser, "SELECT id, name FROM users")
+
+	// verify
+	if len(users) != 1 || users[0].Name != "sam" || database.queries.Load() != 1 {
+		t.Errorf("Expected the result read before the invalidation not to be cached, got %v", users)
+	}
+}
+
+func TestResultsAreCachedPerScannedType(t *testing.T) {
+	// setup
+	db, _ := newTestDB(t, "sam")
+	ctx := context.Background()
+	Query(ctx, db, nil, scanUser, "SELECT id, name FROM users")
+
+	// execute
+	names, err := Query(ctx, db, nil, func(rows *sql.Rows) (string, error) {
+		var id int64
+		var name string
+		err := rows.Scan(&id, &name)
+		return name, err
+	}, "SELECT id, name FROM users")
+
+	// verify
+	if err != nil || len(names) != 1 || names[0] != "sam" {
+		t.Errorf("Expected the names to be scanned, got %v and %v", names, err)
+	}
+}
+
+func TestEvictedResultsAreDroppedFromTheTagIndex(t *testing.T) {
+	// setup
+	sqlDB, _ := openFakeDatabase("sam")
+	defer sqlDB.Close()
+	db := New(sqlDB, cache.NewCacheBuilder[string, any]().
+		SetMaxSize(10).
+		SetEvictionPercent(50))
+	defer db.Close()
+
+	// execute
+	for id := 0; id < minIndexedKeys*4; id++ {
+		Query(context.Background(), db, []string{"users"}, scanUser, "SELECT id, name FROM users WHERE id = ?", id)
+	}
+
+	// verify
+	if db.indexedKeys > minIndexedKeys*2 {
+		t.Errorf("Expected evicted keys to be pruned, got %d indexed keys", db.indexedKeys)
+	}
+}
+
+func TestPointerArgsAreCachedByTheirValue(t *testing.T) {
+	// setup
+	db, database := newTestDB(t, "sam", "alex")
+	ctx := context.Background()
+	id := 1
+
+	// execute
+	first, _ := Query(ctx, db, nil, scanUser, "SELECT id, name FROM users WHERE id = ?", &id)
+	id = 2
+	second, _ := Query(ctx, db, nil, scanUser, "SELECT id, name FROM users WHERE id = ?", &id)
+	third, _ := Query(ctx, db, nil, scanUser, "SELECT id, name FROM users WHERE id = ?", 2)
+
+	// verify
+	if len(first) != 1 || first[0].Name != "sam" || len(second) != 1 || second[0].Name != "alex" {
+		t.Errorf("Expected 'sam' and then 'alex', got %v and %v", first, second)
+	}
+	if len(third) != 1 || third[0].Name != "alex" || database.queries.Load() != 2 {
+		t.Errorf("Expected the value of the pointer to be cached, got %d queries", database.queries.Load())
+	}
+}
+
+func TestConcurrentQueriesShareOneQuery(t *testing.T) {
+	// setup
+	db, database := newTestDB(t, "sam")
+	release := make(chan struct{})
+	scans := atomic.Int32{}
+	scan := func(rows *sql.Rows) (user, error) {
+		scans.Add(1)
+		<-release
+		return scanUser(rows)
+	}
+
+	// execute
+	wait := &sync.WaitGroup{}
+	results := make([][]user, 10)
+	for i := range results {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			results[i], _ = Query(context.Background(), db, []string{"users"}, scan, "SELECT id, name FROM users")
+		}(i)
+	}
+	for scans.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wait.Wait()
+
+	// verify
+	if database.queries.Load() != 1 {
+		t.Errorf("Expected concurrent queries to share one query, got %d queries", database.queries.Load())
+	}
+	for i, result := range results {
+		if len(result) != 1 || result[0].Name != "sam" {
+			t.Errorf("Expected query %d to get 'sam', got %v", i, result)
+		}
+	}
+}
+
+func TestRefreshBuildersReloadExpiredResults(t *testing.T) {
+	// setup
+	sqlDB, _ := openFakeDatabase("sam")
+	defer sqlDB.Close()
+	clock := cache.NewFakeClock(time.Now())
+	db := New(sqlDB, cache.NewCacheBuilderWithFactory[string, any](cache.CacheTypeCacheFactory[string, any]{Clock: clock}).
+		SetCacheType(cache.Refresh).
+		SetExpiration(time.Minute))
+	defer db.Close()
+	ctx := context.Background()
+	Query(ctx, db, nil, scanUser, "SELECT id, name FROM users")
+	db.ExecContext(ctx, nil, "INSERT INTO users (name) VALUES (?)", "alex")
+
+	// execute
+	clock.Advance(time.Minute * 2)
+	users, _ := Query(ctx, db, nil, scanUser, "SELECT id, name FROM users")
+
+	// verify
+	if len(users) != 2 {
+		t.Errorf("Expected the expired result to be queried again, got %v", users)
+	}
+}
+
+func TestResultKeysIncludeTheImportPathOfTheScannedType(t *testing.T) {
+	// execute
+	textKey := resultKey[texttemplate.Template]("SELECT body FROM templates", nil)
+	htmlKey := resultKey[htmltemplate.Template]("SELECT body FROM templates", nil)
+
+	// verify
+	if textKey == htmlKey {
+		t.Errorf("Expected types with the same name in different packages to have different keys, got %q", textKey)
+	}
+}
+
+func TestResultsOfAnotherTypeAreQueriedAgain(t *testing.T) {
+	// setup
+	db, database := newTestDB(t, "sam")
+	db.cache.Put(resultKey[user]("SELECT id, name FROM users", nil), []string{"not users"})
+
+	// execute
+	users, err := Query(context.Background(), db, nil, scanUser, "SELECT id, name FROM users")
+
+	// verify
+	if err != nil || len(users) != 1 || users[0].Name != "sam" || database.queries.Load() != 1 {
+		t.Errorf("Expected the users to be queried, got %v and %v", users, err)
+	}
+}
@@ -0,0 +1,129 @@
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// fakeDatabase - a users table for the fake driver, it understands:
+//
+//	SELECT id, name FROM users
+//	SELECT id, name FROM users WHERE id = ?
+//	INSERT INTO users (name) VALUES (?)
+type fakeDatabase struct {
+	lock    sync.Mutex
+	users   []string
+	queries atomic.Int32
+}
+
+func openFakeDatabase(users ...string) (*sql.DB, *fakeDatabase) {
+	database := &fakeDatabase{users: users}
+	return sql.OpenDB(fakeConnector{database: database}), database
+}
+
+type fakeConnector struct {
+	database *fakeDatabase
+}
+
+func (c fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return fakeConn{database: c.database}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("fake driver: use the connector")
+}
+
+type fakeConn struct {
+	database *fakeDatabase
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{database: c.database, query: query}, nil
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fake driver: transactions are not supported")
+}
+
+type fakeStmt struct {
+	database *fakeDatabase
+	query    string
+}
+
+func (s fakeStmt) Close() error {
+	return nil
+}
+
+func (s fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.query != "INSERT INTO users (name) VALUES (?)" || len(args) != 1 {
+		return nil, fmt.Errorf("fake driver: unsupported statement %q", s.query)
+	}
+	s.database.lock.Lock()
+	defer s.database.lock.Unlock()
+	s.database.users = append(s.database.users, args[0].(string))
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.database.queries.Add(1)
+	s.database.lock.Lock()
+	defer s.database.lock.Unlock()
+	rows := &fakeRows{}
+	switch {
+	case s.query == "SELECT id, name FROM users":
+		for i, name := range s.database.users {
+			rows.values = append(rows.values, []driver.Value{int64(i + 1), name})
+		}
+	case strings.HasPrefix(s.query, "SELECT id, name FROM users WHERE id = ?") && len(args) == 1:
+		id := args[0].(int64)
+		if id > 0 && int(id) <= len(s.database.users) {
+			rows.values = append(rows.values, []driver.Value{id, s.database.users[id-1]})
+		}
+	default:
+		return nil, fmt.Errorf("fake driver: unsupported query %q", s.query)
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	values [][]driver.Value
+	next   int
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id", "name"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats are the counters of a cache since it was built.
+type CacheStats struct {
+	// Hits is the number of gets that found a value that had not expired
+	Hits uint64
+	// Misses is the number of gets that had to load a value
+	Misses uint64
+	// Loads is the number of calls to the cache loader
+	Loads uint64
+	// LoadFailures is the number of loads that failed
+	LoadFailures uint64
+	// TotalLoadTime is the time spent in the cache loader
+	TotalLoadTime time.Duration
+	// Removals is the number of entries removed from the cache by removes, compute functions and invalidations. Evicted entries and the entries
+	// released when the cache is closed are not counted
+	Removals uint64
+}
+
+// HitRate returns the ratio of hits to all gets, 0 if there were no gets.
+func (s CacheStats) HitRate() float64 {
+	requests := s.Hits + s.Misses
+	if requests == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(requests)
+}
+
+// statsCounter - counts the events of a cache by wrapping its hooks
+type statsCounter[K comparable] struct {
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	loads         atomic.Uint64
+	loadFailures  atomic.Uint64
+	totalLoadTime atomic.Int64
+	removals      atomic.Uint64
+}
+
+// wrap returns hooks that count the events and then call the hooks
+func (s *statsCounter[K]) wrap(hooks CacheHooks[K]) CacheHooks[K] {
+	onCacheHit := hooks.OnCacheHit
+	hooks.OnCacheHit = func(k K) {
+		s.hits.Add(1)
+		if onCacheHit != nil {
+			onCacheHit(k)
+		}
+	}
+	onCacheMiss := hooks.OnCacheMiss
+	hooks.OnCacheMiss = func(k K) {
+		s.misses.Add(1)
+		if onCacheMiss != nil {
+			onCacheMiss(k)
+		}
+	}
+	onCacheLoadDuration := hooks.OnCacheLoadDuration
+	hooks.OnCacheLoadDuration = func(k K, duration time.Duration) {
+		s.loads.Add(1)
+		s.totalLoadTime.Add(int64(duration))
+		if onCacheLoadDuration != nil {
+			onCacheLoadDuration(k, duration)
+		}
+	}
+	onFailedToLoadEntry := hooks.OnFailedToLoadEntry
+	hooks.OnFailedToLoadEntry = func(k K) {
+		s.loadFailures.Add(1)
+		if onFailedToLoadEntry != nil {
+			onFailedToLoadEntry(k)
+		}
+	}
+	return hooks
+}
+
+// removed counts removed entries, the OnCacheRemove hook is also called for keys that are not in the cache so the caches count their removals themselves
+func (s *statsCounter[K]) removed(n int) {
+	if s == nil {
+		return
+	}
+	s.removals.Add(uint64(n))
+}
+
+func (s *statsCounter[K]) stats() CacheStats {
+	return CacheStats{
+		Hits:          s.hits.Load(),
+		Misses:        s.misses.Load(),
+		Loads:         s.loads.Load(),
+		LoadFailures:  s.loadFailures.Load(),
+		TotalLoadTime: time.Duration(s.totalLoadTime.Load()),
+		Removals:      s.removals.Load(),
+	}
+}
@@ -0,0 +1,88 @@
+package cache
+
+// Storage is a second tier for a cache. Entries evicted from the cache are put in the storage, and the storage is checked on a miss before the cache loader is called.
+// Entries found in the storage are moved back into the cache. Implementations have to be safe for concurrent use.
+type Storage[K comparable, V any] interface {
+	// Get returns the entry stored for the key k. The second return value will be false if there is no entry or the entry has expired in the storage.
+	Get(k K) (CacheEntry[K, V], bool, error)
+	// Put stores the entries, replacing the existing entries for the same keys.
+	Put(entries []CacheEntry[K, V]) error
+	// Delete removes the entry for the key k, deleting a key that is not stored is not an error.
+	Delete(k K) error
+	// Clear removes all entries.
+	Clear() error
+}
+
+// storageTier - the storage of a cache with the hooks of the cache
+type storageTier[K comparable, V any] struct {
+	storage Storage[K, V]
+	hooks   CacheHooks[K]
+}
+
+func newStorageTier[K comparable, V any](cacheInfo CacheInfo[K, V]) *storageTier[K, V] {
+	if cacheInfo.Storage == nil {
+		return nil
+	}
+	return &storageTier[K, V]{
+		storage: cacheInfo.Storage,
+		hooks:   cacheInfo.Hooks,
+	}
+}
+
+// take removes the entry for the key from the storage and returns it, the entry can be expired for the cache
+func (s *storageTier[K, V]) take(k K) (CacheEntry[K, V], bool) {
+	if s == nil {
+		return CacheEntry[K, V]{}, false
+	}
+
+	entry, found, err := s.storage.Get(k)
+	if err != nil {
+		s.failed(err)
+		return entry, false
+	}
+	if !found {
+		return entry, false
+	}
+
+	// the entry moves back into the cache, when it is evicted again it is put back in the storage
+	s.delete(k)
+	if s.hooks.OnStorageHit != nil {
+		s.hooks.OnStorageHit(k)
+	}
+	return entry, true
+}
+
+func (s *storageTier[K, V]) put(entries []CacheEntry[K, V]) {
+	if s == nil || len(entries) == 0 {
+		return
+	}
+	if err := s.storage.Put(entries); err != nil {
+		s.failed(err)
+	}
+}
+
+func (s *storageTier[K, V]) delete(keys ...K) {
+	if s == nil {
+		return
+	}
+	for _, k := range keys {
+		if err := s.storage.Delete(k); err != nil {
+			s.failed(err)
+		}
+	}
+}
+
+func (s *storageTier[K, V]) clear() {
+	if s == nil {
+		return
+	}
+	if err := s.storage.Clear(); err != nil {
+		s.failed(err)
+	}
+}
+
+func (s *storageTier[K, V]) failed(err error) {
+	if s.hooks.OnStorageError != nil {
+		s.hooks.OnStorageError(err)
+	}
+}
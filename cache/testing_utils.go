@@ -4,6 +4,9 @@ import (
 	"time"
 )
 
+// TestCacheLoader returns the given values in round-robin order and records the requested keys.
+//
+// Deprecated: TestCacheLoader is not safe for concurrent use, use cachetest.FakeLoader instead.
 type TestCacheLoader[K comparable, V any] struct {
 	ReturnValues   []V
 	KeysRequests   []K
@@ -42,7 +45,11 @@ func BuildTestCacheByTypeAndExpirationMillis[K comparable, V any](cacheType Cach
 		Build(loader)
 }
 
+// TestClock returns the given times in round-robin order, timers use the local clock.
+//
+// Deprecated: tests using TestClock depend on how often Now is called, use FakeClock instead.
 type TestClock struct {
+	LocalClock
 	times []time.Time
 	idx   int
 }
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrLoadTimeout is returned from the loading path when the loader did not return within the load timeout.
+var ErrLoadTimeout = errors.New("cache: load timed out")
+
+type loadResult[V any] struct {
+	value V
+	err   error
+}
+
+// callLoader calls the cache loader for the key applying the load timeout and hedge delay of the cache info.
+// When a timeout or hedge delay is set the loader is called on its own goroutine, a call that is abandoned keeps running until the loader returns but its result is dropped.
+func callLoader[K comparable, V any](cacheInfo CacheInfo[K, V], clock Clock, k K) (V, error) {
+	if cacheInfo.LoadTimeout < 1 && cacheInfo.HedgeDelay < 1 {
+		return cacheInfo.CacheLoader(k)
+	}
+
+	// buffered for every call we can start, so abandoned calls never block
+	results := make(chan loadResult[V], 2)
+	startCall := func() {
+		go func() {
+			value, err := cacheInfo.CacheLoader(k)
+			results <- loadResult[V]{value: value, err: err}
+		}()
+	}
+
+	var timeout <-chan time.Time
+	if cacheInfo.LoadTimeout > 0 {
+		timer := clock.NewTimer(cacheInfo.LoadTimeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	var hedge <-chan time.Time
+	if cacheInfo.HedgeDelay > 0 {
+		timer := clock.NewTimer(cacheInfo.HedgeDelay)
+		defer timer.Stop()
+		hedge = timer.C()
+	}
+
+	startCall()
+	calls := 1
+	received := 0
+	for {
+		select {
+		case result := <-results:
+			received++
+			// a failed call only wins if there is no other call still running
+			if result.err == nil || received == calls {
+				return result.value, result.err
+			}
+		case <-hedge:
+			hedge = nil
+			startCall()
+			calls++
+		case <-timeout:
+			var defaultValue V
+			return defaultValue, ErrLoadTimeout
+		}
+	}
+}
+
+// canServeExpired returns true if an expired value can be returned in place of a value that failed to load with err
+func canServeExpired[K comparable, V any](cacheInfo CacheInfo[K, V], err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	return cacheInfo.ServeExpiredOnLoadTimeout && errors.Is(err, ErrLoadTimeout)
+}
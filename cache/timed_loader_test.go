@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// whileAdvancing calls f and advances the clock by d once f waits for a timer
+func whileAdvancing(clock *FakeClock, d time.Duration, f func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	clock.WaitForTimers(1)
+	clock.Advance(d)
+	<-done
+}
+
+func TestWhenLoaderIsSlowerThanTimeoutLoadFailsWithTimeout(t *testing.T) {
+	// setup
+	release := make(chan struct{})
+	defer close(release)
+	cacheInfo := CacheInfo[string, string]{
+		LoadTimeout: time.Millisecond * 10,
+		CacheLoader: func(k string) (string, error) {
+			<-release
+			return "value", nil
+		},
+	}
+
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	// execute
+	var err error
+	whileAdvancing(clock, time.Millisecond*10, func() {
+		_, err = callLoader(cacheInfo, clock, "key")
+	})
+
+	// verify
+	if !errors.Is(err, ErrLoadTimeout) {
+		t.Errorf("Expected load to time out")
+	}
+}
+
+func TestWhenHedgeDelayPassesTheFirstSuccessfulCallIsUsed(t *testing.T) {
+	// setup
+	release := make(chan struct{})
+	defer close(release)
+	var calls int32
+	cacheInfo := CacheInfo[string, string]{
+		HedgeDelay: time.Millisecond * 5,
+		CacheLoader: func(k string) (string, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				<-release
+				return "slow", nil
+			}
+			return "hedged", nil
+		},
+	}
+
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	// execute
+	var value string
+	var err error
+	whileAdvancing(clock, time.Millisecond*5, func() {
+		value, err = callLoader(cacheInfo, clock, "key")
+	})
+
+	// verify
+	if err != nil {
+		t.Errorf("Expected load to succeed")
+	}
+	if value != "hedged" {
+		t.Errorf("Expected value to be 'hedged', got '%s'", value)
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected loader to be called twice")
+	}
+}
+
+func TestWhenLoaderFailsBeforeHedgeDelayErrorIsReturned(t *testing.T) {
+	// setup
+	var calls int32
+	cacheInfo := CacheInfo[string, string]{
+		HedgeDelay: time.Second,
+		CacheLoader: func(k string) (string, error) {
+			atomic.AddInt32(&calls, 1)
+			return "", errors.New("backend down")
+		},
+	}
+
+	// execute
+	_, err := callLoader(cacheInfo, NewFakeClock(time.Unix(1000, 0)), "key")
+
+	// verify
+	if err == nil {
+		t.Errorf("Expected load to fail")
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected loader to be called once")
+	}
+}
+
+func TestWhenReloadTimesOutBlockingCacheServesExpiredValueIfConfigured(t *testing.T) {
+	// setup
+	clock := NewFakeClock(time.Unix(1000, 0))
+	release := make(chan struct{})
+	defer close(release)
+	slow := false
+	timeoutCache := NewCacheBuilderWithFactory[string, string](CacheTypeCacheFactory[string, string]{
+		Clock: clock,
+	}).
+		SetExpiration(time.Second).
+		SetLoadTimeout(time.Millisecond * 10).
+		SetServeExpiredOnLoadTimeout(true).
+		Build(func(k string) (string, error) {
+			if slow {
+				<-release
+			}
+			return "value", nil
+		})
+	timeoutCache.Get("key")
+	slow = true
+	clock.Set(time.Unix(1002, 0))
+
+	// execute
+	var value string
+	var exists, missingExists bool
+	whileAdvancing(clock, time.Millisecond*10, func() {
+		value, exists = timeoutCache.Get("key")
+	})
+	whileAdvancing(clock, time.Millisecond*10, func() {
+		_, missingExists = timeoutCache.Get("missing")
+	})
+
+	// verify
+	if !exists || value != "value" {
+		t.Errorf("Expected expired value to be served")
+	}
+	if missingExists {
+		t.Errorf("Expected missing key to fail")
+	}
+}
@@ -1,5 +1,31 @@
 package cache
 
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
 func PointerTo[T any](v T) *T {
 	return &v
 }
+
+// defaultValueEqual compares values with == when their type is comparable, and falls back to reflect.DeepEqual for values like slices and maps that can not be compared with ==
+func defaultValueEqual[V any](a V, b V) bool {
+	left, right := any(a), any(b)
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if reflect.TypeOf(left).Comparable() && reflect.TypeOf(right).Comparable() {
+		return left == right
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// timeoutContext uses the load timeout of a cache for calls to remote systems, so a slow remote system does not take longer than the loader is allowed to
+func timeoutContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
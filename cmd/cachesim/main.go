@@ -0,0 +1,200 @@
+// Command cachesim replays key access traces through caches built by cache.CacheBuilder and reports the hit rate, evictions and load cost
+// of every configuration in a sweep, to help picking the max size, eviction percent, expiration and cache type.
+//
+// Usage:
+//
+//	cachesim [flags] [trace file]
+//
+// The trace is read from standard input if no file is given. Supported formats are:
+//
+//	keys  one key per line
+//	csv   a timestamp and a key per row, timestamps are RFC 3339 or unix seconds
+//	arc   the traces of the ARC paper, "start_block block_count ignored request_number" per line
+//	lirs  the traces of the LIRS paper, one block number per line
+//
+// Every combination of the comma separated values of -sizes, -eviction-percents, -expirations and -types is simulated.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "cachesim:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("cachesim", flag.ContinueOnError)
+	format := flags.String("format", "", "trace format: keys, csv, arc or lirs - default from the file extension, keys for standard input")
+	sizes := flags.String("sizes", "100,1000,10000", "comma separated max sizes")
+	evictionPercents := flags.String("eviction-percents", "10", "comma separated eviction percents")
+	expirations := flags.String("expirations", "0", "comma separated expirations, 0 means entries do not expire")
+	types := flags.String("types", "blocking", "comma separated cache types: blocking or refresh")
+	interval := flags.Duration("interval", time.Millisecond, "time between accesses of traces without timestamps")
+	loadCost := flags.Duration("load-cost", time.Millisecond*10, "simulated duration of a load")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("expected at most one trace file, got %d", flags.NArg())
+	}
+
+	configs, err := sweep(*sizes, *evictionPercents, *expirations, *types)
+	if err != nil {
+		return err
+	}
+
+	reader := stdin
+	selectedFormat := formatKeys
+	if flags.NArg() == 1 {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+		selectedFormat = formatFromPath(flags.Arg(0))
+	}
+	if *format != "" {
+		selectedFormat = traceFormat(*format)
+	}
+	trace, err := readTrace(reader, selectedFormat)
+	if err != nil {
+		return fmt.Errorf("reading %s trace: %w", selectedFormat, err)
+	}
+
+	results := make([]simulationResult, 0, len(configs))
+	for _, config := range configs {
+		results = append(results, simulate(trace, config, *interval, *loadCost))
+	}
+	return writeResults(stdout, results)
+}
+
+// sweep returns every combination of the comma separated values
+func sweep(sizes string, evictionPercents string, expirations string, types string) ([]simulationConfig, error) {
+	sizeValues, err := parseList(sizes, parsePositiveInt)
+	if err != nil {
+		return nil, fmt.Errorf("-sizes: %w", err)
+	}
+	percentValues, err := parseList(evictionPercents, parsePercent)
+	if err != nil {
+		return nil, fmt.Errorf("-eviction-percents: %w", err)
+	}
+	expirationValues, err := parseList(expirations, parseExpiration)
+	if err != nil {
+		return nil, fmt.Errorf("-expirations: %w", err)
+	}
+	typeValues, err := parseList(types, parseCacheType)
+	if err != nil {
+		return nil, fmt.Errorf("-types: %w", err)
+	}
+
+	var configs []simulationConfig
+	for _, cacheType := range typeValues {
+		for _, expiration := range expirationValues {
+			for _, size := range sizeValues {
+				for _, percent := range percentValues {
+					configs = append(configs, simulationConfig{
+						cacheType:       cacheType,
+						maxSize:         size,
+						evictionPercent: percent,
+						expiration:      expiration,
+					})
+				}
+			}
+		}
+	}
+	return configs, nil
+}
+
+func parseList[T any](list string, parse func(value string) (T, error)) ([]T, error) {
+	var values []T
+	for _, value := range strings.Split(list, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		parsed, err := parse(value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, parsed)
+	}
+	if len(values) == 0 {
+		return nil, errors.New("expected at least one value")
+	}
+	return values, nil
+}
+
+func parsePositiveInt(value string) (int, error) {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if parsed < 1 {
+		return 0, fmt.Errorf("expected a positive number, got %d", parsed)
+	}
+	return parsed, nil
+}
+
+func parsePercent(value string) (int, error) {
+	parsed, err := parsePositiveInt(value)
+	if err != nil {
+		return 0, err
+	}
+	if parsed > 100 {
+		return 0, fmt.Errorf("expected a percent of at most 100, got %d", parsed)
+	}
+	return parsed, nil
+}
+
+func parseExpiration(value string) (time.Duration, error) {
+	if value == "0" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func parseCacheType(value string) (cache.CacheType, error) {
+	for _, cacheType := range []cache.CacheType{cache.Blocking, cache.Refresh} {
+		if cacheType.String() == value {
+			return cacheType, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown cache type %q", value)
+}
+
+func writeResults(writer io.Writer, results []simulationResult) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "type\tmax size\tevict %\texpiration\trequests\thits\thit rate\tloads\tevictions\tload cost\t")
+	for _, result := range results {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%s\t%d\t%d\t%.2f%%\t%d\t%d\t%s\t\n",
+			result.config.cacheType,
+			result.config.maxSize,
+			result.config.evictionPercent,
+			result.config.expiration,
+			result.requests,
+			result.hits,
+			result.hitRate()*100,
+			result.loads,
+			result.evictions,
+			result.loadCost,
+		)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+// simulationStart - the time of the first access of traces without timestamps
+var simulationStart = time.Unix(0, 0)
+
+type simulationConfig struct {
+	cacheType       cache.CacheType
+	maxSize         int
+	evictionPercent int
+	expiration      time.Duration
+}
+
+type simulationResult struct {
+	config    simulationConfig
+	requests  int
+	hits      int
+	loads     int
+	evictions int
+	// loadCost - the simulated time spent in the loader
+	loadCost time.Duration
+}
+
+func (r simulationResult) hitRate() float64 {
+	if r.requests == 0 {
+		return 0
+	}
+	return float64(r.hits) / float64(r.requests)
+}
+
+// simulate replays the trace through a cache built with the config. Accesses without a timestamp are interval apart, every load costs loadCost.
+// The cache runs on a fake clock and refreshes run before Get returns, so the result only depends on the trace and the config.
+func simulate(trace []access, config simulationConfig, interval time.Duration, loadCost time.Duration) simulationResult {
+	result := simulationResult{config: config}
+	clock := cache.NewFakeClock(simulationStart)
+	evictions := &evictionCounter{}
+	simulated := cache.NewCacheBuilderWithFactory[string, string](cache.CacheTypeCacheFactory[string, string]{
+		Clock: clock,
+	}).
+		SetCacheType(config.cacheType).
+		SetMaxSize(config.maxSize).
+		SetEvictionPercent(config.evictionPercent).
+		SetExpiration(config.expiration).
+		SetExecutor(inlineExecutor{}).
+		SetStorage(evictions).
+		SetHooks(cache.CacheHooks[string]{
+			OnCacheHit: func(k string) {
+				result.hits++
+			},
+		}).
+		Build(func(k string) (string, error) {
+			result.loads++
+			return k, nil
+		})
+	defer simulated.Close()
+
+	for i, access := range trace {
+		now := access.time
+		if now.IsZero() {
+			now = simulationStart.Add(interval * time.Duration(i))
+		}
+		if now.After(clock.Now()) {
+			clock.Set(now)
+		}
+		simulated.Get(access.key)
+		result.requests++
+	}
+	result.evictions = evictions.evicted
+	result.loadCost = loadCost * time.Duration(result.loads)
+	return result
+}
+
+// evictionCounter - a storage that counts the entries evicted from the cache and drops them
+type evictionCounter struct {
+	evicted int
+}
+
+func (e *evictionCounter) Get(k string) (cache.CacheEntry[string, string], bool, error) {
+	return cache.CacheEntry[string, string]{}, false, nil
+}
+
+func (e *evictionCounter) Put(entries []cache.CacheEntry[string, string]) error {
+	e.evicted += len(entries)
+	return nil
+}
+
+func (e *evictionCounter) Delete(k string) error {
+	return nil
+}
+
+func (e *evictionCounter) Clear() error {
+	return nil
+}
+
+// inlineExecutor - runs refreshes before the get that started them returns
+type inlineExecutor struct{}
+
+func (inlineExecutor) Execute(task func()) error {
+	task()
+	return nil
+}
+
+func (inlineExecutor) Shutdown(ctx context.Context) error {
+	return nil
+}
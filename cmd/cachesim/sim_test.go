@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SamOrozco/go_loading_cache/cache"
+)
+
+func traceOf(keys ...string) []access {
+	trace := make([]access, 0, len(keys))
+	for _, k := range keys {
+		trace = append(trace, access{key: k})
+	}
+	return trace
+}
+
+func TestSimulationCountsHitsLoadsAndEvictions(t *testing.T) {
+	// setup
+	config := simulationConfig{cacheType: cache.Blocking, maxSize: 2, evictionPercent: 50}
+
+	// execute
+	result := simulate(traceOf("a", "b", "a", "c", "a", "b"), config, time.Millisecond, time.Millisecond*10)
+
+	// verify
+	if result.requests != 6 || result.hits != 2 || result.loads != 4 {
+		t.Errorf("Expected 6 requests, 2 hits and 4 loads, got %d, %d and %d", result.requests, result.hits, result.loads)
+	}
+	if result.evictions != 2 {
+		t.Errorf("Expected 2 evictions, got %d", result.evictions)
+	}
+	if result.loadCost != time.Millisecond*40 {
+		t.Errorf("Expected a load cost of 40ms, got %s", result.loadCost)
+	}
+}
+
+func TestSimulationOfACacheSmallerThanTheEvictionPercentEvicts(t *testing.T) {
+	// setup
+	config := simulationConfig{cacheType: cache.Blocking, maxSize: 5, evictionPercent: 10}
+
+	// execute
+	result := simulate(traceOf("a", "b", "c", "d", "a", "b", "c", "d", "e", "f"), config, time.Millisecond, 0)
+
+	// verify
+	if result.hits != 4 || result.loads != 6 || result.hitRate() != 0.4 {
+		t.Errorf("Expected 4 hits, 6 loads and a hit rate of 0.4, got %d, %d and %f", result.hits, result.loads, result.hitRate())
+	}
+	if result.evictions != 1 {
+		t.Errorf("Expected 1 eviction once the cache is full, got %d", result.evictions)
+	}
+}
+
+func TestSimulationExpiresEntriesOnTraceTime(t *testing.T) {
+	// setup
+	start := time.Unix(1000, 0)
+	trace := []access{
+		{key: "a", time: start},
+		{key: "a", time: start.Add(time.Second)},
+		{key: "a", time: start.Add(time.Minute)},
+	}
+
+	for _, cacheType := range []cache.CacheType{cache.Blocking, cache.Refresh} {
+		// execute
+		result := simulate(trace, simulationConfig{cacheType: cacheType, maxSize: 10, evictionPercent: 10, expiration: time.Second * 30}, 0, 0)
+
+		// verify
+		if result.hits != 1 || result.loads != 2 {
+			t.Errorf("Expected %s cache to have 1 hit and 2 loads, got %d and %d", cacheType, result.hits, result.loads)
+		}
+	}
+}
+
+func TestSweepCombinesAllValues(t *testing.T) {
+	// execute
+	configs, err := sweep("10, 100", "10,50", "0,1m", "blocking,refresh")
+	_, invalidErr := sweep("10", "150", "0", "blocking")
+
+	// verify
+	if err != nil || len(configs) != 16 {
+		t.Errorf("Expected 16 configs, got %d and %v", len(configs), err)
+	}
+	if invalidErr == nil {
+		t.Errorf("Expected an eviction percent over 100 to fail")
+	}
+}
+
+func TestRunPrintsAResultPerConfig(t *testing.T) {
+	// setup
+	output := &bytes.Buffer{}
+
+	// execute
+	err := run([]string{"-sizes", "1,2", "-eviction-percents", "100"}, strings.NewReader("a\nb\na\n"), output)
+
+	// verify
+	if err != nil {
+		t.Fatalf("Expected run to succeed, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 results, got %q", output.String())
+	}
+	if !strings.Contains(lines[2], "33.33%") {
+		t.Errorf("Expected the second config to hit once, got %q", lines[2])
+	}
+}
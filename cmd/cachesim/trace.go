@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// access - one get of the key at time, time is zero for traces without timestamps
+type access struct {
+	key  string
+	time time.Time
+}
+
+type traceFormat string
+
+const (
+	// formatKeys - one key per line, blank lines and lines starting with # are skipped
+	formatKeys traceFormat = "keys"
+	// formatCSV - a timestamp and a key per row, a header row is skipped
+	formatCSV traceFormat = "csv"
+	// formatARC - the traces of the ARC paper, "start_block block_count ignored request_number" per line, every line requests block_count blocks
+	formatARC traceFormat = "arc"
+	// formatLIRS - the traces of the LIRS paper, one block number per line
+	formatLIRS traceFormat = "lirs"
+)
+
+var errUnknownFormat = errors.New("unknown trace format")
+
+// formatFromPath returns the format for the file extension, files with an unknown extension are read as keys
+func formatFromPath(path string) traceFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return formatCSV
+	case ".arc":
+		return formatARC
+	case ".lirs", ".trc":
+		return formatLIRS
+	default:
+		return formatKeys
+	}
+}
+
+func readTrace(reader io.Reader, format traceFormat) ([]access, error) {
+	switch format {
+	case formatKeys:
+		return readKeys(reader)
+	case formatCSV:
+		return readCSV(reader)
+	case formatARC:
+		return readARC(reader)
+	case formatLIRS:
+		return readLIRS(reader)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownFormat, format)
+	}
+}
+
+func readKeys(reader io.Reader) ([]access, error) {
+	var trace []access
+	err := readLines(reader, func(line string) error {
+		if strings.HasPrefix(line, "#") {
+			return nil
+		}
+		trace = append(trace, access{key: line})
+		return nil
+	})
+	return trace, err
+}
+
+func readCSV(reader io.Reader) ([]access, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	csvReader.Comment = '#'
+
+	var trace []access
+	for row := 1; ; row++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return trace, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("row %d: expected a timestamp and a key, got %d columns", row, len(record))
+		}
+		timestamp, err := parseTimestamp(record[0])
+		if err != nil {
+			if row == 1 {
+				// header
+				continue
+			}
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		trace = append(trace, access{key: record[1], time: timestamp})
+	}
+}
+
+// parseTimestamp parses RFC 3339 timestamps and unix timestamps in seconds, the seconds can have a fraction
+func parseTimestamp(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		whole := int64(seconds)
+		return time.Unix(whole, int64((seconds-float64(whole))*float64(time.Second))), nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func readARC(reader io.Reader) ([]access, error) {
+	var trace []access
+	lineNumber := 0
+	err := readLines(reader, func(line string) error {
+		lineNumber++
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return fmt.Errorf("line %d: expected a start block and a block count", lineNumber)
+		}
+		start, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		count, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		for block := start; block < start+count; block++ {
+			trace = append(trace, access{key: strconv.FormatUint(block, 10)})
+		}
+		return nil
+	})
+	return trace, err
+}
+
+func readLIRS(reader io.Reader) ([]access, error) {
+	var trace []access
+	lineNumber := 0
+	err := readLines(reader, func(line string) error {
+		lineNumber++
+		block, err := strconv.ParseUint(line, 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		trace = append(trace, access{key: strconv.FormatUint(block, 10)})
+		return nil
+	})
+	return trace, err
+}
+
+// readLines calls f with every trimmed line that is not blank
+func readLines(reader io.Reader, f func(line string) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := f(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func keysOf(trace []access) string {
+	keys := make([]string, 0, len(trace))
+	for _, access := range trace {
+		keys = append(keys, access.key)
+	}
+	return strings.Join(keys, ",")
+}
+
+func TestKeysTraceSkipsBlankAndCommentLines(t *testing.T) {
+	// execute
+	trace, err := readTrace(strings.NewReader("# users\na\n\n  b \na\n"), formatKeys)
+
+	// verify
+	if err != nil {
+		t.Fatalf("Expected trace to be read, got %v", err)
+	}
+	if keysOf(trace) != "a,b,a" {
+		t.Errorf("Expected keys a,b,a, got %s", keysOf(trace))
+	}
+}
+
+func TestCSVTraceSkipsHeaderAndParsesTimestamps(t *testing.T) {
+	// execute
+	trace, err := readTrace(strings.NewReader("time,key\n1000.5,a\n2024-01-02T03:04:05Z,b\n"), formatCSV)
+
+	// verify
+	if err != nil {
+		t.Fatalf("Expected trace to be read, got %v", err)
+	}
+	if keysOf(trace) != "a,b" {
+		t.Fatalf("Expected keys a,b, got %s", keysOf(trace))
+	}
+	if !trace[0].time.Equal(time.Unix(1000, int64(time.Millisecond*500))) {
+		t.Errorf("Expected unix timestamp to be parsed, got %v", trace[0].time)
+	}
+	if !trace[1].time.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expected RFC 3339 timestamp to be parsed, got %v", trace[1].time)
+	}
+}
+
+func TestCSVTraceFailsOnInvalidTimestampAfterHeader(t *testing.T) {
+	// execute
+	_, err := readTrace(strings.NewReader("time,key\n1000,a\nyesterday,b\n"), formatCSV)
+
+	// verify
+	if err == nil || !strings.Contains(err.Error(), "row 3") {
+		t.Errorf("Expected an error for row 3, got %v", err)
+	}
+}
+
+func TestARCTraceExpandsBlockRanges(t *testing.T) {
+	// execute
+	trace, err := readTrace(strings.NewReader("10 3 0 1\n5 1 0 2\n"), formatARC)
+
+	// verify
+	if err != nil {
+		t.Fatalf("Expected trace to be read, got %v", err)
+	}
+	if keysOf(trace) != "10,11,12,5" {
+		t.Errorf("Expected keys 10,11,12,5, got %s", keysOf(trace))
+	}
+}
+
+func TestLIRSTraceRejectsInvalidBlocks(t *testing.T) {
+	// execute
+	trace, err := readTrace(strings.NewReader("7\n8\n"), formatLIRS)
+	_, invalidErr := readTrace(strings.NewReader("7\nx\n"), formatLIRS)
+
+	// verify
+	if err != nil || keysOf(trace) != "7,8" {
+		t.Errorf("Expected keys 7,8, got %s", keysOf(trace))
+	}
+	if invalidErr == nil {
+		t.Errorf("Expected an invalid block to fail")
+	}
+}
+
+func TestUnknownFormatFails(t *testing.T) {
+	// execute
+	_, err := readTrace(strings.NewReader("a\n"), traceFormat("xml"))
+
+	// verify
+	if !errors.Is(err, errUnknownFormat) {
+		t.Errorf("Expected errUnknownFormat, got %v", err)
+	}
+}
+
+func TestFormatIsChosenByExtension(t *testing.T) {
+	// verify
+	if formatFromPath("trace.CSV") != formatCSV || formatFromPath("OLTP.arc") != formatARC ||
+		formatFromPath("sprite.trc") != formatLIRS || formatFromPath("keys.txt") != formatKeys {
+		t.Errorf("Expected the format to follow the extension")
+	}
+}
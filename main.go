@@ -20,5 +20,7 @@ func main() {
 			return &User{}, nil
 		})
 
+	defer userCache.Close()
+
 	userCache.Put("key", &User{})
 }